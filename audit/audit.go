@@ -0,0 +1,425 @@
+package audit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/certstore/acmstore"
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/notifier"
+	"github.com/begmaroman/acme-dns-route53/runner"
+	"github.com/begmaroman/acme-dns-route53/utils/strsl"
+)
+
+const (
+	// endpointTimeout is the timeout of connections to TLS endpoints
+	endpointTimeout = 10 * time.Second
+
+	// endpointPort is the port of TLS endpoints of domains
+	endpointPort = "443"
+)
+
+// Options is the options of the auditor
+type Options struct {
+	Session *session.Session
+
+	// Jobs are certificate groups, their stores are scanned and their targets are notified
+	Jobs []*runner.Job
+
+	// Stores are scanned in addition to stores of the groups, e.g. top-level stores of the configuration
+	Stores []*config.Store
+
+	// Targets are notified about certificates which do not belong to any group
+	Targets []notifier.Target
+
+	// Thresholds are the numbers of days before expiration at which expiring certificates are notified
+	Thresholds []int
+
+	// Endpoints enables checks of certificates served by TLS endpoints of the domains of groups
+	Endpoints bool
+
+	// ConfigDir is the directory of the file with notified thresholds
+	ConfigDir string
+
+	// DryRun reports expiring certificates without notifying them
+	DryRun bool
+
+	Log *logrus.Logger
+}
+
+// Finding is the audited certificate
+type Finding struct {
+	// Source is where the certificate has been found, e.g. "acm (us-east-1)" or "tls (example.com)"
+	Source string `json:"source"`
+
+	// Group is the name of the group the certificate belongs to, empty if it does not belong to any group
+	Group string `json:"group,omitempty"`
+
+	Domains  []string  `json:"domains"`
+	ID       string    `json:"id,omitempty"` // The identifier inside the store, e.g. ARN for ACM
+	Serial   string    `json:"serial,omitempty"`
+	NotAfter time.Time `json:"not_after"`
+	DaysLeft int       `json:"days_left"`
+
+	// Managed is true if the certificate in the store is managed by this tool
+	Managed bool `json:"managed"`
+
+	// Threshold is the most urgent threshold the certificate has reached, zero if it is not expiring yet
+	Threshold int `json:"threshold,omitempty"`
+
+	// Notified is true if the reached threshold has been notified by this audit
+	Notified bool `json:"notified,omitempty"`
+
+	targets []notifier.Target
+	ca      string
+}
+
+// Report is the result of the audit
+type Report struct {
+	Thresholds []int      `json:"thresholds"`
+	DryRun     bool       `json:"dry_run,omitempty"`
+	Findings   []*Finding `json:"findings"`
+
+	// Errors are sources which could not be scanned
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Auditor scans certificate stores and TLS endpoints, and notifies certificates approaching expiry
+// regardless of whether they are issued by this tool
+type Auditor struct {
+	opts        *Options
+	thresholds  []int
+	inventories map[string]certstore.Inventory // Inventories by their sources
+	dialTLS     func(domain string) (*x509.Certificate, error)
+	now         func() time.Time
+}
+
+// New is the constructor of Auditor
+func New(opts *Options) *Auditor {
+	thresholds := append([]int(nil), opts.Thresholds...)
+	sort.Sort(sort.Reverse(sort.IntSlice(thresholds)))
+
+	return &Auditor{
+		opts:        opts,
+		thresholds:  thresholds,
+		inventories: newInventories(opts),
+		dialTLS:     dialTLS,
+		now:         time.Now,
+	}
+}
+
+// Run audits all certificates, notifies newly reached thresholds and returns the report
+func (a *Auditor) Run() *Report {
+	r := &Report{Thresholds: a.thresholds, DryRun: a.opts.DryRun}
+
+	sources := make([]string, 0, len(a.inventories))
+	for source := range a.inventories {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		certs, err := a.inventories[source].ListAll()
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("%s: %s", source, err))
+			continue
+		}
+
+		for _, cert := range certs {
+			r.Findings = append(r.Findings, a.newFinding(source, cert, a.groupOf(cert.Domains)))
+		}
+	}
+
+	if a.opts.Endpoints {
+		a.checkEndpoints(r)
+	}
+
+	if !a.opts.DryRun {
+		a.notify(r)
+	}
+
+	return r
+}
+
+// checkEndpoints adds certificates served by TLS endpoints of the domains of groups to the report.
+// Wildcard domains have no endpoints, each domain is checked once.
+func (a *Auditor) checkEndpoints(r *Report) {
+	checked := make(map[string]bool)
+	for _, job := range a.opts.Jobs {
+		for _, domain := range job.Group.Domains {
+			if strings.HasPrefix(domain, "*.") || checked[domain] {
+				continue
+			}
+			checked[domain] = true
+
+			source := fmt.Sprintf("tls (%s)", domain)
+
+			cert, err := a.dialTLS(domain)
+			if err != nil {
+				r.Errors = append(r.Errors, fmt.Sprintf("%s: %s", source, err))
+				continue
+			}
+
+			r.Findings = append(r.Findings, a.newFinding(source, &certstore.CertificateDetails{
+				Domains:  cert.DNSNames,
+				NotAfter: cert.NotAfter,
+				Serial:   fmt.Sprintf("%x", cert.SerialNumber),
+			}, job))
+		}
+	}
+}
+
+// newFinding creates the finding of the given certificate of the given source which belongs to the group of the given job.
+// Certificates which do not belong to any group are notified to the targets of the options.
+func (a *Auditor) newFinding(source string, cert *certstore.CertificateDetails, job *runner.Job) *Finding {
+	daysLeft := int(cert.NotAfter.Sub(a.now()) / (24 * time.Hour))
+
+	f := &Finding{
+		Source:    source,
+		Domains:   cert.Domains,
+		ID:        cert.ID,
+		Serial:    cert.Serial,
+		NotAfter:  cert.NotAfter.UTC(),
+		DaysLeft:  daysLeft,
+		Managed:   cert.Managed,
+		Threshold: a.threshold(daysLeft),
+		targets:   a.opts.Targets,
+	}
+
+	if job != nil {
+		f.Group = job.Group.Name
+		f.targets = job.Notifications
+		f.ca = job.Group.CADirURL()
+	}
+
+	return f
+}
+
+// threshold returns the most urgent of thresholds reached with the given number of days left, zero if none is reached.
+// Expired certificates reach the most urgent threshold.
+func (a *Auditor) threshold(daysLeft int) int {
+	reached := 0
+	for _, days := range a.thresholds {
+		if daysLeft <= days {
+			reached = days
+		}
+	}
+
+	return reached
+}
+
+// groupOf returns the job of the group the certificate of the given domains belongs to, nil if there is no such group
+func (a *Auditor) groupOf(domains []string) *runner.Job {
+	for _, job := range a.opts.Jobs {
+		if len(domains) > 0 && strsl.ContainsSub(job.Group.Domains, domains) {
+			return job
+		}
+	}
+
+	return nil
+}
+
+// notify notifies findings which reached a threshold more urgent than the one notified before.
+// The certificate found in several sources, e.g. in the store and at the endpoint, is notified once.
+// All reached thresholds are notified if notified thresholds cannot be loaded.
+func (a *Auditor) notify(r *Report) {
+	now := a.now()
+	published := make(map[string]bool)
+
+	loaded := false
+	err := updateNotified(a.opts.ConfigDir, func(notified map[string]*notifiedThreshold) {
+		loaded = true
+		a.notifyReached(r, notified, published)
+		pruneNotified(notified, now)
+	})
+	if err != nil {
+		a.log().Warnf("audit: unable to keep notified thresholds: %s", err)
+	}
+
+	if !loaded {
+		a.notifyReached(r, make(map[string]*notifiedThreshold), published)
+	}
+}
+
+// notifyReached publishes findings which reached a threshold more urgent than the given notified one, and records it
+func (a *Auditor) notifyReached(r *Report, notified map[string]*notifiedThreshold, published map[string]bool) {
+	for _, f := range r.Findings {
+		if f.Threshold == 0 {
+			continue
+		}
+
+		key := f.key()
+		if last, ok := notified[key]; ok && last.Threshold <= f.Threshold {
+			continue
+		}
+
+		if len(f.Serial) == 0 || !published[f.Serial] {
+			a.publish(f)
+			f.Notified = true
+			published[f.Serial] = true
+		}
+
+		notified[key] = &notifiedThreshold{Threshold: f.Threshold, NotAfter: f.NotAfter}
+	}
+}
+
+// publish publishes the event about the given expiring certificate to its targets which accept it.
+// Failures of notifiers are logged.
+func (a *Auditor) publish(f *Finding) {
+	event := notifier.NewEvent(notifier.EventExpiringSoon, f.Domains).SetExpiry(f.NotAfter)
+	event.Serial = f.Serial
+	event.CA = f.ca
+	if len(f.ID) > 0 {
+		event.CertificateARNs = []string{f.ID}
+	}
+
+	for _, target := range f.targets {
+		if !target.Accepts(event.Type) {
+			continue
+		}
+
+		if err := target.Notifier.Notify(target.Topic, event); err != nil {
+			a.log().Errorf("[%s] audit: failed to publish notification: %s", strings.Join(f.Domains, ", "), err)
+		}
+	}
+}
+
+// log returns the logger of the auditor
+func (a *Auditor) log() *logrus.Logger {
+	if a.opts.Log == nil {
+		return logrus.StandardLogger()
+	}
+
+	return a.opts.Log
+}
+
+// key returns the key of the certificate of the finding in the file with notified thresholds.
+// Renewed certificates have new serial numbers, so their thresholds are notified again.
+func (f *Finding) key() string {
+	id := f.ID
+	if len(id) == 0 {
+		id = f.Source
+	}
+
+	return id + "|" + f.Serial
+}
+
+// Expiring returns the number of certificates which reached a threshold
+func (r *Report) Expiring() int {
+	count := 0
+	for _, f := range r.Findings {
+		if f.Threshold > 0 {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Err returns the error if any certificate reached a threshold or any source could not be scanned
+func (r *Report) Err() error {
+	expiring := r.Expiring()
+	if expiring == 0 && len(r.Errors) == 0 {
+		return nil
+	}
+
+	return errors.Errorf("audit: %d certificate(s) expiring, %d source(s) could not be scanned", expiring, len(r.Errors))
+}
+
+// String returns the human-readable report
+func (r *Report) String() string {
+	var b strings.Builder
+	if r.DryRun {
+		b.WriteString("Dry run: no notifications sent\n")
+	}
+
+	notified := 0
+	for _, f := range r.Findings {
+		status := "OK"
+		if f.Threshold > 0 {
+			status = "EXPIRING"
+		}
+
+		fmt.Fprintf(&b, "[%s] %s %s: %d days left, expires %s", status, f.Source, strings.Join(f.Domains, ", "), f.DaysLeft, f.NotAfter.Format(time.RFC3339))
+		if len(f.Group) > 0 {
+			fmt.Fprintf(&b, ", group %s", f.Group)
+		}
+		if !f.Managed && !strings.HasPrefix(f.Source, "tls") {
+			b.WriteString(", not managed")
+		}
+		if f.Notified {
+			fmt.Fprintf(&b, ", notified at %d days", f.Threshold)
+			notified++
+		}
+		b.WriteString("\n")
+	}
+
+	for _, err := range r.Errors {
+		fmt.Fprintf(&b, "[ERROR] %s\n", err)
+	}
+
+	fmt.Fprintf(&b, "Audit: %d certificates, %d expiring, %d notified, %d errors\n", len(r.Findings), r.Expiring(), notified, len(r.Errors))
+
+	return b.String()
+}
+
+// newInventories creates inventories of ACM in all regions of the stores of the options and the groups
+func newInventories(opts *Options) map[string]certstore.Inventory {
+	stores := append([]*config.Store(nil), opts.Stores...)
+	for _, job := range opts.Jobs {
+		stores = append(stores, job.Group.Stores...)
+	}
+
+	inventories := make(map[string]certstore.Inventory)
+	for _, store := range stores {
+		sess := opts.Session
+		region := store.Region
+		if len(region) > 0 {
+			sess = sess.Copy(&aws.Config{Region: aws.String(region)})
+		} else {
+			region = aws.StringValue(sess.Config.Region)
+		}
+
+		source := fmt.Sprintf("acm (%s)", region)
+		if _, ok := inventories[source]; ok {
+			continue
+		}
+
+		if inventory, ok := acmstore.New(sess, opts.Log).(certstore.Inventory); ok {
+			inventories[source] = inventory
+		}
+	}
+
+	return inventories
+}
+
+// dialTLS returns the leaf certificate served by the TLS endpoint of the given domain.
+// The certificate is not verified, so expired and otherwise invalid certificates are reported as well.
+func dialTLS(domain string) (*x509.Certificate, error) {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: endpointTimeout}, "tcp", net.JoinHostPort(domain, endpointPort), &tls.Config{
+		ServerName:         domain,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect")
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate served")
+	}
+
+	return certs[0], nil
+}
@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/notifier"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// fakeInventory lists the given certificates
+type fakeInventory struct {
+	certs []*certstore.CertificateDetails
+	err   error
+}
+
+func (f *fakeInventory) ListAll() ([]*certstore.CertificateDetails, error) {
+	return f.certs, f.err
+}
+
+// recordingNotifier records notified events
+type recordingNotifier struct {
+	events []*notifier.Event
+}
+
+func (r *recordingNotifier) Notify(topic string, event *notifier.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestThreshold(t *testing.T) {
+	a := New(&Options{Thresholds: []int{1, 21, 3, 14, 7}})
+
+	testTable := []*struct {
+		testName string
+		daysLeft int
+		expected int
+	}{
+		{testName: "not expiring", daysLeft: 30, expected: 0},
+		{testName: "first threshold", daysLeft: 21, expected: 21},
+		{testName: "between thresholds", daysLeft: 10, expected: 14},
+		{testName: "last day", daysLeft: 0, expected: 1},
+		{testName: "expired", daysLeft: -3, expected: 1},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			require.Equal(t, tt.expected, a.threshold(tt.daysLeft))
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	groupNotifier := &recordingNotifier{}
+	topLevelNotifier := &recordingNotifier{}
+
+	jobs := []*runner.Job{{
+		Group:         &config.Group{Name: "web", Domains: []string{"example.com", "*.example.com"}},
+		Notifications: []notifier.Target{{Notifier: groupNotifier, Topic: "web"}},
+	}}
+
+	newAuditor := func(dryRun bool) *Auditor {
+		a := New(&Options{
+			Jobs:       jobs,
+			Targets:    []notifier.Target{{Notifier: topLevelNotifier, Topic: "all"}},
+			Thresholds: config.DefaultExpiryThresholds,
+			Endpoints:  true,
+			ConfigDir:  configDir,
+			DryRun:     dryRun,
+		})
+		a.now = func() time.Time { return now }
+		a.inventories = map[string]certstore.Inventory{
+			"acm (us-east-1)": &fakeInventory{certs: []*certstore.CertificateDetails{
+				{ID: "arn:web", Domains: []string{"example.com", "*.example.com"}, NotAfter: time.Date(2026, 10, 11, 12, 0, 0, 0, time.UTC), Serial: "1a", Managed: true},
+				{ID: "arn:other", Domains: []string{"other.org"}, NotAfter: time.Date(2026, 10, 3, 18, 0, 0, 0, time.UTC), Serial: "2b"},
+				{ID: "arn:fresh", Domains: []string{"fresh.org"}, NotAfter: time.Date(2026, 12, 30, 0, 0, 0, 0, time.UTC), Serial: "3c"},
+			}},
+			"acm (eu-west-1)": &fakeInventory{err: errors.New("AccessDenied")},
+		}
+		a.dialTLS = func(domain string) (*x509.Certificate, error) {
+			return &x509.Certificate{
+				DNSNames:     []string{"example.com", "*.example.com"},
+				NotAfter:     time.Date(2026, 10, 11, 12, 0, 0, 0, time.UTC),
+				SerialNumber: big.NewInt(0x1a),
+			}, nil
+		}
+
+		return a
+	}
+
+	// Dry run reports expiring certificates without notifying them
+	report := newAuditor(true).Run()
+	require.Len(t, report.Findings, 4)
+	require.Equal(t, 3, report.Expiring())
+	require.Equal(t, []string{"acm (eu-west-1): AccessDenied"}, report.Errors)
+	require.Error(t, report.Err())
+	require.Empty(t, groupNotifier.events)
+	require.Empty(t, topLevelNotifier.events)
+
+	_, err = os.Stat(filepath.Join(configDir, notifiedFileName))
+	require.True(t, os.IsNotExist(err))
+
+	// The certificate of the group is notified to the group once, even though it is served by the endpoint too
+	report = newAuditor(false).Run()
+	require.Len(t, groupNotifier.events, 1)
+	require.Equal(t, notifier.EventExpiringSoon, groupNotifier.events[0].Type)
+	require.Equal(t, []string{"arn:web"}, groupNotifier.events[0].CertificateARNs)
+	require.Equal(t, "web", report.Findings[0].Group)
+	require.Equal(t, 14, report.Findings[0].Threshold)
+
+	// The certificate not managed by this tool is notified to the top-level targets
+	require.Len(t, topLevelNotifier.events, 1)
+	require.Equal(t, []string{"other.org"}, topLevelNotifier.events[0].Domains)
+	require.Equal(t, 3, report.Findings[1].Threshold)
+	require.Contains(t, report.String(), "[EXPIRING] acm (us-east-1) other.org: 2 days left, expires 2026-10-03T18:00:00Z, not managed, notified at 3 days")
+
+	// Reached thresholds are not notified again
+	newAuditor(false).Run()
+	require.Len(t, groupNotifier.events, 1)
+	require.Len(t, topLevelNotifier.events, 1)
+
+	// More urgent thresholds are notified
+	now = now.Add(5 * 24 * time.Hour)
+	newAuditor(false).Run()
+	require.Len(t, groupNotifier.events, 2)
+	require.Len(t, topLevelNotifier.events, 2)
+}
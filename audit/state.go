@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// notifiedFileName is the name of the file in the config directory with notified thresholds of certificates
+	notifiedFileName = "expiry-notifications.json"
+
+	// notifiedRetention is the period after expiration within which notified thresholds of the certificate are kept
+	notifiedRetention = 30 * 24 * time.Hour
+)
+
+// notifiedThreshold is the most urgent threshold notified for the certificate
+type notifiedThreshold struct {
+	Threshold int       `json:"threshold"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// updateNotified loads notified thresholds from the given config directory, updates and stores them
+func updateNotified(configDir string, update func(notified map[string]*notifiedThreshold)) error {
+	path := filepath.Join(configDir, notifiedFileName)
+
+	notified := make(map[string]*notifiedThreshold)
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &notified); err != nil {
+			return errors.Wrapf(err, "invalid file '%s'", path)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	update(notified)
+
+	if data, err = json.Marshal(notified); err != nil {
+		return err
+	}
+
+	if len(configDir) > 0 {
+		if err := os.MkdirAll(configDir, 0700); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// pruneNotified forgets thresholds of certificates which expired before the retention period
+func pruneNotified(notified map[string]*notifiedThreshold, now time.Time) {
+	for key, n := range notified {
+		if now.Sub(n.NotAfter) > notifiedRetention {
+			delete(notified, key)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/acm/acmiface"
 	"github.com/go-acme/lego/certificate"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -14,15 +15,36 @@ import (
 	"github.com/begmaroman/acme-dns-route53/utils/strsl"
 )
 
+const (
+	// managedByTagKey is the key of the tag which marks certificates imported by this tool
+	managedByTagKey = "ManagedBy"
+
+	// managedByTagValue is the value of the tag which marks certificates imported by this tool
+	managedByTagValue = "acme-dns-route53"
+)
+
 var (
+	// keyTypes are all key types of certificates in ACM, ACM lists RSA 2048 certificates only unless key types are given
+	keyTypes = aws.StringSlice([]string{
+		acm.KeyAlgorithmRsa1024,
+		acm.KeyAlgorithmRsa2048,
+		acm.KeyAlgorithmRsa4096,
+		acm.KeyAlgorithmEcPrime256v1,
+		acm.KeyAlgorithmEcSecp384r1,
+		acm.KeyAlgorithmEcSecp521r1,
+	})
+
 	// ErrCertificateMissing is the error when certificate is empty
 	ErrCertificateMissing = errors.New("certificate is empty")
+
+	// ErrCertificateNotFound is the error when there is no certificate for the given domains in ACM
+	ErrCertificateNotFound = errors.New("certificate not found")
 )
 
 // ACM is the implementation of CertStore interface.
 // Used Amazon Certificate Manager to work with certificates
 type acmStore struct {
-	acm *acm.ACM
+	acm acmiface.ACMAPI
 	log *logrus.Logger
 }
 
@@ -35,9 +57,9 @@ func New(provider client.ConfigProvider, log *logrus.Logger) certstore.CertStore
 }
 
 // Store implements CertStore interface
-func (a *acmStore) Store(cert *certificate.Resource, domains []string) error {
+func (a *acmStore) Store(cert *certificate.Resource, domains []string) ([]string, error) {
 	if cert == nil || cert.Certificate == nil {
-		return ErrCertificateMissing
+		return nil, ErrCertificateMissing
 	}
 
 	domainsListString := strings.Join(domains, ", ")
@@ -46,14 +68,14 @@ func (a *acmStore) Store(cert *certificate.Resource, domains []string) error {
 
 	serverCert, err := retrieveServerCertificate(cert.Certificate)
 	if err != nil {
-		return errors.Wrap(err, "acm: unable to retrieve server certificate")
+		return nil, errors.Wrap(err, "acm: unable to retrieve server certificate")
 	}
 
 	a.log.Infof("[%s] acm: Finding existing server certificate in ACM", domainsListString)
 
 	existingCert, err := a.findExistingCertificate(domains)
 	if err != nil {
-		return errors.Wrap(err, "acm: unable to find existing certificate")
+		return nil, errors.Wrap(err, "acm: unable to find existing certificate")
 	}
 
 	// Retrieve exising certificate ID
@@ -76,12 +98,27 @@ func (a *acmStore) Store(cert *certificate.Resource, domains []string) error {
 
 	resp, err := a.acm.ImportCertificate(input)
 	if err != nil {
-		return errors.Wrap(err, "acm: unable to store certificate into ACM")
+		return nil, errors.Wrap(err, "acm: unable to store certificate into ACM")
 	}
 
 	a.log.Infof("[%s] acm: Imported certificate data in ACM with Arn = '%s'", domainsListString, aws.StringValue(resp.CertificateArn))
 
-	return nil
+	// Mark the certificate as managed by this tool to be able to discover it later
+	if _, err := a.acm.AddTagsToCertificate(&acm.AddTagsToCertificateInput{
+		CertificateArn: resp.CertificateArn,
+		Tags: []*acm.Tag{
+			{
+				Key:   aws.String(managedByTagKey),
+				Value: aws.String(managedByTagValue),
+			},
+		},
+	}); err != nil {
+		// The certificate is imported, so its ARN is returned anyway
+		a.log.Errorf("[%s] acm: unable to tag certificate with Arn = '%s', it is not managed until it is tagged with %s=%s: %s",
+			domainsListString, aws.StringValue(resp.CertificateArn), managedByTagKey, managedByTagValue, err)
+	}
+
+	return []string{aws.StringValue(resp.CertificateArn)}, nil
 }
 
 // Load loads certificate by the given domains
@@ -91,30 +128,184 @@ func (a *acmStore) Load(domains []string) (*certstore.CertificateDetails, error)
 		return nil, errors.Wrap(err, "acm: unable to find certificate")
 	}
 
-	return toCertificateDetails(cert), nil
+	if cert == nil {
+		return nil, nil
+	}
+
+	// Retrieve the certificate body
+	certResp, err := a.acm.GetCertificate(&acm.GetCertificateInput{
+		CertificateArn: cert.CertificateArn,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "acm: unable to get certificate with Arn = '%s'", aws.StringValue(cert.CertificateArn))
+	}
+
+	details := toCertificateDetails(cert)
+	details.Certificate = []byte(aws.StringValue(certResp.Certificate))
+
+	return details, nil
 }
 
-// findExistingCertificate look ups a certificate in ACm by the given domains
-func (a *acmStore) findExistingCertificate(domains []string) (*acm.CertificateDetail, error) {
-	listResp, err := a.acm.ListCertificates(&acm.ListCertificatesInput{
-		MaxItems: aws.Int64(1000),
+// Delete removes certificate of the given domains from ACM
+func (a *acmStore) Delete(domains []string) error {
+	domainsListString := strings.Join(domains, ", ")
+
+	cert, err := a.findExistingCertificate(domains)
+	if err != nil {
+		return errors.Wrap(err, "acm: unable to find certificate")
+	}
+
+	if cert == nil {
+		return ErrCertificateNotFound
+	}
+
+	if _, err := a.acm.DeleteCertificate(&acm.DeleteCertificateInput{
+		CertificateArn: cert.CertificateArn,
+	}); err != nil {
+		return errors.Wrapf(err, "acm: unable to delete certificate with Arn = '%s'", aws.StringValue(cert.CertificateArn))
+	}
+
+	a.log.Infof("[%s] acm: Deleted certificate with Arn = '%s'", domainsListString, aws.StringValue(cert.CertificateArn))
+
+	return nil
+}
+
+// List lists certificates in ACM which are tagged as managed by this tool
+func (a *acmStore) List() ([]*certstore.CertificateDetails, error) {
+	certArns, err := a.listCertificateArns()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*certstore.CertificateDetails
+	for _, certArn := range certArns {
+		managed, err := a.isManaged(certArn)
+		if err != nil {
+			return nil, err
+		}
+
+		if !managed {
+			continue
+		}
+
+		certResp, err := a.acm.DescribeCertificate(&acm.DescribeCertificateInput{
+			CertificateArn: certArn,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "acm: unable to describe certificate")
+		}
+
+		list = append(list, toCertificateDetails(certResp.Certificate))
+	}
+
+	return list, nil
+}
+
+// ListAll implements certstore.Inventory interface.
+// Certificates which are not issued yet, e.g. pending validation, are skipped.
+func (a *acmStore) ListAll() ([]*certstore.CertificateDetails, error) {
+	certArns, err := a.listCertificateArns()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*certstore.CertificateDetails
+	for _, certArn := range certArns {
+		certResp, err := a.acm.DescribeCertificate(&acm.DescribeCertificateInput{
+			CertificateArn: certArn,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "acm: unable to describe certificate")
+		}
+
+		if certResp.Certificate.NotAfter == nil {
+			continue
+		}
+
+		managed, err := a.isManaged(certArn)
+		if err != nil {
+			return nil, err
+		}
+
+		details := toCertificateDetails(certResp.Certificate)
+		details.Serial = normalizeSerial(aws.StringValue(certResp.Certificate.Serial))
+		details.Managed = managed
+
+		list = append(list, details)
+	}
+
+	return list, nil
+}
+
+// isManaged checks if the certificate with the given ARN is tagged as managed by this tool
+func (a *acmStore) isManaged(certArn *string) (bool, error) {
+	tagsResp, err := a.acm.ListTagsForCertificate(&acm.ListTagsForCertificateInput{
+		CertificateArn: certArn,
 	})
 	if err != nil {
+		return false, errors.Wrapf(err, "acm: unable to list tags of certificate with Arn = '%s'", aws.StringValue(certArn))
+	}
+
+	for _, tag := range tagsResp.Tags {
+		if aws.StringValue(tag.Key) == managedByTagKey && aws.StringValue(tag.Value) == managedByTagValue {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// listCertificateArns lists ARNs of all certificates in ACM of any key type
+func (a *acmStore) listCertificateArns() ([]*string, error) {
+	var certArns []*string
+	if err := a.acm.ListCertificatesPages(&acm.ListCertificatesInput{
+		Includes: &acm.Filters{KeyTypes: keyTypes},
+	}, func(page *acm.ListCertificatesOutput, lastPage bool) bool {
+		for _, crt := range page.CertificateSummaryList {
+			certArns = append(certArns, crt.CertificateArn)
+		}
+
+		return true
+	}); err != nil {
 		return nil, errors.Wrap(err, "acm: unable to list certificates")
 	}
 
-	for _, crt := range listResp.CertificateSummaryList {
+	return certArns, nil
+}
+
+// findExistingCertificate look ups a certificate in ACm by the given domains.
+// Certificates which are not tagged as managed by this tool are ignored, so they are never changed or deleted.
+func (a *acmStore) findExistingCertificate(domains []string) (*acm.CertificateDetail, error) {
+	certArns, err := a.listCertificateArns()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, certArn := range certArns {
 		certResp, err := a.acm.DescribeCertificate(&acm.DescribeCertificateInput{
-			CertificateArn: crt.CertificateArn,
+			CertificateArn: certArn,
 		})
 		if err != nil {
 			return nil, errors.Wrap(err, "acm: unable to describe certificate")
 		}
 
 		altNames := aws.StringValueSlice(certResp.Certificate.SubjectAlternativeNames)
-		if strsl.ContainsSub(domains, altNames) {
-			return certResp.Certificate, nil
+		if !strsl.ContainsSub(domains, altNames) {
+			continue
 		}
+
+		managed, err := a.isManaged(certArn)
+		if err != nil {
+			return nil, err
+		}
+
+		if !managed {
+			a.log.Warnf("[%s] acm: certificate with Arn = '%s' is not tagged with %s=%s, it is ignored",
+				strings.Join(domains, ", "), aws.StringValue(certArn), managedByTagKey, managedByTagValue)
+			continue
+		}
+
+		return certResp.Certificate, nil
 	}
 
 	return nil, nil
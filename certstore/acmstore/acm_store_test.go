@@ -0,0 +1,195 @@
+package acmstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/acm/acmiface"
+	"github.com/go-acme/lego/certificate"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCertificate is the certificate in the fake ACM
+type fakeCertificate struct {
+	arn      string
+	keyType  string
+	domains  []string
+	managed  bool
+	notAfter bool
+}
+
+// fakeACM lists one certificate per page, like ACM it lists RSA 2048 certificates only unless key types are given
+type fakeACM struct {
+	acmiface.ACMAPI
+
+	certs    []*fakeCertificate
+	deleted  []string
+	imported []string
+	tagErr   error
+}
+
+func (f *fakeACM) ListCertificatesPages(input *acm.ListCertificatesInput, fn func(*acm.ListCertificatesOutput, bool) bool) error {
+	keyTypes := []string{acm.KeyAlgorithmRsa2048}
+	if input.Includes != nil && len(input.Includes.KeyTypes) > 0 {
+		keyTypes = aws.StringValueSlice(input.Includes.KeyTypes)
+	}
+
+	var listed []*fakeCertificate
+	for _, cert := range f.certs {
+		for _, keyType := range keyTypes {
+			if cert.keyType == keyType {
+				listed = append(listed, cert)
+			}
+		}
+	}
+
+	for i, cert := range listed {
+		page := &acm.ListCertificatesOutput{
+			CertificateSummaryList: []*acm.CertificateSummary{{CertificateArn: aws.String(cert.arn)}},
+		}
+		if !fn(page, i == len(listed)-1) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeACM) DescribeCertificate(input *acm.DescribeCertificateInput) (*acm.DescribeCertificateOutput, error) {
+	cert := f.find(aws.StringValue(input.CertificateArn))
+
+	detail := &acm.CertificateDetail{
+		CertificateArn:          aws.String(cert.arn),
+		DomainName:              aws.String(cert.domains[0]),
+		SubjectAlternativeNames: aws.StringSlice(cert.domains),
+		KeyAlgorithm:            aws.String(cert.keyType),
+	}
+	if cert.notAfter {
+		detail.NotAfter = aws.Time(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+
+	return &acm.DescribeCertificateOutput{Certificate: detail}, nil
+}
+
+func (f *fakeACM) ListTagsForCertificate(input *acm.ListTagsForCertificateInput) (*acm.ListTagsForCertificateOutput, error) {
+	output := &acm.ListTagsForCertificateOutput{}
+	if f.find(aws.StringValue(input.CertificateArn)).managed {
+		output.Tags = []*acm.Tag{{Key: aws.String(managedByTagKey), Value: aws.String(managedByTagValue)}}
+	}
+
+	return output, nil
+}
+
+func (f *fakeACM) DeleteCertificate(input *acm.DeleteCertificateInput) (*acm.DeleteCertificateOutput, error) {
+	f.deleted = append(f.deleted, aws.StringValue(input.CertificateArn))
+	return &acm.DeleteCertificateOutput{}, nil
+}
+
+func (f *fakeACM) ImportCertificate(input *acm.ImportCertificateInput) (*acm.ImportCertificateOutput, error) {
+	arn := aws.StringValue(input.CertificateArn)
+	if len(arn) == 0 {
+		arn = "imported"
+	}
+
+	f.imported = append(f.imported, arn)
+	return &acm.ImportCertificateOutput{CertificateArn: aws.String(arn)}, nil
+}
+
+func (f *fakeACM) AddTagsToCertificate(input *acm.AddTagsToCertificateInput) (*acm.AddTagsToCertificateOutput, error) {
+	return &acm.AddTagsToCertificateOutput{}, f.tagErr
+}
+
+func (f *fakeACM) GetCertificate(input *acm.GetCertificateInput) (*acm.GetCertificateOutput, error) {
+	return &acm.GetCertificateOutput{Certificate: aws.String("certificate")}, nil
+}
+
+func (f *fakeACM) find(arn string) *fakeCertificate {
+	for _, cert := range f.certs {
+		if cert.arn == arn {
+			return cert
+		}
+	}
+
+	return nil
+}
+
+func newTestStore(certs ...*fakeCertificate) (*acmStore, *fakeACM) {
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	fake := &fakeACM{certs: certs}
+	return &acmStore{acm: fake, log: log}, fake
+}
+
+func TestKeyTypes(t *testing.T) {
+	store, fake := newTestStore(
+		&fakeCertificate{arn: "rsa", keyType: acm.KeyAlgorithmRsa2048, domains: []string{"rsa.example.com"}, managed: true, notAfter: true},
+		&fakeCertificate{arn: "ec256", keyType: acm.KeyAlgorithmEcPrime256v1, domains: []string{"ec.example.com"}, managed: true, notAfter: true},
+		&fakeCertificate{arn: "rsa4096", keyType: acm.KeyAlgorithmRsa4096, domains: []string{"big.example.com"}, managed: true, notAfter: true},
+	)
+
+	cert, err := store.findExistingCertificate([]string{"ec.example.com"})
+	require.NoError(t, err)
+	require.NotNil(t, cert)
+	require.Equal(t, "ec256", aws.StringValue(cert.CertificateArn))
+
+	list, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	all, err := store.ListAll()
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+
+	require.NoError(t, store.Delete([]string{"big.example.com"}))
+	require.Equal(t, []string{"rsa4096"}, fake.deleted)
+}
+
+func TestUnmanagedCertificates(t *testing.T) {
+	store, fake := newTestStore(
+		&fakeCertificate{arn: "unmanaged", keyType: acm.KeyAlgorithmRsa2048, domains: []string{"example.com"}, notAfter: true},
+	)
+
+	cert, err := store.Load([]string{"example.com"})
+	require.NoError(t, err)
+	require.Nil(t, cert)
+
+	require.Equal(t, ErrCertificateNotFound, store.Delete([]string{"example.com"}))
+	require.Empty(t, fake.deleted)
+
+	// The new certificate is imported instead of replacing the unmanaged one, failed tagging does not fail storing
+	fake.tagErr = errors.New("access denied")
+	ids, err := store.Store(&certificate.Resource{Certificate: selfSignedCertificate(t)}, []string{"example.com"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"imported"}, ids)
+	require.Equal(t, []string{"imported"}, fake.imported)
+}
+
+// selfSignedCertificate returns the PEM encoded self-signed certificate
+func selfSignedCertificate(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/x509"
 	"encoding/pem"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 
@@ -51,6 +52,14 @@ func toCertificateDetails(cert *acm.CertificateDetail) *certstore.CertificateDet
 	}
 
 	return &certstore.CertificateDetails{
-		NotAfter: aws.TimeValue(cert.NotAfter),
+		ID:        aws.StringValue(cert.CertificateArn),
+		Domains:   aws.StringValueSlice(cert.SubjectAlternativeNames),
+		NotBefore: aws.TimeValue(cert.NotBefore),
+		NotAfter:  aws.TimeValue(cert.NotAfter),
 	}
 }
+
+// normalizeSerial converts the serial number reported by ACM, e.g. "0a:1b:2c", to the hex encoded form without leading zeros
+func normalizeSerial(serial string) string {
+	return strings.TrimLeft(strings.ToLower(strings.Replace(serial, ":", "", -1)), "0")
+}
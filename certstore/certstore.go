@@ -8,15 +8,48 @@ import (
 
 // CertStore represents the interface to CRUD certificates
 type CertStore interface {
-	// Store represents logic to store the given certificate for the given domains
-	Store(certificate *certificate.Resource, domains []string) error
+	// Store represents logic to store the given certificate for the given domains.
+	// Returns identifiers of the stored certificate, e.g. ARNs for ACM.
+	Store(certificate *certificate.Resource, domains []string) ([]string, error)
 
+	// Load loads the certificate details for the given domains.
+	// Returns nil if there is no certificate for the given domains.
 	Load(domains []string) (*CertificateDetails, error)
+
+	// Delete removes the certificate of the given domains from the store
+	Delete(domains []string) error
+
+	// List lists details of all certificates in the store which are managed by this tool
+	List() ([]*CertificateDetails, error)
 }
 
 // CertificateDetails contains certificate details
-// TODO: Add more fields
 type CertificateDetails struct {
+	// ID is the identifier of the certificate inside the store, e.g. ARN for ACM.
+	ID string
+
+	// Domains is the list of domains the certificate is issued for.
+	Domains []string
+
+	// NotBefore is the time before which the certificate is not valid.
+	NotBefore time.Time
+
 	// NotAfter is the time after which the certificate is not valid.
 	NotAfter time.Time
+
+	// Certificate is the PEM encoded certificate.
+	Certificate []byte
+
+	// Serial is the hex encoded serial number of the certificate, set by inventories only.
+	Serial string
+
+	// Managed is true if the certificate is managed by this tool, set by inventories only.
+	Managed bool
+}
+
+// Inventory represents the store which lists all its certificates,
+// including those which are not managed by this tool
+type Inventory interface {
+	// ListAll lists details of all certificates in the store
+	ListAll() ([]*CertificateDetails, error)
 }
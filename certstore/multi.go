@@ -0,0 +1,76 @@
+package certstore
+
+import (
+	"github.com/go-acme/lego/certificate"
+)
+
+// multiStore is the CertStore which keeps certificates in several stores at once
+type multiStore []CertStore
+
+// NewMulti creates the CertStore which keeps certificates in all the given stores.
+// Certificates are stored into all stores, and listed from the first one.
+func NewMulti(stores ...CertStore) CertStore {
+	if len(stores) == 1 {
+		return stores[0]
+	}
+
+	return multiStore(stores)
+}
+
+// Store implements CertStore interface
+func (m multiStore) Store(cert *certificate.Resource, domains []string) ([]string, error) {
+	var ids []string
+	for _, store := range m {
+		storeIDs, err := store.Store(cert, domains)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, storeIDs...)
+	}
+
+	return ids, nil
+}
+
+// Load implements CertStore interface.
+// Returns nil if any store misses the certificate, so it is issued and stored everywhere,
+// otherwise returns details of the certificate which expires first.
+func (m multiStore) Load(domains []string) (*CertificateDetails, error) {
+	var details *CertificateDetails
+	for _, store := range m {
+		storeDetails, err := store.Load(domains)
+		if err != nil {
+			return nil, err
+		}
+
+		if storeDetails == nil {
+			return nil, nil
+		}
+
+		if details == nil || storeDetails.NotAfter.Before(details.NotAfter) {
+			details = storeDetails
+		}
+	}
+
+	return details, nil
+}
+
+// Delete implements CertStore interface
+func (m multiStore) Delete(domains []string) error {
+	for _, store := range m {
+		if err := store.Delete(domains); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List implements CertStore interface
+func (m multiStore) List() ([]*CertificateDetails, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+
+	return m[0].List()
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/begmaroman/acme-dns-route53/audit"
+	"github.com/begmaroman/acme-dns-route53/cmd/flags"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// auditCmd represents the expiry watchdog command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Alert on certificates approaching expiry",
+	Long:  `This command scans certificates in ACM in all regions of the stores, including certificates not managed by this tool, and optionally TLS endpoints of the domains of the given domains or certificate groups of the configuration file. Certificates reaching each threshold before expiration are notified once to the targets of their group, or to the top-level targets if they do not belong to any group. Nothing is renewed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Load certificate groups, the top-level stores are scanned without them
+		conf, err := loadConfig(cmd, true)
+		if err != nil {
+			return err
+		}
+
+		thresholds, err := flags.GetThresholdsFlagValue(cmd)
+		if err != nil {
+			return configError(err)
+		}
+
+		// Apply top-level defaults
+		defaults := conf.NewGroup(nil)
+		if len(thresholds) == 0 {
+			thresholds = conf.Expiry.Thresholds
+		}
+
+		log := logrus.New()
+		opts := newRunnerOptions(cmd, log)
+
+		report := audit.New(&audit.Options{
+			Session:    AWSSession,
+			Jobs:       runner.NewJobs(conf.Groups, opts),
+			Stores:     defaults.Stores,
+			Targets:    runner.NewGroupTargets(defaults, opts),
+			Thresholds: thresholds,
+			Endpoints:  flags.GetEndpointsFlagValue(cmd) || conf.Expiry.Endpoints,
+			ConfigDir:  opts.ConfigDir,
+			DryRun:     opts.DryRun,
+			Log:        log,
+		}).Run()
+
+		cmd.Print(report.String())
+
+		if err := report.Err(); err != nil {
+			return &exitError{code: ExitCodeFailed, err: err}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	addConfigFlags(auditCmd, "The domains list, comma-separated. Only stores are scanned if neither domains nor --config are provided")
+	flags.AddConfigPathFlag(auditCmd)
+	flags.AddThresholdsFlag(auditCmd)
+	flags.AddEndpointsFlag(auditCmd)
+	flags.AddDryRunFlag(auditCmd)
+
+	RootCmd.AddCommand(auditCmd)
+}
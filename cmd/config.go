@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/begmaroman/acme-dns-route53/cmd/flags"
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+var (
+	// errDomainsMissing is the error when neither configuration file nor domains are provided
+	errDomainsMissing = errors.New("either --config or --domains flag must be provided")
+
+	// errEmailMissing is the error when email is not provided without configuration file
+	errEmailMissing = errors.New("--email flag must be provided without --config flag")
+)
+
+// addConfigFlags adds flags defining certificate groups to the command
+func addConfigFlags(c *cobra.Command, domainsDescription string) {
+	flags.AddConfigFlag(c)
+	flags.AddGroupsFlag(c)
+	flags.AddOptionalDomainsFlag(c, domainsDescription)
+	flags.AddOptionalEmailFlag(c)
+	flags.AddStagingFlag(c)
+	flags.AddTopicFlag(c)
+	flags.AddRenewBeforeFlag(c)
+	flags.AddCAAFlag(c)
+	flags.AddParallelismFlag(c)
+}
+
+// loadConfig loads the configuration from the file given by --config flag and selects groups given by --groups flag.
+// Without configuration file, the configuration is built from the command flags with a separate group for each domain.
+// The configuration without groups is returned as is if allowEmpty is true, otherwise it is validated.
+// All returned errors are configuration errors.
+func loadConfig(cmd *cobra.Command, allowEmpty bool) (*config.Config, error) {
+	conf, err := loadConfigGroups(cmd, allowEmpty)
+	if err != nil {
+		return nil, configError(err)
+	}
+
+	return conf, nil
+}
+
+// loadConfigGroups loads the configuration with groups from the file or from the command flags
+func loadConfigGroups(cmd *cobra.Command, allowEmpty bool) (*config.Config, error) {
+	if location := flags.GetConfigFlagValue(cmd); len(location) > 0 {
+		conf, err := config.Load(location, AWSSession)
+		if err != nil {
+			return nil, err
+		}
+
+		if conf.Groups, err = conf.GroupsByName(flags.GetGroupsFlagValue(cmd)); err != nil {
+			return nil, err
+		}
+
+		// Explicit --staging flag switches all groups to the staging environment, e.g. to try a new configuration
+		if flags.IsStagingFlagSet(cmd) && flags.GetStagingFlagValue(cmd) {
+			for _, group := range conf.Groups {
+				group.CA = config.CAStaging
+			}
+		}
+
+		return conf, nil
+	}
+
+	conf := &config.Config{
+		Email:       flags.GetEmailFlagValue(cmd),
+		RenewBefore: flags.GetRenewBeforeFlagValue(cmd),
+		CAA:         flags.GetCAAFlagValue(cmd),
+	}
+
+	if len(conf.Email) == 0 {
+		return nil, errEmailMissing
+	}
+
+	if flags.GetStagingFlagValue(cmd) {
+		conf.CA = config.CAStaging
+	}
+
+	if topic := flags.GetTopicFlagValue(cmd); len(topic) > 0 {
+		conf.Notifications = []*config.Notification{{Type: config.NotificationTypeSNS, Topic: topic}}
+	}
+
+	// Each domain is a separate certificate
+	for _, domain := range flags.GetDomainsFlagValue(cmd) {
+		conf.Groups = append(conf.Groups, conf.NewGroup([]string{domain}))
+	}
+
+	if len(conf.Groups) == 0 {
+		if allowEmpty {
+			return conf, nil
+		}
+
+		return nil, errDomainsMissing
+	}
+
+	if err := conf.Init(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// newRunnerOptions creates options of certificate handlers from the command flags
+func newRunnerOptions(cmd *cobra.Command, log *logrus.Logger) *runner.Options {
+	return &runner.Options{
+		Session:    AWSSession,
+		ConfigDir:  flags.GetConfigPathFlagValue(cmd),
+		DisableARI: flags.GetDisableARIFlagValue(cmd),
+		DryRun:     flags.GetDryRunFlagValue(cmd),
+		Log:        log,
+	}
+}
+
+// getParallelism returns the number of certificate groups processed at once given by the flag or the configuration
+func getParallelism(cmd *cobra.Command, conf *config.Config) int {
+	if parallelism := flags.GetParallelismFlagValue(cmd); parallelism > 0 {
+		return parallelism
+	}
+
+	return conf.Parallelism
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/begmaroman/acme-dns-route53/cmd/flags"
+	"github.com/begmaroman/acme-dns-route53/doctor"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// doctorCmd represents the environment verification command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Verify the environment",
+	Long:  `This command verifies AWS credentials and region, IAM permissions of each subsystem, hosted zones of the given domains or certificate groups of the configuration file, the ACME directory and the account, and prints the checklist. Nothing is changed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Load certificate groups
+		conf, err := loadConfig(cmd, false)
+		if err != nil {
+			return err
+		}
+
+		report := doctor.New(&doctor.Options{
+			Session:        AWSSession,
+			Jobs:           runner.NewJobs(conf.Groups, newRunnerOptions(cmd, logrus.New())),
+			ConfigLocation: flags.GetConfigFlagValue(cmd),
+		}).Run()
+
+		cmd.Print(report.String())
+
+		if failed := report.Count(doctor.StatusFail); failed > 0 {
+			return &exitError{code: ExitCodeFailed, err: errors.Errorf("doctor: %d check(s) failed", failed)}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	addConfigFlags(doctorCmd, "The domains list, comma-separated, required without --config")
+	flags.AddConfigPathFlag(doctorCmd)
+
+	RootCmd.AddCommand(doctorCmd)
+}
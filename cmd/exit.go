@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+const (
+	// ExitCodeOK is the exit code when all certificate groups are processed successfully
+	ExitCodeOK = 0
+
+	// ExitCodeFailed is the exit code when processing of some certificate groups failed
+	ExitCodeFailed = 1
+
+	// ExitCodeConfig is the exit code when the configuration or command flags are invalid
+	ExitCodeConfig = 2
+)
+
+// exitError is the error which defines the exit code of the process
+type exitError struct {
+	code int
+	err  error
+}
+
+// Error implements error interface
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// configError marks the given error as the configuration error
+func configError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &exitError{code: ExitCodeConfig, err: err}
+}
+
+// exitCode returns the exit code of the process finished with the given error
+func exitCode(err error) int {
+	if err == nil {
+		return ExitCodeOK
+	}
+
+	if exitErr, ok := err.(*exitError); ok {
+		return exitErr.code
+	}
+
+	return ExitCodeFailed
+}
+
+// printSummary prints the given summary to the command output and returns the error if processing of any group failed
+func printSummary(cmd *cobra.Command, summary *runner.Summary) error {
+	cmd.Print(summary.String())
+
+	if err := summary.Err(); err != nil {
+		return &exitError{code: ExitCodeFailed, err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package flags
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagThresholds = "thresholds"
+	flagEndpoints  = "endpoints"
+)
+
+// AddThresholdsFlag adds the thresholds flag to the command
+func AddThresholdsFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagThresholds, "", "The numbers of days before expiration at which expiring certificates are notified, comma-separated. The configuration or 21,14,7,3,1 if not set", false)
+}
+
+// GetThresholdsFlagValue gets the value of the thresholds flag from the command, nil if it is not set
+func GetThresholdsFlagValue(c *cobra.Command) ([]int, error) {
+	val := c.Flag(flagThresholds).Value.String()
+	if len(val) == 0 {
+		return nil, nil
+	}
+
+	parts := strings.Split(val, domainsSeparator)
+	thresholds := make([]int, len(parts))
+	for i, part := range parts {
+		days, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || days <= 0 {
+			return nil, errors.Errorf("invalid --%s value '%s', expected positive numbers of days", flagThresholds, val)
+		}
+
+		thresholds[i] = days
+	}
+
+	return thresholds, nil
+}
+
+// AddEndpointsFlag adds the endpoints flag to the command
+func AddEndpointsFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagEndpoints, false, "Use --endpoints flag for checking certificates served by TLS endpoints of the domains as well", false)
+}
+
+// GetEndpointsFlagValue gets the value of the endpoints flag from the command
+func GetEndpointsFlagValue(c *cobra.Command) bool {
+	return c.Flag(flagEndpoints).Value.String() == "true"
+}
@@ -19,6 +19,10 @@ const (
 	flagStaging     = "staging"
 	flagTopic       = "topic"
 	flagRenewBefore = "renew-before"
+	flagForce       = "force"
+	flagDisableARI  = "disable-ari"
+	flagCAA         = "caa"
+	flagDryRun      = "dry-run"
 )
 
 // AddDomainsFlag adds the domains flag to the command
@@ -26,9 +30,18 @@ func AddDomainsFlag(c *cobra.Command) {
 	AddPersistentStringFlag(c, flagDomains, "", "The domains list, comma-separated", true)
 }
 
+// AddOptionalDomainsFlag adds the domains flag which may be omitted to the command
+func AddOptionalDomainsFlag(c *cobra.Command, description string) {
+	AddPersistentStringFlag(c, flagDomains, "", description, false)
+}
+
 // GetDomainsFlagValue gets the value of the domains list from command
 func GetDomainsFlagValue(c *cobra.Command) []string {
 	domainsString := c.Flag(flagDomains).Value.String()
+	if len(domainsString) == 0 {
+		return nil
+	}
+
 	return strings.Split(domainsString, domainsSeparator)
 }
 
@@ -47,9 +60,15 @@ func AddConfigPathFlag(c *cobra.Command) {
 	AddPersistentStringFlag(c, flagConfigPath, defaultConfigPath, "The path to config directory", false)
 }
 
-// GetConfigPathFlagValue gets the value of the config path flag from the command
+// GetConfigPathFlagValue gets the value of the config path flag from the command.
+// Returns empty string if the command does not have the flag.
 func GetConfigPathFlagValue(c *cobra.Command) string {
-	return c.Flag(flagConfigPath).Value.String()
+	f := c.Flag(flagConfigPath)
+	if f == nil {
+		return ""
+	}
+
+	return f.Value.String()
 }
 
 // AddConfigPathFlag adds the staging flag to the command
@@ -86,3 +105,47 @@ func GetRenewBeforeFlagValue(c *cobra.Command) int {
 
 	return days
 }
+
+// AddCAAFlag adds the caa flag to the command
+func AddCAAFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagCAA, "", "Verification of CAA records before requesting certificates: check, upsert (update them in Route 53 to authorize the CA) or disabled. check if not set", false)
+}
+
+// GetCAAFlagValue gets the value of the caa flag from the command
+func GetCAAFlagValue(c *cobra.Command) string {
+	return c.Flag(flagCAA).Value.String()
+}
+
+// AddForceFlag adds the force flag to the command
+func AddForceFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagForce, false, "Use --force flag for renewing certificates regardless of their expiration date", false)
+}
+
+// GetForceFlagValue gets the value of the force flag from the command
+func GetForceFlagValue(c *cobra.Command) bool {
+	return c.Flag(flagForce).Value.String() == "true"
+}
+
+// AddDisableARIFlag adds the disable-ari flag to the command
+func AddDisableARIFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagDisableARI, false, "Use --disable-ari flag for ignoring ACME Renewal Information and renewing certificates only within the renew-before period", false)
+}
+
+// GetDisableARIFlagValue gets the value of the disable-ari flag from the command.
+// Returns false if the command does not have the flag.
+func GetDisableARIFlagValue(c *cobra.Command) bool {
+	f := c.Flag(flagDisableARI)
+	return f != nil && f.Value.String() == "true"
+}
+
+// AddDryRunFlag adds the dry-run flag to the command
+func AddDryRunFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagDryRun, false, "Use --dry-run flag for printing what would be done without requesting certificates, changing records and stores or sending notifications", false)
+}
+
+// GetDryRunFlagValue gets the value of the dry-run flag from the command.
+// Returns false if the command does not have the flag.
+func GetDryRunFlagValue(c *cobra.Command) bool {
+	f := c.Flag(flagDryRun)
+	return f != nil && f.Value.String() == "true"
+}
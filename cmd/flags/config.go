@@ -0,0 +1,66 @@
+package flags
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	groupsSeparator = ","
+
+	flagConfig      = "config"
+	flagGroups      = "groups"
+	flagParallelism = "parallelism"
+)
+
+// AddConfigFlag adds the config flag to the command
+func AddConfigFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagConfig, "", "The location of YAML or JSON configuration file of certificate groups: local path, s3://<bucket>/<key> or ssm:<parameter-name>", false)
+}
+
+// GetConfigFlagValue gets the value of the config flag from the command
+func GetConfigFlagValue(c *cobra.Command) string {
+	return c.Flag(flagConfig).Value.String()
+}
+
+// AddGroupsFlag adds the groups flag to the command
+func AddGroupsFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagGroups, "", "The names of certificate groups from the configuration file, comma-separated. All groups are used if empty", false)
+}
+
+// GetGroupsFlagValue gets the value of the groups flag from the command
+func GetGroupsFlagValue(c *cobra.Command) []string {
+	groupsString := c.Flag(flagGroups).Value.String()
+	if len(groupsString) == 0 {
+		return nil
+	}
+
+	return strings.Split(groupsString, groupsSeparator)
+}
+
+// AddOptionalEmailFlag adds the email flag which may be omitted to the command
+func AddOptionalEmailFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagEmail, "", "E-mail address where Let's Encrypt will send certificate expiry notices to, required without --config", false)
+}
+
+// IsStagingFlagSet checks if the staging flag is set explicitly
+func IsStagingFlagSet(c *cobra.Command) bool {
+	return c.Flag(flagStaging).Changed
+}
+
+// AddParallelismFlag adds the parallelism flag to the command
+func AddParallelismFlag(c *cobra.Command) {
+	AddPersistentIntFlag(c, flagParallelism, 0, "The maximum number of certificate groups processed at once, overrides the configuration file. 5 if not set", false)
+}
+
+// GetParallelismFlagValue gets the value of the parallelism flag from the command, 0 if not set
+func GetParallelismFlagValue(c *cobra.Command) int {
+	parallelism, err := strconv.Atoi(c.Flag(flagParallelism).Value.String())
+	if err != nil {
+		return 0
+	}
+
+	return parallelism
+}
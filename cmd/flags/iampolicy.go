@@ -0,0 +1,85 @@
+package flags
+
+import (
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagAccountID       = "account-id"
+	flagRestrictRecords = "restrict-records"
+	flagAllowDelete     = "allow-delete"
+	flagFunction        = "function"
+	flagContinueAsync   = "continue-async"
+	flagHTTP            = "http"
+	flagQueue           = "queue"
+)
+
+// AddAccountIDFlag adds the account-id flag to the command
+func AddAccountIDFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagAccountID, "", "The ID of AWS account of the resources, the account of the credentials if not set", false)
+}
+
+// GetAccountIDFlagValue gets the value of the account-id flag from the command
+func GetAccountIDFlagValue(c *cobra.Command) string {
+	return c.Flag(flagAccountID).Value.String()
+}
+
+// AddRestrictRecordsFlag adds the restrict-records flag to the command
+func AddRestrictRecordsFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagRestrictRecords, false, "Use --restrict-records flag for limiting changes of Route 53 records to _acme-challenge TXT records of the domains", false)
+}
+
+// GetRestrictRecordsFlagValue gets the value of the restrict-records flag from the command
+func GetRestrictRecordsFlagValue(c *cobra.Command) bool {
+	return c.Flag(flagRestrictRecords).Value.String() == "true"
+}
+
+// AddAllowDeleteFlag adds the allow-delete flag to the command
+func AddAllowDeleteFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagAllowDelete, false, "Use --allow-delete flag for allowing to delete certificates from ACM, required by revoke with --delete or --replace", false)
+}
+
+// GetAllowDeleteFlagValue gets the value of the allow-delete flag from the command
+func GetAllowDeleteFlagValue(c *cobra.Command) bool {
+	return c.Flag(flagAllowDelete).Value.String() == "true"
+}
+
+// AddFunctionFlag adds the function flag to the command
+func AddFunctionFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagFunction, "", "The name or ARN of the Lambda function, required by --continue-async and --http", false)
+}
+
+// GetFunctionFlagValue gets the value of the function flag from the command
+func GetFunctionFlagValue(c *cobra.Command) string {
+	return c.Flag(flagFunction).Value.String()
+}
+
+// AddContinueAsyncFlag adds the continue-async flag to the command
+func AddContinueAsyncFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagContinueAsync, false, "Use --continue-async flag for allowing the function to invoke itself with unprocessed groups, see CONTINUE_ASYNC", false)
+}
+
+// GetContinueAsyncFlagValue gets the value of the continue-async flag from the command
+func GetContinueAsyncFlagValue(c *cobra.Command) bool {
+	return c.Flag(flagContinueAsync).Value.String() == "true"
+}
+
+// AddHTTPFlag adds the http flag to the command
+func AddHTTPFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagHTTP, false, "Use --http flag for allowing the function to obtain certificates requested by HTTP", false)
+}
+
+// GetHTTPFlagValue gets the value of the http flag from the command
+func GetHTTPFlagValue(c *cobra.Command) bool {
+	return c.Flag(flagHTTP).Value.String() == "true"
+}
+
+// AddQueueFlag adds the queue flag to the command
+func AddQueueFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagQueue, "", "The ARN of the SQS queue triggering the function", false)
+}
+
+// GetQueueFlagValue gets the value of the queue flag from the command
+func GetQueueFlagValue(c *cobra.Command) string {
+	return c.Flag(flagQueue).Value.String()
+}
@@ -0,0 +1,43 @@
+package flags
+
+import (
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultReason = "unspecified"
+
+	flagReason  = "reason"
+	flagDelete  = "delete"
+	flagReplace = "replace"
+)
+
+// AddReasonFlag adds the revocation reason flag to the command
+func AddReasonFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagReason, defaultReason, "RFC 5280 revocation reason: unspecified, keyCompromise, affiliationChanged, superseded or cessationOfOperation", false)
+}
+
+// GetReasonFlagValue gets the value of the revocation reason flag from the command
+func GetReasonFlagValue(c *cobra.Command) string {
+	return c.Flag(flagReason).Value.String()
+}
+
+// AddDeleteFlag adds the delete flag to the command
+func AddDeleteFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagDelete, false, "Use --delete flag for removing the revoked certificate from the store", false)
+}
+
+// GetDeleteFlagValue gets the value of the delete flag from the command
+func GetDeleteFlagValue(c *cobra.Command) bool {
+	return c.Flag(flagDelete).Value.String() == "true"
+}
+
+// AddReplaceFlag adds the replace flag to the command
+func AddReplaceFlag(c *cobra.Command) {
+	AddPersistentBoolFlag(c, flagReplace, false, "Use --replace flag for obtaining a new certificate instead of the revoked one", false)
+}
+
+// GetReplaceFlagValue gets the value of the replace flag from the command
+func GetReplaceFlagValue(c *cobra.Command) bool {
+	return c.Flag(flagReplace).Value.String() == "true"
+}
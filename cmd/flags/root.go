@@ -3,6 +3,7 @@ package flags
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -62,3 +63,17 @@ func AddPersistentIntFlag(c *cobra.Command, flag string, value int, description
 		c.MarkPersistentFlagRequired(flag)
 	}
 }
+
+// AddPersistentDurationFlag adds a duration flag to the command
+func AddPersistentDurationFlag(c *cobra.Command, flag string, value time.Duration, description string, isRequired bool) {
+	req := ""
+	if isRequired {
+		req = " (required)"
+	}
+
+	c.PersistentFlags().Duration(flag, value, fmt.Sprintf("%s%s", description, req))
+
+	if isRequired {
+		c.MarkPersistentFlagRequired(flag)
+	}
+}
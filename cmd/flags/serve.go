@@ -0,0 +1,69 @@
+package flags
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultInterval        = 12 * time.Hour
+	defaultJitter          = 30 * time.Minute
+	defaultListen          = ":8080"
+	defaultShutdownTimeout = 5 * time.Minute
+
+	flagInterval        = "interval"
+	flagJitter          = "jitter"
+	flagListen          = "listen"
+	flagShutdownTimeout = "shutdown-timeout"
+)
+
+// AddIntervalFlag adds the interval flag to the command
+func AddIntervalFlag(c *cobra.Command) {
+	AddPersistentDurationFlag(c, flagInterval, defaultInterval, "The period between checks of each certificate", false)
+}
+
+// GetIntervalFlagValue gets the value of the interval flag from the command
+func GetIntervalFlagValue(c *cobra.Command) time.Duration {
+	return getDurationFlagValue(c, flagInterval, defaultInterval)
+}
+
+// AddJitterFlag adds the jitter flag to the command
+func AddJitterFlag(c *cobra.Command) {
+	AddPersistentDurationFlag(c, flagJitter, defaultJitter, "The maximum random delay added to each check to spread them over time", false)
+}
+
+// GetJitterFlagValue gets the value of the jitter flag from the command
+func GetJitterFlagValue(c *cobra.Command) time.Duration {
+	return getDurationFlagValue(c, flagJitter, defaultJitter)
+}
+
+// AddListenFlag adds the listen flag to the command
+func AddListenFlag(c *cobra.Command) {
+	AddPersistentStringFlag(c, flagListen, defaultListen, "The address of HTTP health and status endpoints, empty to disable", false)
+}
+
+// GetListenFlagValue gets the value of the listen flag from the command
+func GetListenFlagValue(c *cobra.Command) string {
+	return c.Flag(flagListen).Value.String()
+}
+
+// AddShutdownTimeoutFlag adds the shutdown-timeout flag to the command
+func AddShutdownTimeoutFlag(c *cobra.Command) {
+	AddPersistentDurationFlag(c, flagShutdownTimeout, defaultShutdownTimeout, "The time to wait for in-flight orders on shutdown before aborting them", false)
+}
+
+// GetShutdownTimeoutFlagValue gets the value of the shutdown-timeout flag from the command
+func GetShutdownTimeoutFlagValue(c *cobra.Command) time.Duration {
+	return getDurationFlagValue(c, flagShutdownTimeout, defaultShutdownTimeout)
+}
+
+// getDurationFlagValue gets the value of the given duration flag from the command
+func getDurationFlagValue(c *cobra.Command, flag string, defaultValue time.Duration) time.Duration {
+	d, err := time.ParseDuration(c.Flag(flag).Value.String())
+	if err != nil {
+		return defaultValue
+	}
+
+	return d
+}
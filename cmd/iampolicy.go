@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/begmaroman/acme-dns-route53/cmd/flags"
+	"github.com/begmaroman/acme-dns-route53/iampolicy"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// iamPolicyCmd represents the IAM policy generation command
+var iamPolicyCmd = &cobra.Command{
+	Use:   "iam-policy",
+	Short: "Print the least-privilege IAM policy",
+	Long:  `This command resolves hosted zones, ACM regions, SNS topics and the configuration file location of the given domains or certificate groups of the configuration file, and prints the minimal IAM policy allowing to manage their certificates.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Load certificate groups
+		conf, err := loadConfig(cmd, false)
+		if err != nil {
+			return err
+		}
+
+		opts := &iampolicy.Options{
+			Partition:       "aws",
+			AccountID:       flags.GetAccountIDFlagValue(cmd),
+			Region:          aws.StringValue(AWSSession.Config.Region),
+			Groups:          conf.Groups,
+			ConfigLocation:  flags.GetConfigFlagValue(cmd),
+			RestrictRecords: flags.GetRestrictRecordsFlagValue(cmd),
+			Delete:          flags.GetAllowDeleteFlagValue(cmd),
+			Function:        flags.GetFunctionFlagValue(cmd),
+			ContinueAsync:   flags.GetContinueAsyncFlagValue(cmd),
+			HTTP:            flags.GetHTTPFlagValue(cmd),
+			Queue:           flags.GetQueueFlagValue(cmd),
+		}
+
+		if (opts.ContinueAsync || opts.HTTP) && len(opts.Function) == 0 {
+			return errors.New("--function is required by --continue-async and --http")
+		}
+
+		if len(opts.AccountID) == 0 {
+			identity, err := sts.New(AWSSession).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+			if err != nil {
+				return errors.Wrap(err, "unable to get the account of the credentials, use --account-id flag")
+			}
+
+			opts.AccountID = aws.StringValue(identity.Account)
+			if parsed, err := arn.Parse(aws.StringValue(identity.Arn)); err == nil {
+				opts.Partition = parsed.Partition
+			}
+		}
+
+		// Resolve hosted zones where challenge records are created
+		for _, job := range runner.NewJobs(conf.Groups, newRunnerOptions(cmd, logrus.New())) {
+			for _, domain := range job.Group.Domains {
+				zoneID, err := job.DNS01.HostedZone(domain)
+				if err != nil {
+					return errors.Wrapf(err, "unable to resolve hosted zone of '%s'", domain)
+				}
+
+				opts.HostedZoneIDs = append(opts.HostedZoneIDs, zoneID)
+			}
+		}
+
+		data, err := json.MarshalIndent(iampolicy.NewDocument(iampolicy.Required(opts)), "", "  ")
+		if err != nil {
+			return err
+		}
+
+		cmd.Println(string(data))
+
+		return nil
+	},
+}
+
+func init() {
+	addConfigFlags(iamPolicyCmd, "The domains list, comma-separated, required without --config")
+	flags.AddAccountIDFlag(iamPolicyCmd)
+	flags.AddRestrictRecordsFlag(iamPolicyCmd)
+	flags.AddAllowDeleteFlag(iamPolicyCmd)
+	flags.AddFunctionFlag(iamPolicyCmd)
+	flags.AddContinueAsyncFlag(iamPolicyCmd)
+	flags.AddHTTPFlag(iamPolicyCmd)
+	flags.AddQueueFlag(iamPolicyCmd)
+
+	RootCmd.AddCommand(iamPolicyCmd)
+}
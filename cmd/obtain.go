@@ -1,67 +1,49 @@
 package cmd
 
 import (
-	"sync"
+	"context"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
-	"github.com/begmaroman/acme-dns-route53/certstore/acmstore"
 	"github.com/begmaroman/acme-dns-route53/cmd/flags"
 	"github.com/begmaroman/acme-dns-route53/handler"
-	"github.com/begmaroman/acme-dns-route53/handler/r53dns"
-	"github.com/begmaroman/acme-dns-route53/notifier/awsns"
+	"github.com/begmaroman/acme-dns-route53/runner"
 )
 
 // certificateObtainCmd represents the certificate obtaining command
 var certificateObtainCmd = &cobra.Command{
 	Use:   "obtain",
 	Short: "Obtain SSL certificates",
-	Long:  `This command creates new SSL certificates or renews existing ones for the given domains using the given parameters.`,
+	Long:  `This command creates new SSL certificates or renews existing ones for the given domains or certificate groups of the configuration file using the given parameters.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Inits needed parameters
-		domains := flags.GetDomainsFlagValue(cmd)
-		email := flags.GetEmailFlagValue(cmd)
+		// Load certificate groups
+		conf, err := loadConfig(cmd, false)
+		if err != nil {
+			return err
+		}
 
 		// Init a common logger
 		log := logrus.New()
 
-		// Create a new certificates handler
-		h := handler.NewCertificateHandler(&handler.CertificateHandlerOptions{
-			ConfigDir:         flags.GetConfigPathFlagValue(cmd),
-			Staging:           flags.GetStagingFlagValue(cmd),
-			NotificationTopic: flags.GetTopicFlagValue(cmd),
-			RenewBefore:       flags.GetRenewBeforeFlagValue(cmd) * 24,
-			Log:               log,
-			Notifier:          awsns.New(AWSSession, log),    // Initialize SNS API client
-			DNS01:             r53dns.New(AWSSession, log),   // Initialize DNS-01 challenge provider by Route 53
-			Store:             acmstore.New(AWSSession, log), // Initialize ACM client
-		})
+		// Create certificates handlers of the groups
+		jobs := runner.NewJobs(conf.Groups, newRunnerOptions(cmd, log))
 
-		var wg sync.WaitGroup
-		for _, domain := range domains {
-			wg.Add(1)
-			go func(domainList []string) {
-				defer wg.Done()
+		summary := runner.Run(context.Background(), jobs, getParallelism(cmd, conf), func(job *runner.Job) (*handler.Result, error) {
+			return job.Handler.Obtain(job.Group.Domains, job.Group.Email)
+		})
 
-				if err := h.Obtain(domainList, email); err != nil {
-					logrus.Errorf("[%s] unable to obtain certificate: %s\n", domain, err)
-				}
-			}([]string{domain})
-		}
-		wg.Wait()
+		summary.DryRun = flags.GetDryRunFlagValue(cmd)
 
-		return nil
+		return printSummary(cmd, summary)
 	},
 }
 
 func init() {
-	flags.AddDomainsFlag(certificateObtainCmd)
-	flags.AddEmailFlag(certificateObtainCmd)
+	addConfigFlags(certificateObtainCmd, "The domains list, comma-separated, required without --config")
 	flags.AddConfigPathFlag(certificateObtainCmd)
-	flags.AddStagingFlag(certificateObtainCmd)
-	flags.AddTopicFlag(certificateObtainCmd)
-	flags.AddRenewBeforeFlag(certificateObtainCmd)
+	flags.AddDisableARIFlag(certificateObtainCmd)
+	flags.AddDryRunFlag(certificateObtainCmd)
 
 	RootCmd.AddCommand(certificateObtainCmd)
 }
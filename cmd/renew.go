@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/begmaroman/acme-dns-route53/cmd/flags"
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// certificateRenewCmd represents the certificate renewal command
+var certificateRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew SSL certificates",
+	Long:  `This command renews existing SSL certificates for the given domains or certificate groups of the configuration file, or all certificates managed by this tool if neither is provided. New certificates are never issued.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Load certificate groups
+		conf, err := loadConfig(cmd, true)
+		if err != nil {
+			return err
+		}
+
+		force := flags.GetForceFlagValue(cmd)
+
+		// Init a common logger
+		log := logrus.New()
+
+		opts := newRunnerOptions(cmd, log)
+
+		// Discover all managed certificates if groups are not provided
+		if len(conf.Groups) == 0 {
+			domainsList, err := runner.NewJob(conf.NewGroup(nil), opts).Handler.ManagedDomains()
+			if err != nil {
+				return err
+			}
+
+			log.Infof("found %d managed certificates", len(domainsList))
+
+			for _, domains := range domainsList {
+				conf.Groups = append(conf.Groups, conf.NewGroup(domains))
+			}
+
+			if len(conf.Groups) == 0 {
+				return nil
+			}
+
+			if err := conf.Init(); err != nil {
+				return configError(err)
+			}
+		}
+
+		// Create certificates handlers of the groups
+		jobs := runner.NewJobs(conf.Groups, opts)
+
+		summary := runner.Run(context.Background(), jobs, getParallelism(cmd, conf), func(job *runner.Job) (*handler.Result, error) {
+			return job.Handler.Renew(job.Group.Domains, job.Group.Email, force)
+		})
+
+		return printSummary(cmd, summary)
+	},
+}
+
+func init() {
+	addConfigFlags(certificateRenewCmd, "The domains list, comma-separated. All certificates managed by this tool are renewed if neither domains nor --config are provided")
+	flags.AddConfigPathFlag(certificateRenewCmd)
+	flags.AddDisableARIFlag(certificateRenewCmd)
+	flags.AddForceFlag(certificateRenewCmd)
+
+	RootCmd.AddCommand(certificateRenewCmd)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/begmaroman/acme-dns-route53/cmd/flags"
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// errRevokeGroupsMissing is the error when certificate groups to revoke are not selected from the configuration file
+var errRevokeGroupsMissing = errors.New("--groups flag must be provided to revoke certificates of the configuration file")
+
+// certificateRevokeCmd represents the certificate revocation command
+var certificateRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke SSL certificates",
+	Long:  `This command revokes existing SSL certificates of the given domains or certificate groups of the configuration file with the given reason, and optionally deletes or replaces them in the store.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Revoking all groups of the configuration by mistake is too dangerous
+		if len(flags.GetConfigFlagValue(cmd)) > 0 && len(flags.GetGroupsFlagValue(cmd)) == 0 {
+			return configError(errRevokeGroupsMissing)
+		}
+
+		// Load certificate groups
+		conf, err := loadConfig(cmd, false)
+		if err != nil {
+			return err
+		}
+
+		reason, err := handler.ParseRevocationReason(flags.GetReasonFlagValue(cmd))
+		if err != nil {
+			return configError(err)
+		}
+
+		opts := &handler.RevokeOptions{
+			Reason:  reason,
+			Delete:  flags.GetDeleteFlagValue(cmd),
+			Replace: flags.GetReplaceFlagValue(cmd),
+		}
+
+		if opts.Delete && opts.Replace {
+			return configError(handler.ErrRevokeDeleteAndReplace)
+		}
+
+		// Init a common logger
+		log := logrus.New()
+
+		// Create certificates handlers of the groups
+		jobs := runner.NewJobs(conf.Groups, newRunnerOptions(cmd, log))
+
+		summary := runner.Run(context.Background(), jobs, getParallelism(cmd, conf), func(job *runner.Job) (*handler.Result, error) {
+			return job.Handler.Revoke(job.Group.Domains, job.Group.Email, opts)
+		})
+
+		return printSummary(cmd, summary)
+	},
+}
+
+func init() {
+	addConfigFlags(certificateRevokeCmd, "The domains list, comma-separated, required without --config")
+	flags.AddConfigPathFlag(certificateRevokeCmd)
+	flags.AddReasonFlag(certificateRevokeCmd)
+	flags.AddDeleteFlag(certificateRevokeCmd)
+	flags.AddReplaceFlag(certificateRevokeCmd)
+
+	RootCmd.AddCommand(certificateRevokeCmd)
+}
@@ -31,6 +31,15 @@ func init() {
 
 	// Initialized AWS session
 	AWSSession = session.Must(session.NewSession())
+
+	// Invalid flags are configuration errors
+	RootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return configError(err)
+	})
+
+	// Usage is not helpful for failed certificates, and errors are printed by Execute
+	RootCmd.SilenceUsage = true
+	RootCmd.SilenceErrors = true
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -38,6 +47,6 @@ func init() {
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
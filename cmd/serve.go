@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/begmaroman/acme-dns-route53/cmd/flags"
+	"github.com/begmaroman/acme-dns-route53/daemon"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// serveCmd represents the daemon command
+var serveCmd = &cobra.Command{
+	Use:     "serve",
+	Aliases: []string{"daemon"},
+	Short:   "Run as a daemon renewing SSL certificates periodically",
+	Long:    `This command keeps running and periodically obtains or renews SSL certificates for the given domains or certificate groups of the configuration file, exposing HTTP health and status endpoints.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Load certificate groups
+		conf, err := loadConfig(cmd, false)
+		if err != nil {
+			return err
+		}
+
+		// Init a common logger
+		log := logrus.New()
+
+		d, err := daemon.New(&daemon.Options{
+			Jobs:        runner.NewJobs(conf.Groups, newRunnerOptions(cmd, log)),
+			Interval:    flags.GetIntervalFlagValue(cmd),
+			Jitter:      flags.GetJitterFlagValue(cmd),
+			Parallelism: getParallelism(cmd, conf),
+			Log:         log,
+		})
+		if err != nil {
+			return configError(err)
+		}
+
+		// Start health and status endpoints
+		var server *http.Server
+		if listen := flags.GetListenFlagValue(cmd); len(listen) > 0 {
+			server = &http.Server{Addr: listen, Handler: d.HTTPHandler()}
+			go func() {
+				log.Infof("serve: listening on %s", listen)
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Errorf("serve: HTTP server failed: %s", err)
+				}
+			}()
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			d.Run(ctx)
+		}()
+
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+		select {
+		case <-done:
+		case sig := <-signals:
+			log.Infof("serve: received %s, waiting for in-flight orders to finish", sig)
+
+			d.Stopping()
+			cancel()
+
+			shutdownTimeout := flags.GetShutdownTimeoutFlagValue(cmd)
+			select {
+			case <-done:
+				log.Infof("serve: in-flight orders finished")
+			case <-time.After(shutdownTimeout):
+				log.Warnf("serve: in-flight orders did not finish within %s, aborting", shutdownTimeout)
+			case sig = <-signals:
+				log.Warnf("serve: received %s again, aborting in-flight orders", sig)
+			}
+
+			// Remove challenge records of the aborted orders, it is no-op if all orders finished
+			if err := d.CleanUp(); err != nil {
+				log.Errorf("serve: unable to clean up DNS records: %s", err)
+			}
+		}
+
+		if server != nil {
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer shutdownCancel()
+
+			server.Shutdown(shutdownCtx)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	addConfigFlags(serveCmd, "The domains list, comma-separated, required without --config")
+	flags.AddConfigPathFlag(serveCmd)
+	flags.AddDisableARIFlag(serveCmd)
+	flags.AddIntervalFlag(serveCmd)
+	flags.AddJitterFlag(serveCmd)
+	flags.AddListenFlag(serveCmd)
+	flags.AddShutdownTimeoutFlag(serveCmd)
+
+	RootCmd.AddCommand(serveCmd)
+}
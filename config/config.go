@@ -0,0 +1,481 @@
+package config
+
+import (
+	"time"
+
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/lego"
+)
+
+const (
+	// CAProduction is the name of Let's Encrypt production environment
+	CAProduction = "production"
+
+	// CAStaging is the name of Let's Encrypt staging environment
+	CAStaging = "staging"
+
+	// StoreTypeACM is the type of the store which keeps certificates in Amazon Certificate Manager
+	StoreTypeACM = "acm"
+
+	// NotificationTypeSNS is the type of the notification published to Amazon Simple Notification Service
+	NotificationTypeSNS = "sns"
+
+	// NotificationTypeSlack is the type of the notification posted to Slack by an incoming webhook or a bot token
+	NotificationTypeSlack = "slack"
+
+	// NotificationTypeTeams is the type of the notification posted to Microsoft Teams by a workflow webhook
+	NotificationTypeTeams = "teams"
+
+	// NotificationTypeWebhook is the type of the notification posted as JSON event to a signed webhook
+	NotificationTypeWebhook = "webhook"
+
+	// NotificationTypeSMTP is the type of the notification sent as email via an SMTP server
+	NotificationTypeSMTP = "smtp"
+
+	// NotificationTypeSES is the type of the notification sent as email via Amazon Simple Email Service
+	NotificationTypeSES = "ses"
+
+	// HookTypeCommand is the type of the deploy hook which runs a local shell command
+	HookTypeCommand = "command"
+
+	// HookTypeLambda is the type of the deploy hook which invokes a Lambda function
+	HookTypeLambda = "lambda"
+
+	// HookTypeSSM is the type of the deploy hook which runs a shell command on instances by SSM Run Command
+	HookTypeSSM = "ssm"
+
+	// HookTypeEventBridge is the type of the deploy hook which publishes an event to the default event bus of EventBridge
+	HookTypeEventBridge = "eventbridge"
+
+	// HookTypeELBv2 is the type of the deploy hook which attaches the certificate to a listener of Application or Network Load Balancer
+	HookTypeELBv2 = "elbv2"
+
+	// HookTypeCloudFront is the type of the deploy hook which sets the viewer certificate of a CloudFront distribution
+	HookTypeCloudFront = "cloudfront"
+
+	// HookTypeAPIGateway is the type of the deploy hook which sets the certificate of an API Gateway custom domain name
+	HookTypeAPIGateway = "apigateway"
+
+	// CAACheck means that CAA records must authorize the CA before requesting certificates, the default
+	CAACheck = "check"
+
+	// CAAUpsert means that CAA records are updated in Route 53 if they do not authorize the CA
+	CAAUpsert = "upsert"
+
+	// CAADisabled means that CAA records are not verified
+	CAADisabled = "disabled"
+
+	// DefaultKeyType is the default key type of certificates
+	DefaultKeyType = "rsa2048"
+
+	// DefaultRenewBefore is the default number of days before expiration within which a certificate must be renewed
+	DefaultRenewBefore = 30
+
+	// DefaultFailureInterval is the default period within which a failure of a certificate is notified once
+	DefaultFailureInterval = 24 * time.Hour
+)
+
+var (
+	// keyTypes maps names of key types to their lego values, RSA 8192 keys are not supported since ACM cannot import them
+	keyTypes = map[string]certcrypto.KeyType{
+		"rsa2048": certcrypto.RSA2048,
+		"rsa4096": certcrypto.RSA4096,
+		"ec256":   certcrypto.EC256,
+		"ec384":   certcrypto.EC384,
+	}
+
+	// DefaultExpiryThresholds are the default numbers of days before expiration at which expiring certificates are notified
+	DefaultExpiryThresholds = []int{21, 14, 7, 3, 1}
+
+	// caDirURLs maps names of well-known CAs to their directory URLs
+	caDirURLs = map[string]string{
+		CAProduction: lego.LEDirectoryProduction,
+		CAStaging:    lego.LEDirectoryStaging,
+	}
+)
+
+// Config is the declarative configuration of certificate groups.
+// Top-level settings are the defaults of all groups.
+type Config struct {
+	Email           string          `json:"email,omitempty" yaml:"email,omitempty"`
+	CA              string          `json:"ca,omitempty" yaml:"ca,omitempty"`
+	KeyType         string          `json:"key_type,omitempty" yaml:"key_type,omitempty"`
+	RenewBefore     int             `json:"renew_before,omitempty" yaml:"renew_before,omitempty"`
+	CAA             string          `json:"caa,omitempty" yaml:"caa,omitempty"`
+	CAAIdentities   []string        `json:"caa_identities,omitempty" yaml:"caa_identities,omitempty"`
+	FailureInterval time.Duration   `json:"failure_interval,omitempty" yaml:"failure_interval,omitempty"`
+	Stores          []*Store        `json:"stores,omitempty" yaml:"stores,omitempty"`
+	Notifications   []*Notification `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+	Parallelism     int             `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
+	Policy          *Policy         `json:"policy,omitempty" yaml:"policy,omitempty"`
+	Expiry          *Expiry         `json:"expiry,omitempty" yaml:"expiry,omitempty"`
+
+	// Notifiers are notification targets by their names, events are dispatched to them by routes
+	Notifiers map[string]*Notification `json:"notifiers,omitempty" yaml:"notifiers,omitempty"`
+	Routes    []*Route                 `json:"routes,omitempty" yaml:"routes,omitempty"`
+
+	Groups []*Group `json:"groups" yaml:"groups"`
+}
+
+// Group is the group of domains which share one certificate
+type Group struct {
+	Name            string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Domains         []string          `json:"domains" yaml:"domains"`
+	Email           string            `json:"email,omitempty" yaml:"email,omitempty"`
+	CA              string            `json:"ca,omitempty" yaml:"ca,omitempty"`
+	KeyType         string            `json:"key_type,omitempty" yaml:"key_type,omitempty"`
+	RenewBefore     int               `json:"renew_before,omitempty" yaml:"renew_before,omitempty"`
+	CAA             string            `json:"caa,omitempty" yaml:"caa,omitempty"`
+	CAAIdentities   []string          `json:"caa_identities,omitempty" yaml:"caa_identities,omitempty"`
+	FailureInterval time.Duration     `json:"failure_interval,omitempty" yaml:"failure_interval,omitempty"`
+	Stores          []*Store          `json:"stores,omitempty" yaml:"stores,omitempty"`
+	Notifications   []*Notification   `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+	HostedZones     map[string]string `json:"hosted_zones,omitempty" yaml:"hosted_zones,omitempty"`
+	Deploy          []*Hook           `json:"deploy,omitempty" yaml:"deploy,omitempty"`
+
+	policy *Policy       // The policy of the configuration, groups cannot override it
+	routes []*GroupRoute // Routes of the configuration which apply to the group
+}
+
+// Store is the place where certificates are stored
+type Store struct {
+	Type   string `json:"type" yaml:"type"`
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+}
+
+// Policy is the set of rules all certificates must comply with before they are requested from the CA
+type Policy struct {
+	// AllowedSuffixes are the zones which domains must belong to, any domain is allowed if empty
+	AllowedSuffixes []string `json:"allowed_suffixes,omitempty" yaml:"allowed_suffixes,omitempty"`
+
+	// DeniedNames are the domains which must not be included into certificates
+	DeniedNames []string `json:"denied_names,omitempty" yaml:"denied_names,omitempty"`
+
+	// AllowWildcard permits wildcard domains, true if not set
+	AllowWildcard *bool `json:"allow_wildcard,omitempty" yaml:"allow_wildcard,omitempty"`
+
+	// MaxSANs is the maximum number of domains of a certificate, unlimited if zero
+	MaxSANs int `json:"max_sans,omitempty" yaml:"max_sans,omitempty"`
+
+	// AllowedKeyTypes are the names of permitted key types, any key type is allowed if empty
+	AllowedKeyTypes []string `json:"allowed_key_types,omitempty" yaml:"allowed_key_types,omitempty"`
+
+	// AllowedCAs are the names or directory URLs of permitted CAs, any CA is allowed if empty
+	AllowedCAs []string `json:"allowed_cas,omitempty" yaml:"allowed_cas,omitempty"`
+}
+
+// Route dispatches events of groups to notifiers in addition to notifications of the groups
+type Route struct {
+	// Events are types of events dispatched by the route, all but RenewalSkipped if empty
+	Events []string `json:"events,omitempty" yaml:"events,omitempty"`
+
+	// Groups are names of groups whose events are dispatched by the route, all groups if empty
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+
+	// Notify are names of notifiers the events are dispatched to
+	Notify []string `json:"notify" yaml:"notify"`
+}
+
+// GroupRoute is the route which applies to the group with the notifiers it dispatches events to
+type GroupRoute struct {
+	Events        []string
+	Notifications []*Notification
+}
+
+// Hook deploys the certificate of the group after it has been issued or renewed and stored
+type Hook struct {
+	Type string `json:"type" yaml:"type"`
+
+	// Name is the name of the hook in results, the type if empty
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Timeout is the timeout of the hook, e.g. "30s", 5 minutes if not set
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// Command is the shell command run locally or on instances by SSM Run Command
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+
+	// Dir is the directory where certificate files are written for local commands, the temporary directory if empty
+	Dir string `json:"dir,omitempty" yaml:"dir,omitempty"`
+
+	// Function is the name or ARN of the Lambda function
+	Function string `json:"function,omitempty" yaml:"function,omitempty"`
+
+	// InstanceIDs and Targets select instances of SSM Run Command, targets map keys to values, e.g. "tag:Role": [haproxy]
+	InstanceIDs []string            `json:"instance_ids,omitempty" yaml:"instance_ids,omitempty"`
+	Targets     map[string][]string `json:"targets,omitempty" yaml:"targets,omitempty"`
+
+	// Listener is the ARN of the ELBv2 listener, the certificate is added to its certificate list for SNI
+	// unless DefaultCertificate is set, which replaces the default certificate of the listener
+	Listener           string `json:"listener,omitempty" yaml:"listener,omitempty"`
+	DefaultCertificate bool   `json:"default_certificate,omitempty" yaml:"default_certificate,omitempty"`
+
+	// Distribution is the ID of the CloudFront distribution
+	Distribution string `json:"distribution,omitempty" yaml:"distribution,omitempty"`
+
+	// DomainName is the API Gateway custom domain name
+	DomainName string `json:"domain_name,omitempty" yaml:"domain_name,omitempty"`
+
+	// Region is the region of the Lambda function, SSM instances, EventBridge or API Gateway, the default region if empty
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+}
+
+// HookName returns the name of the hook in results
+func (h *Hook) HookName() string {
+	if len(h.Name) > 0 {
+		return h.Name
+	}
+
+	return h.Type
+}
+
+// Expiry is the settings of the expiry watchdog which audits certificates regardless of their issuance
+type Expiry struct {
+	// Thresholds are the numbers of days before expiration at which expiring certificates are notified, 21, 14, 7, 3 and 1 if empty
+	Thresholds []int `json:"thresholds,omitempty" yaml:"thresholds,omitempty"`
+
+	// Endpoints enables checks of certificates served by TLS endpoints of the domains of groups
+	Endpoints bool `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+}
+
+// Notification is the target which is notified about certificates
+type Notification struct {
+	Type  string `json:"type" yaml:"type"`
+	Topic string `json:"topic,omitempty" yaml:"topic,omitempty"` // SNS topic ARN
+
+	// URL is the URL of the webhook, Slack or Microsoft Teams webhook
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+
+	// Token is the Slack bot token used instead of the webhook, it posts messages to the channel
+	Token   string `json:"token,omitempty" yaml:"token,omitempty"`
+	Channel string `json:"channel,omitempty" yaml:"channel,omitempty"`
+
+	// Secret is the key of HMAC-SHA256 signatures of webhook requests, requests are not signed if empty
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+
+	// Headers are custom headers of webhook requests
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+
+	// Timeout is the timeout of each webhook request, e.g. "10s"
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// Retries is the number of retries of failed webhook requests, 3 if not set
+	Retries *int `json:"retries,omitempty" yaml:"retries,omitempty"`
+
+	// Events are types of events sent to the target, all but RenewalSkipped if empty
+	Events []string `json:"events,omitempty" yaml:"events,omitempty"`
+
+	// Template is the text/template of messages of Slack and Microsoft Teams, and text bodies of emails, executed with the event
+	Template string `json:"template,omitempty" yaml:"template,omitempty"`
+
+	// HTMLTemplate is the html/template of HTML bodies of emails, executed with the event and its rendered Message
+	HTMLTemplate string `json:"html_template,omitempty" yaml:"html_template,omitempty"`
+
+	// From is the sender address of emails, it must be a verified identity for SES
+	From string `json:"from,omitempty" yaml:"from,omitempty"`
+
+	// To are the recipient addresses of emails
+	To []string `json:"to,omitempty" yaml:"to,omitempty"`
+
+	// Host and Port are the address of the SMTP server, port 587 if not set
+	Host string `json:"host,omitempty" yaml:"host,omitempty"`
+	Port int    `json:"port,omitempty" yaml:"port,omitempty"`
+
+	// Username and Password authenticate to the SMTP server, emails are sent without authentication if empty
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+
+	// TLS is the encryption of SMTP connections: starttls (default), tls or none
+	TLS string `json:"tls,omitempty" yaml:"tls,omitempty"`
+
+	// Region is the region of SES, the default region if empty
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+}
+
+// CADirURL returns the directory URL of the CA of the group
+func (g *Group) CADirURL() string {
+	return CADirURL(g.CA)
+}
+
+// CertKeyType returns the key type of the certificate of the group
+func (g *Group) CertKeyType() certcrypto.KeyType {
+	return KeyType(g.KeyType)
+}
+
+// Policy returns the policy of the configuration of the group, nil if there is no policy
+func (g *Group) Policy() *Policy {
+	return g.policy
+}
+
+// Routes returns routes of the configuration which apply to the group
+func (g *Group) Routes() []*GroupRoute {
+	return g.routes
+}
+
+// CADirURL returns the directory URL of the CA with the given name or URL
+func CADirURL(ca string) string {
+	if url, ok := caDirURLs[ca]; ok {
+		return url
+	}
+
+	return ca
+}
+
+// KeyType returns the key type with the given name
+func KeyType(name string) certcrypto.KeyType {
+	return keyTypes[name]
+}
+
+// GroupsByName returns groups with the given names, or all groups if no names given
+func (c *Config) GroupsByName(names []string) ([]*Group, error) {
+	if len(names) == 0 {
+		return c.Groups, nil
+	}
+
+	byName := make(map[string]*Group, len(c.Groups))
+	for _, group := range c.Groups {
+		byName[group.Name] = group
+	}
+
+	groups := make([]*Group, 0, len(names))
+	for _, name := range names {
+		group, ok := byName[name]
+		if !ok {
+			return nil, &ValidationError{Errors: []string{"unknown group '" + name + "'"}}
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// NewGroup creates the group of the given domains with the top-level settings of the configuration.
+// The group is not added to the configuration.
+func (c *Config) NewGroup(domains []string) *Group {
+	c.applyTopLevelDefaults()
+
+	group := &Group{Domains: domains}
+	group.applyDefaults(c)
+
+	return group
+}
+
+// applyDefaults fills empty settings with the defaults
+func (c *Config) applyDefaults() {
+	c.applyTopLevelDefaults()
+
+	for _, group := range c.Groups {
+		group.applyDefaults(c)
+	}
+}
+
+// applyTopLevelDefaults fills empty top-level settings with the defaults
+func (c *Config) applyTopLevelDefaults() {
+	if len(c.CA) == 0 {
+		c.CA = CAProduction
+	}
+
+	if len(c.KeyType) == 0 {
+		c.KeyType = DefaultKeyType
+	}
+
+	if c.RenewBefore == 0 {
+		c.RenewBefore = DefaultRenewBefore
+	}
+
+	if c.FailureInterval == 0 {
+		c.FailureInterval = DefaultFailureInterval
+	}
+
+	if len(c.Stores) == 0 {
+		c.Stores = []*Store{{Type: StoreTypeACM}}
+	}
+
+	if c.Expiry == nil {
+		c.Expiry = &Expiry{}
+	}
+
+	if len(c.Expiry.Thresholds) == 0 {
+		c.Expiry.Thresholds = append([]int(nil), DefaultExpiryThresholds...)
+	}
+}
+
+// applyDefaults fills empty settings of the group with the top-level settings of the given configuration
+func (g *Group) applyDefaults(c *Config) {
+	if len(g.Name) == 0 && len(g.Domains) > 0 {
+		g.Name = g.Domains[0]
+	}
+
+	if len(g.Email) == 0 {
+		g.Email = c.Email
+	}
+
+	if len(g.CA) == 0 {
+		g.CA = c.CA
+	}
+
+	if len(g.KeyType) == 0 {
+		g.KeyType = c.KeyType
+	}
+
+	if g.RenewBefore == 0 {
+		g.RenewBefore = c.RenewBefore
+	}
+
+	if len(g.CAA) == 0 {
+		g.CAA = c.CAA
+	}
+
+	if g.CAAIdentities == nil {
+		g.CAAIdentities = c.CAAIdentities
+	}
+
+	if g.FailureInterval == 0 {
+		g.FailureInterval = c.FailureInterval
+	}
+
+	if g.Stores == nil {
+		g.Stores = c.Stores
+	}
+
+	if g.Notifications == nil {
+		g.Notifications = c.Notifications
+	}
+
+	g.policy = c.Policy
+	g.routes = c.groupRoutes(g.Name)
+}
+
+// groupRoutes returns routes which apply to the group with the given name, unknown notifiers are skipped.
+// Routes which select groups do not apply to the group without name.
+func (c *Config) groupRoutes(name string) []*GroupRoute {
+	var routes []*GroupRoute
+	for _, route := range c.Routes {
+		if len(route.Groups) > 0 && !containsString(route.Groups, name) {
+			continue
+		}
+
+		groupRoute := &GroupRoute{Events: route.Events}
+		for _, notifierName := range route.Notify {
+			if notification := c.Notifiers[notifierName]; notification != nil {
+				groupRoute.Notifications = append(groupRoute.Notifications, notification)
+			}
+		}
+
+		routes = append(routes, groupRoute)
+	}
+
+	return routes
+}
+
+// containsString checks if the given list contains the given value
+func containsString(list []string, val string) bool {
+	for _, item := range list {
+		if item == val {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,394 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/lego"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testTable := []*struct {
+		testName       string
+		data           string
+		expectedGroups []*Group
+		expectedError  string
+	}{
+		{
+			testName: "YAML with defaults",
+			data: `
+email: admin@example.com
+renew_before: 20
+groups:
+  - domains: [example.com, www.example.com]
+  - name: api
+    domains: [api.example.org]
+    ca: staging
+    key_type: ec256
+    hosted_zones:
+      example.org: Z123ABC
+`,
+			expectedGroups: []*Group{
+				{
+					Name:            "example.com",
+					Domains:         []string{"example.com", "www.example.com"},
+					Email:           "admin@example.com",
+					CA:              CAProduction,
+					KeyType:         DefaultKeyType,
+					RenewBefore:     20,
+					FailureInterval: DefaultFailureInterval,
+					Stores:          []*Store{{Type: StoreTypeACM}},
+				},
+				{
+					Name:            "api",
+					Domains:         []string{"api.example.org"},
+					Email:           "admin@example.com",
+					CA:              CAStaging,
+					KeyType:         "ec256",
+					RenewBefore:     20,
+					FailureInterval: DefaultFailureInterval,
+					Stores:          []*Store{{Type: StoreTypeACM}},
+					HostedZones:     map[string]string{"example.org": "Z123ABC"},
+				},
+			},
+		},
+		{
+			testName: "JSON",
+			data:     `{"groups": [{"domains": ["*.example.com"], "email": "admin@example.com", "stores": [{"type": "acm", "region": "us-east-1"}]}]}`,
+			expectedGroups: []*Group{
+				{
+					Name:            "*.example.com",
+					Domains:         []string{"*.example.com"},
+					Email:           "admin@example.com",
+					CA:              CAProduction,
+					KeyType:         DefaultKeyType,
+					RenewBefore:     DefaultRenewBefore,
+					FailureInterval: DefaultFailureInterval,
+					Stores:          []*Store{{Type: StoreTypeACM, Region: "us-east-1"}},
+				},
+			},
+		},
+		{
+			testName:      "unknown field",
+			data:          "email: admin@example.com\ndomain: example.com\n",
+			expectedError: "config: unable to parse configuration",
+		},
+		{
+			testName: "invalid groups",
+			data: `
+groups:
+  - name: first
+    domains: [Example.com]
+    key_type: rsa1024
+  - name: first
+    domains: [example.org]
+    email: admin@example.org
+    caa_identities: ["letsencrypt.org; validationmethods=dns-01"]
+    hosted_zones:
+      example.net: Z123
+`,
+			expectedError: `config: invalid configuration:
+  - groups[0] (first).domains[0]: invalid domain 'Example.com': invalid label 'Example', only lowercase letters, digits and hyphens are allowed, and wildcard as the first label only
+  - groups[0] (first).email: must be set either in the group or at the top level
+  - groups[0] (first).key_type: unknown key type 'rsa1024', expected one of ec256, ec384, rsa2048, rsa4096
+  - groups[1] (first): name is already used by groups[0]
+  - groups[1] (first).caa_identities[0]: invalid CAA identity 'letsencrypt.org; validationmethods=dns-01', expected the issuer domain name, e.g. letsencrypt.org
+  - groups[1] (first).hosted_zones[example.net]: domain 'example.net' is not a domain of the group or its parent`,
+		},
+		{
+			testName: "invalid notifications",
+			data: `
+email: admin@example.com
+groups:
+  - domains: [example.com]
+    notifications:
+      - type: slack
+        url: https://hooks.slack.com/services/T000/B000/XXXX
+      - type: slack
+        token: xoxb-token
+      - type: slack
+        url: https://hooks.slack.com/services/T000/B000/XXXX
+        token: xoxb-token
+      - type: teams
+        url: http://example.webhook.office.com/workflows
+      - type: email
+      - type: webhook
+        url: ftp://deploy.example.com/hooks
+        timeout: -1s
+        retries: -1
+      - type: sns
+        topic: arn:aws:sns:us-east-1:123456789012:certificates
+        events: [RenewalFailed, Expired]
+        template: "{{ .Domains"
+      - type: smtp
+        from: certificates
+        port: 70000
+        tls: ssl
+      - type: ses
+        from: Certificates <certs@example.com>
+        to: [owner@example.com, "a@example.com, b@example.com"]
+        html_template: "{{ .Message"
+`,
+			expectedError: `config: invalid configuration:
+  - groups[0] (example.com).notifications[1].channel: must be set with the token
+  - groups[0] (example.com).notifications[2]: either url or token must be set, not both
+  - groups[0] (example.com).notifications[3].url: https:// webhook URL must be set
+  - groups[0] (example.com).notifications[4].type: unknown notification type 'email', expected sns, slack, teams, webhook, smtp or ses
+  - groups[0] (example.com).notifications[5].url: http:// or https:// URL must be set
+  - groups[0] (example.com).notifications[5].timeout: must be a positive duration
+  - groups[0] (example.com).notifications[5].retries: must be a positive number
+  - groups[0] (example.com).notifications[6].events[1]: unknown event type 'Expired', expected one of CertificateIssued, CertificateRenewed, RenewalSkipped, RenewalFailed, ExpiringSoon, Revoked
+  - groups[0] (example.com).notifications[6].template: notifier: invalid template: template: message:1: unclosed action
+  - groups[0] (example.com).notifications[7].from: invalid sender address 'certificates'
+  - groups[0] (example.com).notifications[7].to: at least one recipient must be set
+  - groups[0] (example.com).notifications[7].host: SMTP server host must be set
+  - groups[0] (example.com).notifications[7].port: invalid port 70000
+  - groups[0] (example.com).notifications[7].tls: unknown TLS mode 'ssl', expected starttls, tls or none
+  - groups[0] (example.com).notifications[8].to[1]: invalid recipient address 'a@example.com, b@example.com'
+  - groups[0] (example.com).notifications[8].html_template: email: invalid HTML template: template: html:1: unclosed action`,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			conf, err := Parse([]byte(tt.data))
+			if len(tt.expectedError) > 0 {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedGroups, conf.Groups)
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	conf, err := Parse([]byte(`
+email: admin@example.com
+groups:
+  - name: custom
+    domains: [example.com]
+    ca: https://acme.example.net/directory
+    key_type: ec384
+  - name: default
+    domains: [example.org]
+`))
+	require.NoError(t, err)
+
+	groups, err := conf.GroupsByName([]string{"default", "custom"})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	require.Equal(t, lego.LEDirectoryProduction, groups[0].CADirURL())
+	require.Equal(t, certcrypto.RSA2048, groups[0].CertKeyType())
+	require.Equal(t, "https://acme.example.net/directory", groups[1].CADirURL())
+	require.Equal(t, certcrypto.EC384, groups[1].CertKeyType())
+
+	_, err = conf.GroupsByName([]string{"missing"})
+	require.Error(t, err)
+}
+
+func TestPolicy(t *testing.T) {
+	conf, err := Parse([]byte(`
+email: admin@example.com
+policy:
+  allowed_suffixes: [example.com]
+  allow_wildcard: false
+  max_sans: 10
+  allowed_key_types: [ec256]
+  allowed_cas: [production]
+groups:
+  - domains: [example.com]
+`))
+	require.NoError(t, err)
+	require.NotNil(t, conf.Policy)
+	require.Equal(t, conf.Policy, conf.Groups[0].Policy())
+	require.Equal(t, conf.Policy, conf.NewGroup([]string{"www.example.com"}).Policy())
+
+	_, err = Parse([]byte(`
+email: admin@example.com
+policy:
+  allowed_suffixes: [Example..com]
+  max_sans: -1
+  allowed_key_types: [rsa1024]
+  allowed_cas: [http://acme.example.net/directory]
+groups:
+  - domains: [example.com]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `
+  - policy.allowed_suffixes[0]: invalid domain suffix 'Example..com'`)
+	require.Contains(t, err.Error(), `
+  - policy.max_sans: must be a positive number
+  - policy.allowed_key_types[0]: unknown key type 'rsa1024', expected one of ec256, ec384, rsa2048, rsa4096
+  - policy.allowed_cas[0]: unknown CA 'http://acme.example.net/directory', expected production, staging or https:// directory URL`)
+}
+
+func TestExpiry(t *testing.T) {
+	conf, err := Parse([]byte(`
+email: admin@example.com
+groups:
+  - domains: [example.com]
+`))
+	require.NoError(t, err)
+	require.Equal(t, &Expiry{Thresholds: DefaultExpiryThresholds}, conf.Expiry)
+
+	_, err = Parse([]byte(`
+email: admin@example.com
+expiry:
+  thresholds: [14, 0, 7, 14]
+  endpoints: true
+groups:
+  - domains: [example.com]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `
+  - expiry.thresholds[1]: must be a positive number of days
+  - expiry.thresholds[3]: threshold 14 is duplicated`)
+}
+
+func TestRoutes(t *testing.T) {
+	conf, err := Parse([]byte(`
+email: admin@example.com
+notifiers:
+  ops:
+    type: slack
+    url: https://hooks.slack.com/services/T000/B000/XXXX
+  sns:
+    type: sns
+    topic: arn:aws:sns:us-east-1:123456789012:certificates
+routes:
+  - events: [RenewalFailed]
+    notify: [ops, sns]
+  - groups: [x]
+    notify: [ops]
+groups:
+  - name: x
+    domains: [example.com]
+  - name: y
+    domains: [example.org]
+`))
+	require.NoError(t, err)
+
+	ops, sns := conf.Notifiers["ops"], conf.Notifiers["sns"]
+	require.Equal(t, []*GroupRoute{
+		{Events: []string{"RenewalFailed"}, Notifications: []*Notification{ops, sns}},
+		{Notifications: []*Notification{ops}},
+	}, conf.Groups[0].Routes())
+	require.Equal(t, []*GroupRoute{
+		{Events: []string{"RenewalFailed"}, Notifications: []*Notification{ops, sns}},
+	}, conf.Groups[1].Routes())
+	require.Len(t, conf.NewGroup([]string{"example.net"}).Routes(), 1)
+
+	_, err = Parse([]byte(`
+email: admin@example.com
+notifiers:
+  ops:
+    type: slack
+  empty:
+routes:
+  - events: [Expired]
+    notify: [ops, missing]
+  - notify: []
+groups:
+  - domains: [example.com]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `
+  - notifiers[empty]: must be defined
+  - notifiers[ops].url: `)
+	require.Contains(t, err.Error(), `
+  - routes[0].events[0]: unknown event type 'Expired'`)
+	require.Contains(t, err.Error(), `
+  - routes[0].notify[1]: unknown notifier 'missing'
+  - routes[1].notify: at least one notifier must be set`)
+
+	_, err = Parse([]byte(`
+email: admin@example.com
+routes:
+  - groups: [missing]
+    notify: []
+groups:
+  - name: x
+    domains: [example.com]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "routes[0].notify: at least one notifier must be set")
+
+	_, err = Parse([]byte(`
+email: admin@example.com
+notifiers:
+  ops:
+    type: sns
+    topic: arn:aws:sns:us-east-1:123456789012:certificates
+routes:
+  - groups: [missing]
+    notify: [ops]
+groups:
+  - name: x
+    domains: [example.com]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "routes[0].groups[0]: unknown group 'missing'")
+}
+
+func TestDeploy(t *testing.T) {
+	conf, err := Parse([]byte(`
+email: admin@example.com
+groups:
+  - domains: [example.com]
+    deploy:
+      - type: command
+        name: haproxy
+        command: systemctl reload haproxy
+        dir: /etc/haproxy/certs
+        timeout: 30s
+      - type: ssm
+        command: systemctl restart keystore-consumer
+        targets:
+          tag:Role: [app]
+      - type: eventbridge
+      - type: elbv2
+        listener: arn:aws:elasticloadbalancing:eu-west-1:123456789012:listener/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2
+        default_certificate: true
+`))
+	require.NoError(t, err)
+	require.Len(t, conf.Groups[0].Deploy, 4)
+	require.True(t, conf.Groups[0].Deploy[3].DefaultCertificate)
+	require.Equal(t, "haproxy", conf.Groups[0].Deploy[0].HookName())
+	require.Equal(t, 30*time.Second, conf.Groups[0].Deploy[0].Timeout)
+	require.Equal(t, "ssm", conf.Groups[0].Deploy[1].HookName())
+
+	_, err = Parse([]byte(`
+email: admin@example.com
+groups:
+  - domains: [example.com]
+    deploy:
+      - type: command
+        timeout: -1s
+      - type: lambda
+      - type: ssm
+        name: lambda
+        command: reload
+      - type: ftp
+      - type: elbv2
+        listener: arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/app/web/50dc6c495c0c9188
+      - type: cloudfront
+      - type: apigateway
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `
+  - groups[0] (example.com).deploy[0].timeout: must be a positive duration
+  - groups[0] (example.com).deploy[0].command: must be set
+  - groups[0] (example.com).deploy[1].function: name or ARN of the function must be set
+  - groups[0] (example.com).deploy[2]: instance_ids or targets must be set
+  - groups[0] (example.com).deploy[2].name: duplicate hook name 'lambda'
+  - groups[0] (example.com).deploy[3].type: unknown hook type 'ftp', expected command, lambda, ssm, eventbridge, elbv2, cloudfront or apigateway
+  - groups[0] (example.com).deploy[4].listener: ELBv2 listener ARN must be set, got 'arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/app/web/50dc6c495c0c9188'
+  - groups[0] (example.com).deploy[5].distribution: ID of the distribution must be set
+  - groups[0] (example.com).deploy[6].domain_name: must be set`)
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// s3Prefix is the prefix of configuration locations in S3
+	s3Prefix = "s3://"
+
+	// ssmPrefix is the prefix of configuration locations in SSM Parameter Store
+	ssmPrefix = "ssm:"
+)
+
+// Load loads the configuration from the given location:
+// - s3://<bucket>/<key> is an object in S3
+// - ssm:<parameter-name> is a parameter in SSM Parameter Store
+// - any other value is a path to a local file
+func Load(location string, provider client.ConfigProvider) (*Config, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch {
+	case strings.HasPrefix(location, s3Prefix):
+		data, err = loadS3(provider, strings.TrimPrefix(location, s3Prefix))
+	case strings.HasPrefix(location, ssmPrefix):
+		data, err = loadSSM(provider, strings.TrimPrefix(location, ssmPrefix))
+	default:
+		data, err = ioutil.ReadFile(location)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "config: unable to load configuration from '%s'", location)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses the YAML or JSON encoded configuration, applies defaults and validates it.
+// Routes must select groups defined by the configuration.
+func Parse(data []byte) (*Config, error) {
+	var c Config
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, errors.Wrap(err, "config: unable to parse configuration")
+	}
+
+	if err := c.Init(); err != nil {
+		return nil, err
+	}
+
+	if err := c.validateRouteGroups(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// loadS3 loads the object with the given "<bucket>/<key>" path from S3
+func loadS3(provider client.ConfigProvider, path string) ([]byte, error) {
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, errors.New("S3 location must be in s3://<bucket>/<key> format")
+	}
+
+	resp, err := s3.New(provider).GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(parts[0]),
+		Key:    aws.String(parts[1]),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// loadSSM loads the parameter with the given name from SSM Parameter Store
+func loadSSM(provider client.ConfigProvider, name string) ([]byte, error) {
+	resp, err := ssm.New(provider).GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(aws.StringValue(resp.Parameter.Value)), nil
+}
@@ -0,0 +1,512 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+	"github.com/begmaroman/acme-dns-route53/notifier/email"
+)
+
+var (
+	// domainLabelRegexp matches a single label of a domain name
+	domainLabelRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+	// hostedZoneIDRegexp matches the ID of a Route 53 hosted zone
+	hostedZoneIDRegexp = regexp.MustCompile(`^(/hostedzone/)?Z[A-Z0-9]+$`)
+)
+
+// ValidationError is the error which describes all problems found in the configuration
+type ValidationError struct {
+	Errors []string
+}
+
+// Error implements error interface
+func (e *ValidationError) Error() string {
+	return "config: invalid configuration:\n  - " + strings.Join(e.Errors, "\n  - ")
+}
+
+// Init applies defaults to the configuration and validates it
+func (c *Config) Init() error {
+	c.applyDefaults()
+	return c.Validate()
+}
+
+// Validate checks the configuration and returns *ValidationError describing all found problems
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if len(c.Groups) == 0 {
+		v.add("groups", "at least one group must be defined")
+	}
+
+	if c.Parallelism < 0 {
+		v.add("parallelism", "must be a positive number")
+	}
+
+	if c.Policy != nil {
+		v.validatePolicy("policy", c.Policy)
+	}
+
+	if c.Expiry != nil {
+		v.validateExpiry("expiry", c.Expiry)
+	}
+
+	names := make(map[string]int, len(c.Groups))
+	for i, group := range c.Groups {
+		path := fmt.Sprintf("groups[%d]", i)
+		if len(group.Name) > 0 {
+			path = fmt.Sprintf("groups[%d] (%s)", i, group.Name)
+
+			if j, ok := names[group.Name]; ok {
+				v.add(path, "name is already used by groups[%d]", j)
+			}
+			names[group.Name] = i
+		}
+
+		v.validateGroup(path, group)
+	}
+
+	v.validateRouting(c)
+
+	if len(v.errors) > 0 {
+		return &ValidationError{Errors: v.errors}
+	}
+
+	return nil
+}
+
+// validator collects validation errors
+type validator struct {
+	errors []string
+}
+
+// add adds the error with the given path
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, path+": "+fmt.Sprintf(format, args...))
+}
+
+// validateGroup validates the given group
+func (v *validator) validateGroup(path string, group *Group) {
+	if len(group.Domains) == 0 {
+		v.add(path+".domains", "at least one domain must be defined")
+	}
+
+	seen := make(map[string]bool, len(group.Domains))
+	for i, domain := range group.Domains {
+		if err := validateDomain(domain); err != "" {
+			v.add(fmt.Sprintf("%s.domains[%d]", path, i), "invalid domain '%s': %s", domain, err)
+		}
+
+		if seen[domain] {
+			v.add(fmt.Sprintf("%s.domains[%d]", path, i), "duplicate domain '%s'", domain)
+		}
+		seen[domain] = true
+	}
+
+	if len(group.Email) == 0 {
+		v.add(path+".email", "must be set either in the group or at the top level")
+	} else if !strings.Contains(group.Email, "@") {
+		v.add(path+".email", "invalid e-mail address '%s'", group.Email)
+	}
+
+	if !isValidCA(group.CA) {
+		v.add(path+".ca", "unknown CA '%s', expected %s, %s or https:// directory URL", group.CA, CAProduction, CAStaging)
+	}
+
+	if _, ok := keyTypes[group.KeyType]; !ok {
+		v.add(path+".key_type", "unknown key type '%s', expected one of %s", group.KeyType, strings.Join(keyTypeNames(), ", "))
+	}
+
+	if group.RenewBefore < 0 {
+		v.add(path+".renew_before", "must be a positive number of days")
+	}
+
+	if group.FailureInterval < 0 {
+		v.add(path+".failure_interval", "must be a positive duration")
+	}
+
+	switch group.CAA {
+	case "", CAACheck, CAAUpsert, CAADisabled:
+	default:
+		v.add(path+".caa", "unknown CAA mode '%s', expected %s, %s or %s", group.CAA, CAACheck, CAAUpsert, CAADisabled)
+	}
+
+	for i, identity := range group.CAAIdentities {
+		if len(identity) == 0 || strings.ContainsAny(identity, " ;\"") {
+			v.add(fmt.Sprintf("%s.caa_identities[%d]", path, i), "invalid CAA identity '%s', expected the issuer domain name, e.g. letsencrypt.org", identity)
+		}
+	}
+
+	if len(group.Stores) == 0 {
+		v.add(path+".stores", "at least one store must be defined")
+	}
+
+	for i, store := range group.Stores {
+		if store.Type != StoreTypeACM {
+			v.add(fmt.Sprintf("%s.stores[%d].type", path, i), "unknown store type '%s', expected %s", store.Type, StoreTypeACM)
+		}
+	}
+
+	for i, notification := range group.Notifications {
+		v.validateNotification(fmt.Sprintf("%s.notifications[%d]", path, i), notification)
+	}
+
+	hookNames := make(map[string]bool, len(group.Deploy))
+	for i, hook := range group.Deploy {
+		hookPath := fmt.Sprintf("%s.deploy[%d]", path, i)
+		v.validateHook(hookPath, hook)
+
+		if hookNames[hook.HookName()] {
+			v.add(hookPath+".name", "duplicate hook name '%s'", hook.HookName())
+		}
+		hookNames[hook.HookName()] = true
+	}
+
+	for domain, zoneID := range group.HostedZones {
+		zonePath := fmt.Sprintf("%s.hosted_zones[%s]", path, domain)
+
+		if !coversAnyDomain(domain, group.Domains) {
+			v.add(zonePath, "domain '%s' is not a domain of the group or its parent", domain)
+		}
+
+		if !hostedZoneIDRegexp.MatchString(zoneID) {
+			v.add(zonePath, "invalid hosted zone ID '%s'", zoneID)
+		}
+	}
+}
+
+// validateRouting validates notifiers and routes of the given configuration.
+// Groups of routes are validated by validateRouteGroups since groups may be selected after parsing.
+func (v *validator) validateRouting(c *Config) {
+	notifierNames := make([]string, 0, len(c.Notifiers))
+	for name := range c.Notifiers {
+		notifierNames = append(notifierNames, name)
+	}
+	sort.Strings(notifierNames)
+
+	for _, name := range notifierNames {
+		if c.Notifiers[name] == nil {
+			v.add(fmt.Sprintf("notifiers[%s]", name), "must be defined")
+			continue
+		}
+
+		v.validateNotification(fmt.Sprintf("notifiers[%s]", name), c.Notifiers[name])
+	}
+
+	for i, route := range c.Routes {
+		path := fmt.Sprintf("routes[%d]", i)
+
+		for j, eventType := range route.Events {
+			if !isEventType(eventType) {
+				v.add(fmt.Sprintf("%s.events[%d]", path, j), "unknown event type '%s', expected one of %s", eventType, eventTypeNames())
+			}
+		}
+
+		if len(route.Notify) == 0 {
+			v.add(path+".notify", "at least one notifier must be set")
+		}
+
+		for j, name := range route.Notify {
+			if c.Notifiers[name] == nil {
+				v.add(fmt.Sprintf("%s.notify[%d]", path, j), "unknown notifier '%s'", name)
+			}
+		}
+	}
+}
+
+// validateRouteGroups checks that routes select groups defined by the configuration
+func (c *Config) validateRouteGroups() error {
+	v := &validator{}
+
+	names := make(map[string]bool, len(c.Groups))
+	for _, group := range c.Groups {
+		names[group.Name] = true
+	}
+
+	for i, route := range c.Routes {
+		for j, name := range route.Groups {
+			if !names[name] {
+				v.add(fmt.Sprintf("routes[%d].groups[%d]", i, j), "unknown group '%s'", name)
+			}
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return &ValidationError{Errors: v.errors}
+	}
+
+	return nil
+}
+
+// validateNotification validates the given notification
+func (v *validator) validateNotification(path string, notification *Notification) {
+	for i, eventType := range notification.Events {
+		if !isEventType(eventType) {
+			v.add(fmt.Sprintf("%s.events[%d]", path, i), "unknown event type '%s', expected one of %s", eventType, eventTypeNames())
+		}
+	}
+
+	if len(notification.Template) > 0 {
+		if _, err := notifier.ParseTemplate(notification.Template); err != nil {
+			v.add(path+".template", "%s", err)
+		}
+	}
+
+	switch notification.Type {
+	case NotificationTypeSNS:
+		if !strings.HasPrefix(notification.Topic, "arn:") {
+			v.add(path+".topic", "SNS topic ARN must be set, got '%s'", notification.Topic)
+		}
+	case NotificationTypeSlack:
+		switch {
+		case len(notification.URL) > 0 && len(notification.Token) > 0:
+			v.add(path, "either url or token must be set, not both")
+		case len(notification.Token) > 0:
+			if len(notification.Channel) == 0 {
+				v.add(path+".channel", "must be set with the token")
+			}
+		default:
+			v.validateWebhookURL(path+".url", notification.URL)
+		}
+	case NotificationTypeTeams:
+		v.validateWebhookURL(path+".url", notification.URL)
+	case NotificationTypeWebhook:
+		if u, err := url.Parse(notification.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || len(u.Host) == 0 {
+			v.add(path+".url", "http:// or https:// URL must be set")
+		}
+
+		if notification.Timeout < 0 {
+			v.add(path+".timeout", "must be a positive duration")
+		}
+
+		if notification.Retries != nil && *notification.Retries < 0 {
+			v.add(path+".retries", "must be a positive number")
+		}
+	case NotificationTypeSMTP:
+		v.validateEmail(path, notification)
+
+		if len(notification.Host) == 0 {
+			v.add(path+".host", "SMTP server host must be set")
+		}
+
+		if notification.Port < 0 || notification.Port > 65535 {
+			v.add(path+".port", "invalid port %d", notification.Port)
+		}
+
+		if !isSMTPTLS(notification.TLS) {
+			v.add(path+".tls", "unknown TLS mode '%s', expected %s, %s or %s", notification.TLS, email.TLSStartTLS, email.TLSImplicit, email.TLSNone)
+		}
+	case NotificationTypeSES:
+		v.validateEmail(path, notification)
+	default:
+		v.add(path+".type", "unknown notification type '%s', expected %s, %s, %s, %s, %s or %s",
+			notification.Type, NotificationTypeSNS, NotificationTypeSlack, NotificationTypeTeams, NotificationTypeWebhook, NotificationTypeSMTP, NotificationTypeSES)
+	}
+}
+
+// validateHook validates the given deploy hook
+func (v *validator) validateHook(path string, hook *Hook) {
+	if hook.Timeout < 0 {
+		v.add(path+".timeout", "must be a positive duration")
+	}
+
+	switch hook.Type {
+	case HookTypeCommand:
+		if len(hook.Command) == 0 {
+			v.add(path+".command", "must be set")
+		}
+	case HookTypeLambda:
+		if len(hook.Function) == 0 {
+			v.add(path+".function", "name or ARN of the function must be set")
+		}
+	case HookTypeSSM:
+		if len(hook.Command) == 0 {
+			v.add(path+".command", "must be set")
+		}
+
+		if len(hook.InstanceIDs) == 0 && len(hook.Targets) == 0 {
+			v.add(path, "instance_ids or targets must be set")
+		}
+	case HookTypeEventBridge:
+	case HookTypeELBv2:
+		if !strings.HasPrefix(hook.Listener, "arn:") || !strings.Contains(hook.Listener, ":elasticloadbalancing:") || !strings.Contains(hook.Listener, ":listener/") {
+			v.add(path+".listener", "ELBv2 listener ARN must be set, got '%s'", hook.Listener)
+		}
+	case HookTypeCloudFront:
+		if len(hook.Distribution) == 0 {
+			v.add(path+".distribution", "ID of the distribution must be set")
+		}
+	case HookTypeAPIGateway:
+		if len(hook.DomainName) == 0 {
+			v.add(path+".domain_name", "must be set")
+		}
+	default:
+		v.add(path+".type", "unknown hook type '%s', expected %s, %s, %s, %s, %s, %s or %s", hook.Type,
+			HookTypeCommand, HookTypeLambda, HookTypeSSM, HookTypeEventBridge, HookTypeELBv2, HookTypeCloudFront, HookTypeAPIGateway)
+	}
+}
+
+// validatePolicy validates the given policy
+func (v *validator) validatePolicy(path string, policy *Policy) {
+	for i, suffix := range policy.AllowedSuffixes {
+		if err := validateDomain(strings.TrimPrefix(suffix, ".")); err != "" {
+			v.add(fmt.Sprintf("%s.allowed_suffixes[%d]", path, i), "invalid domain suffix '%s': %s", suffix, err)
+		}
+	}
+
+	if policy.MaxSANs < 0 {
+		v.add(path+".max_sans", "must be a positive number")
+	}
+
+	for i, keyType := range policy.AllowedKeyTypes {
+		if _, ok := keyTypes[keyType]; !ok {
+			v.add(fmt.Sprintf("%s.allowed_key_types[%d]", path, i), "unknown key type '%s', expected one of %s", keyType, strings.Join(keyTypeNames(), ", "))
+		}
+	}
+
+	for i, ca := range policy.AllowedCAs {
+		if !isValidCA(ca) {
+			v.add(fmt.Sprintf("%s.allowed_cas[%d]", path, i), "unknown CA '%s', expected %s, %s or https:// directory URL", ca, CAProduction, CAStaging)
+		}
+	}
+}
+
+// validateExpiry validates the given settings of the expiry watchdog
+func (v *validator) validateExpiry(path string, expiry *Expiry) {
+	seen := make(map[int]bool, len(expiry.Thresholds))
+	for i, days := range expiry.Thresholds {
+		if days <= 0 {
+			v.add(fmt.Sprintf("%s.thresholds[%d]", path, i), "must be a positive number of days")
+		} else if seen[days] {
+			v.add(fmt.Sprintf("%s.thresholds[%d]", path, i), "threshold %d is duplicated", days)
+		}
+		seen[days] = true
+	}
+}
+
+// validateEmail validates the sender, the recipients and the HTML template of the given email notification
+func (v *validator) validateEmail(path string, notification *Notification) {
+	if _, err := mail.ParseAddress(notification.From); err != nil {
+		v.add(path+".from", "invalid sender address '%s'", notification.From)
+	}
+
+	if len(notification.To) == 0 {
+		v.add(path+".to", "at least one recipient must be set")
+	}
+
+	for i, address := range notification.To {
+		if _, err := mail.ParseAddress(address); err != nil || strings.Contains(address, ",") {
+			v.add(fmt.Sprintf("%s.to[%d]", path, i), "invalid recipient address '%s'", address)
+		}
+	}
+
+	if len(notification.HTMLTemplate) > 0 {
+		if _, err := email.ParseHTMLTemplate(notification.HTMLTemplate); err != nil {
+			v.add(path+".html_template", "%s", err)
+		}
+	}
+}
+
+// validateWebhookURL validates the given webhook URL
+func (v *validator) validateWebhookURL(path, webhookURL string) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Scheme != "https" || len(u.Host) == 0 {
+		v.add(path, "https:// webhook URL must be set")
+	}
+}
+
+// isSMTPTLS checks if the given name is the TLS mode of SMTP connections, empty name is the default mode
+func isSMTPTLS(name string) bool {
+	switch name {
+	case "", email.TLSStartTLS, email.TLSImplicit, email.TLSNone:
+		return true
+	default:
+		return false
+	}
+}
+
+// isValidCA checks if the given CA is a well-known CA name or a directory URL
+func isValidCA(ca string) bool {
+	if _, ok := caDirURLs[ca]; ok {
+		return true
+	}
+
+	u, err := url.Parse(ca)
+	return err == nil && u.Scheme == "https" && len(u.Host) > 0
+}
+
+// validateDomain validates the given domain name and returns the description of the problem
+func validateDomain(domain string) string {
+	if len(domain) > 253 {
+		return "must not be longer than 253 characters"
+	}
+
+	labels := strings.Split(strings.TrimPrefix(domain, "*."), ".")
+	if len(labels) < 2 {
+		return "must contain at least two labels"
+	}
+
+	for _, label := range labels {
+		if !domainLabelRegexp.MatchString(label) {
+			return fmt.Sprintf("invalid label '%s', only lowercase letters, digits and hyphens are allowed, and wildcard as the first label only", label)
+		}
+	}
+
+	return ""
+}
+
+// coversAnyDomain checks if the given zone domain is one of the given domains or their parent
+func coversAnyDomain(zoneDomain string, domains []string) bool {
+	for _, domain := range domains {
+		if InZone(domain, zoneDomain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InZone checks if the given domain, possibly wildcard, belongs to the zone with the given name
+func InZone(domain, zone string) bool {
+	zone = strings.TrimSuffix(zone, ".")
+	domain = strings.TrimPrefix(domain, "*.")
+
+	return domain == zone || strings.HasSuffix(domain, "."+zone)
+}
+
+// isEventType checks if the given name is a notification event type
+func isEventType(name string) bool {
+	for _, eventType := range notifier.EventTypes {
+		if string(eventType) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eventTypeNames returns names of notification event types joined by comma
+func eventTypeNames() string {
+	names := make([]string, len(notifier.EventTypes))
+	for i, eventType := range notifier.EventTypes {
+		names[i] = string(eventType)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// keyTypeNames returns sorted names of supported key types
+func keyTypeNames() []string {
+	names := make([]string, 0, len(keyTypes))
+	for name := range keyTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
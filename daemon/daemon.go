@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// ErrInvalidInterval is the error when the interval between checks is not positive, checks would run in a tight loop
+var ErrInvalidInterval = errors.New("daemon: interval must be positive")
+
+// Options is the options of the daemon
+type Options struct {
+	// Jobs is the list of certificate groups with their handlers
+	Jobs []*runner.Job
+
+	// Interval is the period between checks of a certificate group
+	Interval time.Duration
+
+	// Jitter is the maximum random delay added to each check to spread them over time
+	Jitter time.Duration
+
+	// Parallelism is the maximum number of certificate groups checked at once
+	Parallelism int
+
+	Log *logrus.Logger
+}
+
+// GroupStatus is the status of a certificate group
+type GroupStatus struct {
+	Name      string    `json:"name"`
+	Domains   []string  `json:"domains"`
+	Running   bool      `json:"running"`
+	LastCheck time.Time `json:"last_check,omitempty"`
+	NextCheck time.Time `json:"next_check,omitempty"`
+
+	LastOutcome handler.Outcome `json:"last_outcome,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// Daemon periodically obtains or renews certificates of the configured groups
+type Daemon struct {
+	opts  *Options
+	slots chan struct{} // Limits the number of checks running at once
+
+	statusLock sync.RWMutex
+	statuses   []*GroupStatus // Statuses of jobs with the same index
+	started    time.Time
+	stopping   bool
+}
+
+// New is the constructor of Daemon
+func New(opts *Options) (*Daemon, error) {
+	if opts.Interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	statuses := make([]*GroupStatus, len(opts.Jobs))
+	for i, job := range opts.Jobs {
+		statuses[i] = &GroupStatus{Name: job.Name(), Domains: job.Group.Domains}
+	}
+
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = runner.DefaultParallelism
+	}
+
+	return &Daemon{
+		opts:     opts,
+		slots:    make(chan struct{}, parallelism),
+		statuses: statuses,
+	}, nil
+}
+
+// Run schedules checks of all certificate groups until the given context is done.
+// Checks which are in progress when the context is done are finished before returning,
+// since an ACME order cannot be interrupted without leaving its challenges behind.
+func (d *Daemon) Run(ctx context.Context) {
+	d.statusLock.Lock()
+	d.started = time.Now()
+	d.statusLock.Unlock()
+
+	var wg sync.WaitGroup
+	for i := range d.opts.Jobs {
+		wg.Add(1)
+		go func(job *runner.Job, status *GroupStatus) {
+			defer wg.Done()
+			d.schedule(ctx, job, status)
+		}(d.opts.Jobs[i], d.statuses[i])
+	}
+	wg.Wait()
+}
+
+// CleanUp removes challenge records which have been left by interrupted orders
+func (d *Daemon) CleanUp() error {
+	var lastErr error
+	for _, job := range d.opts.Jobs {
+		if err := job.DNS01.CleanUpPending(); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// schedule runs checks of the given certificate group with the configured interval until the given context is done
+func (d *Daemon) schedule(ctx context.Context, job *runner.Job, status *GroupStatus) {
+	// The first check is delayed by jitter only, so all groups are checked soon after start
+	delay := d.jitter()
+
+	for {
+		d.setNextCheck(status, time.Now().Add(delay))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Wait for a free slot if too many groups are checked at once
+		select {
+		case <-ctx.Done():
+			return
+		case d.slots <- struct{}{}:
+		}
+
+		d.check(job, status)
+		<-d.slots
+
+		delay = d.opts.Interval + d.jitter()
+	}
+}
+
+// check obtains or renews the certificate of the given group and records the result
+func (d *Daemon) check(job *runner.Job, status *GroupStatus) {
+	d.statusLock.Lock()
+	status.Running = true
+	d.statusLock.Unlock()
+
+	result, err := job.Handler.Obtain(job.Group.Domains, job.Group.Email)
+	if err != nil {
+		d.opts.Log.Errorf("[%s] unable to obtain certificate: %s", job.DomainsString(), err)
+	}
+
+	d.statusLock.Lock()
+	defer d.statusLock.Unlock()
+
+	status.Running = false
+	status.LastCheck = time.Now()
+	status.LastOutcome = result.Outcome
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+	}
+}
+
+// setNextCheck sets the time of the next check of the given group
+func (d *Daemon) setNextCheck(status *GroupStatus, next time.Time) {
+	d.statusLock.Lock()
+	defer d.statusLock.Unlock()
+
+	status.NextCheck = next
+}
+
+// jitter returns a random delay within the configured jitter
+func (d *Daemon) jitter() time.Duration {
+	if d.opts.Jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(d.opts.Jitter)))
+}
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Status is the status of the daemon
+type Status struct {
+	Started  time.Time      `json:"started"`
+	Stopping bool           `json:"stopping"`
+	Groups   []*GroupStatus `json:"groups"`
+}
+
+// Status returns the current status of the daemon
+func (d *Daemon) Status() *Status {
+	d.statusLock.RLock()
+	defer d.statusLock.RUnlock()
+
+	groups := make([]*GroupStatus, len(d.statuses))
+	for i, status := range d.statuses {
+		groupStatus := *status
+		groups[i] = &groupStatus
+	}
+
+	return &Status{
+		Started:  d.started,
+		Stopping: d.stopping,
+		Groups:   groups,
+	}
+}
+
+// Stopping marks the daemon as stopping, so the health check starts failing
+func (d *Daemon) Stopping() {
+	d.statusLock.Lock()
+	defer d.statusLock.Unlock()
+
+	d.stopping = true
+}
+
+// HTTPHandler returns the HTTP handler which serves health and status endpoints:
+// - /healthz responds 200 while the daemon is running and 503 when it is stopping
+// - /status responds with the JSON encoded status of all certificate groups
+func (d *Daemon) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if d.Status().Stopping {
+			http.Error(w, "stopping", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	})
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d.Status())
+	})
+
+	return mux
+}
@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	d, err := New(&Options{
+		Jobs: []*runner.Job{
+			{Group: &config.Group{Name: "com", Domains: []string{"example.com"}}},
+			{Group: &config.Group{Name: "org", Domains: []string{"example.org"}}},
+		},
+		Interval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	h := d.HTTPHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status Status
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	require.Len(t, status.Groups, 2)
+	require.Equal(t, "org", status.Groups[1].Name)
+	require.Equal(t, []string{"example.org"}, status.Groups[1].Domains)
+
+	d.Stopping()
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestNewInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		_, err := New(&Options{Interval: interval})
+		require.Equal(t, ErrInvalidInterval, err)
+	}
+
+	_, err := New(&Options{Interval: time.Minute})
+	require.NoError(t, err)
+}
@@ -0,0 +1,83 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/apigateway/apigatewayiface"
+	"github.com/pkg/errors"
+)
+
+// To make sure that domainNameHook implements Hook interface
+var _ Hook = &domainNameHook{}
+
+// domainNameHook sets certificates of API Gateway custom domain names
+type domainNameHook struct {
+	apigateway apigatewayiface.APIGatewayAPI
+	region     string
+	domainName string
+}
+
+// NewDomainName is the constructor of the hook which sets the certificate of the given API Gateway custom domain name in the given region.
+// Certificates of regional domain names must be stored in ACM of the region, certificates of edge-optimized ones in ACM of us-east-1 region.
+// Nothing is changed if the domain name already uses the certificate.
+func NewDomainName(provider client.ConfigProvider, region, domainName string) Hook {
+	return &domainNameHook{
+		apigateway: apigateway.New(provider),
+		region:     region,
+		domainName: domainName,
+	}
+}
+
+// Deploy implements Hook interface
+func (h *domainNameHook) Deploy(ctx context.Context, cert *Certificate) (string, error) {
+	domain, err := h.apigateway.GetDomainNameWithContext(ctx, &apigateway.GetDomainNameInput{DomainName: aws.String(h.domainName)})
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to get domain name '%s'", h.domainName)
+	}
+
+	path, region, current := "/certificateArn", cloudFrontRegion, domain.CertificateArn
+	if isRegional(domain) {
+		path, region, current = "/regionalCertificateArn", h.region, domain.RegionalCertificateArn
+	}
+
+	certARN, err := certificateARN(cert, region)
+	if err != nil {
+		return "", err
+	}
+
+	if aws.StringValue(current) == certARN {
+		return fmt.Sprintf("domain name '%s' already uses certificate '%s'", h.domainName, certARN), nil
+	}
+
+	if _, err := h.apigateway.UpdateDomainNameWithContext(ctx, &apigateway.UpdateDomainNameInput{
+		DomainName: aws.String(h.domainName),
+		PatchOperations: []*apigateway.PatchOperation{{
+			Op:    aws.String(apigateway.OpReplace),
+			Path:  aws.String(path),
+			Value: aws.String(certARN),
+		}},
+	}); err != nil {
+		return "", errors.Wrapf(err, "unable to update domain name '%s'", h.domainName)
+	}
+
+	return fmt.Sprintf("domain name '%s' updated to use certificate '%s'", h.domainName, certARN), nil
+}
+
+// isRegional checks if the given domain name has the regional endpoint
+func isRegional(domain *apigateway.DomainName) bool {
+	if domain.EndpointConfiguration == nil {
+		return false
+	}
+
+	for _, endpointType := range domain.EndpointConfiguration.Types {
+		if aws.StringValue(endpointType) == apigateway.EndpointTypeRegional {
+			return true
+		}
+	}
+
+	return false
+}
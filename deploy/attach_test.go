@@ -0,0 +1,211 @@
+package deploy
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/apigateway/apigatewayiface"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/cloudfront/cloudfrontiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testListener = "arn:aws:elasticloadbalancing:eu-west-1:123456789012:listener/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2"
+	testCertARN  = "arn:aws:acm:eu-west-1:123456789012:certificate/1"
+	testEdgeARN  = "arn:aws:acm:us-east-1:123456789012:certificate/2"
+)
+
+// fakeELBv2 keeps certificates of one listener
+type fakeELBv2 struct {
+	elbv2iface.ELBV2API
+
+	certificates []*elbv2.Certificate
+	changes      int
+}
+
+func (f *fakeELBv2) DescribeListenerCertificatesWithContext(_ aws.Context, input *elbv2.DescribeListenerCertificatesInput, _ ...request.Option) (*elbv2.DescribeListenerCertificatesOutput, error) {
+	// Each certificate is returned on its own page
+	i, _ := strconv.Atoi(aws.StringValue(input.Marker))
+
+	resp := &elbv2.DescribeListenerCertificatesOutput{Certificates: f.certificates[i : i+1]}
+	if i+1 < len(f.certificates) {
+		resp.NextMarker = aws.String(strconv.Itoa(i + 1))
+	}
+
+	return resp, nil
+}
+
+func (f *fakeELBv2) AddListenerCertificatesWithContext(_ aws.Context, input *elbv2.AddListenerCertificatesInput, _ ...request.Option) (*elbv2.AddListenerCertificatesOutput, error) {
+	f.changes++
+	f.certificates = append(f.certificates, input.Certificates...)
+	return &elbv2.AddListenerCertificatesOutput{}, nil
+}
+
+func (f *fakeELBv2) ModifyListenerWithContext(_ aws.Context, input *elbv2.ModifyListenerInput, _ ...request.Option) (*elbv2.ModifyListenerOutput, error) {
+	f.changes++
+	f.certificates[0] = &elbv2.Certificate{CertificateArn: input.Certificates[0].CertificateArn, IsDefault: aws.Bool(true)}
+	return &elbv2.ModifyListenerOutput{}, nil
+}
+
+// fakeCloudFront keeps the config of one distribution
+type fakeCloudFront struct {
+	cloudfrontiface.CloudFrontAPI
+
+	config *cloudfront.DistributionConfig
+	input  *cloudfront.UpdateDistributionInput
+}
+
+func (f *fakeCloudFront) GetDistributionConfigWithContext(aws.Context, *cloudfront.GetDistributionConfigInput, ...request.Option) (*cloudfront.GetDistributionConfigOutput, error) {
+	return &cloudfront.GetDistributionConfigOutput{ETag: aws.String("E1"), DistributionConfig: f.config}, nil
+}
+
+func (f *fakeCloudFront) UpdateDistributionWithContext(_ aws.Context, input *cloudfront.UpdateDistributionInput, _ ...request.Option) (*cloudfront.UpdateDistributionOutput, error) {
+	f.input = input
+	return &cloudfront.UpdateDistributionOutput{}, nil
+}
+
+// fakeAPIGateway keeps one domain name
+type fakeAPIGateway struct {
+	apigatewayiface.APIGatewayAPI
+
+	domain *apigateway.DomainName
+	input  *apigateway.UpdateDomainNameInput
+}
+
+func (f *fakeAPIGateway) GetDomainNameWithContext(aws.Context, *apigateway.GetDomainNameInput, ...request.Option) (*apigateway.DomainName, error) {
+	return f.domain, nil
+}
+
+func (f *fakeAPIGateway) UpdateDomainNameWithContext(_ aws.Context, input *apigateway.UpdateDomainNameInput, _ ...request.Option) (*apigateway.DomainName, error) {
+	f.input = input
+	return f.domain, nil
+}
+
+func newEdgeCertificate() *Certificate {
+	cert := newTestCertificate()
+	cert.CertificateIDs = []string{testCertARN, testEdgeARN}
+
+	return cert
+}
+
+func TestListener(t *testing.T) {
+	fake := &fakeELBv2{certificates: []*elbv2.Certificate{
+		{CertificateArn: aws.String("arn:aws:acm:eu-west-1:123456789012:certificate/old"), IsDefault: aws.Bool(true)},
+		{CertificateArn: aws.String("arn:aws:acm:eu-west-1:123456789012:certificate/other")},
+	}}
+
+	sni := &listenerHook{elbv2: fake, listener: testListener}
+
+	output, err := sni.Deploy(context.Background(), newTestCertificate())
+	require.NoError(t, err)
+	require.Contains(t, output, "added to listener")
+	require.Len(t, fake.certificates, 3)
+
+	// The certificate is added once
+	output, err = sni.Deploy(context.Background(), newTestCertificate())
+	require.NoError(t, err)
+	require.Contains(t, output, "is already attached")
+	require.Equal(t, 1, fake.changes)
+
+	defaultHook := &listenerHook{elbv2: fake, listener: testListener, setDefault: true}
+
+	output, err = defaultHook.Deploy(context.Background(), newTestCertificate())
+	require.NoError(t, err)
+	require.Contains(t, output, "replacing 'arn:aws:acm:eu-west-1:123456789012:certificate/old'")
+	require.Equal(t, testCertARN, aws.StringValue(fake.certificates[0].CertificateArn))
+
+	output, err = defaultHook.Deploy(context.Background(), newTestCertificate())
+	require.NoError(t, err)
+	require.Contains(t, output, "is already the default certificate")
+	require.Equal(t, 2, fake.changes)
+
+	// The certificate must be stored in the region of the listener
+	other := &listenerHook{elbv2: fake, listener: "arn:aws:elasticloadbalancing:us-west-2:123456789012:listener/net/api/50dc6c495c0c9188/f2f7dc8efc522ab2"}
+	_, err = other.Deploy(context.Background(), newTestCertificate())
+	require.EqualError(t, err, "certificate is not stored in ACM of region 'us-west-2', add the store of the region to the group")
+}
+
+func TestDistribution(t *testing.T) {
+	fake := &fakeCloudFront{config: &cloudfront.DistributionConfig{
+		Comment:           aws.String("website"),
+		ViewerCertificate: &cloudfront.ViewerCertificate{CloudFrontDefaultCertificate: aws.Bool(true), MinimumProtocolVersion: aws.String("TLSv1")},
+	}}
+	hook := &distributionHook{cloudfront: fake, distribution: "E2QWRUHAPOMQZL"}
+
+	_, err := hook.Deploy(context.Background(), newTestCertificate())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "region 'us-east-1'")
+
+	output, err := hook.Deploy(context.Background(), newEdgeCertificate())
+	require.NoError(t, err)
+	require.Contains(t, output, "updated to use certificate")
+
+	require.Equal(t, "E1", aws.StringValue(fake.input.IfMatch))
+	require.Equal(t, "website", aws.StringValue(fake.input.DistributionConfig.Comment))
+	require.Equal(t, &cloudfront.ViewerCertificate{
+		ACMCertificateArn:      aws.String(testEdgeARN),
+		SSLSupportMethod:       aws.String(cloudfront.SSLSupportMethodSniOnly),
+		MinimumProtocolVersion: aws.String(cloudfront.MinimumProtocolVersionTlsv122018),
+	}, fake.input.DistributionConfig.ViewerCertificate)
+
+	fake.input = nil
+	output, err = hook.Deploy(context.Background(), newEdgeCertificate())
+	require.NoError(t, err)
+	require.Contains(t, output, "already uses certificate")
+	require.Nil(t, fake.input)
+}
+
+func TestDomainName(t *testing.T) {
+	testTable := []*struct {
+		testName     string
+		domain       *apigateway.DomainName
+		expectedPath string
+		expectedARN  string
+	}{
+		{
+			testName:     "regional",
+			domain:       &apigateway.DomainName{EndpointConfiguration: &apigateway.EndpointConfiguration{Types: aws.StringSlice([]string{"REGIONAL"})}},
+			expectedPath: "/regionalCertificateArn",
+			expectedARN:  testCertARN,
+		},
+		{
+			testName:     "edge-optimized",
+			domain:       &apigateway.DomainName{EndpointConfiguration: &apigateway.EndpointConfiguration{Types: aws.StringSlice([]string{"EDGE"})}},
+			expectedPath: "/certificateArn",
+			expectedARN:  testEdgeARN,
+		},
+		{
+			testName: "up to date",
+			domain: &apigateway.DomainName{
+				EndpointConfiguration:  &apigateway.EndpointConfiguration{Types: aws.StringSlice([]string{"REGIONAL"})},
+				RegionalCertificateArn: aws.String(testCertARN),
+			},
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			fake := &fakeAPIGateway{domain: tt.domain}
+			hook := &domainNameHook{apigateway: fake, region: "eu-west-1", domainName: "api.example.com"}
+
+			_, err := hook.Deploy(context.Background(), newEdgeCertificate())
+			require.NoError(t, err)
+
+			if len(tt.expectedPath) == 0 {
+				require.Nil(t, fake.input)
+				return
+			}
+
+			require.Len(t, fake.input.PatchOperations, 1)
+			require.Equal(t, tt.expectedPath, aws.StringValue(fake.input.PatchOperations[0].Path))
+			require.Equal(t, tt.expectedARN, aws.StringValue(fake.input.PatchOperations[0].Value))
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package deploy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLambda records invocations
+type fakeLambda struct {
+	lambdaiface.LambdaAPI
+
+	input *lambda.InvokeInput
+}
+
+func (f *fakeLambda) InvokeWithContext(_ aws.Context, input *lambda.InvokeInput, _ ...request.Option) (*lambda.InvokeOutput, error) {
+	f.input = input
+	return &lambda.InvokeOutput{
+		FunctionError: aws.String("Unhandled"),
+		LogResult:     aws.String(base64.StdEncoding.EncodeToString([]byte("START\n"))),
+		Payload:       []byte(`{"errorMessage":"keystore locked"}`),
+	}, nil
+}
+
+// fakeEvents records published events
+type fakeEvents struct {
+	cloudwatcheventsiface.CloudWatchEventsAPI
+
+	input *cloudwatchevents.PutEventsInput
+}
+
+func (f *fakeEvents) PutEventsWithContext(_ aws.Context, input *cloudwatchevents.PutEventsInput, _ ...request.Option) (*cloudwatchevents.PutEventsOutput, error) {
+	f.input = input
+	return &cloudwatchevents.PutEventsOutput{
+		FailedEntryCount: aws.Int64(0),
+		Entries:          []*cloudwatchevents.PutEventsResultEntry{{EventId: aws.String("event-1")}},
+	}, nil
+}
+
+// fakeSSM finishes commands after the given number of status checks
+type fakeSSM struct {
+	ssmiface.SSMAPI
+
+	input    *ssm.SendCommandInput
+	statuses []string
+}
+
+func (f *fakeSSM) SendCommandWithContext(_ aws.Context, input *ssm.SendCommandInput, _ ...request.Option) (*ssm.SendCommandOutput, error) {
+	f.input = input
+	return &ssm.SendCommandOutput{Command: &ssm.Command{CommandId: aws.String("command-1")}}, nil
+}
+
+func (f *fakeSSM) ListCommandsWithContext(_ aws.Context, input *ssm.ListCommandsInput, _ ...request.Option) (*ssm.ListCommandsOutput, error) {
+	status := f.statuses[0]
+	f.statuses = f.statuses[1:]
+
+	return &ssm.ListCommandsOutput{Commands: []*ssm.Command{{CommandId: input.CommandId, Status: aws.String(status)}}}, nil
+}
+
+func (f *fakeSSM) ListCommandInvocationsPagesWithContext(_ aws.Context, _ *ssm.ListCommandInvocationsInput, fn func(*ssm.ListCommandInvocationsOutput, bool) bool, _ ...request.Option) error {
+	fn(&ssm.ListCommandInvocationsOutput{CommandInvocations: []*ssm.CommandInvocation{{
+		InstanceId:     aws.String("i-1"),
+		Status:         aws.String(ssm.CommandInvocationStatusSuccess),
+		CommandPlugins: []*ssm.CommandPlugin{{Output: aws.String("reloaded\n")}},
+	}}}, true)
+
+	return nil
+}
+
+func TestLambda(t *testing.T) {
+	fake := &fakeLambda{}
+	hook := &lambdaHook{lambda: fake, function: "deploy"}
+
+	output, err := hook.Deploy(context.Background(), newTestCertificate())
+	require.EqualError(t, err, "function 'deploy' failed: Unhandled")
+	require.Equal(t, "START\n{\"errorMessage\":\"keystore locked\"}", output)
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(fake.input.Payload, &payload))
+	require.Equal(t, "web", payload["group"])
+	require.Equal(t, "2027-01-30T10:00:00Z", payload["expiry"])
+	require.NotContains(t, string(fake.input.Payload), "KEY")
+}
+
+func TestEventBridge(t *testing.T) {
+	fake := &fakeEvents{}
+	hook := &eventBridgeHook{events: fake}
+
+	output, err := hook.Deploy(context.Background(), newTestCertificate())
+	require.NoError(t, err)
+	require.Equal(t, "event event-1", output)
+
+	entry := fake.input.Entries[0]
+	require.Equal(t, EventSource, aws.StringValue(entry.Source))
+	require.Equal(t, "CertificateRenewed", aws.StringValue(entry.DetailType))
+	require.Equal(t, []string{"arn:aws:acm:eu-west-1:123456789012:certificate/1"}, aws.StringValueSlice(entry.Resources))
+}
+
+func TestSSM(t *testing.T) {
+	fake := &fakeSSM{statuses: []string{ssm.CommandStatusPending, ssm.CommandStatusInProgress, ssm.CommandStatusSuccess}}
+	hook := &ssmHook{
+		ssm: fake,
+		opts: &SSMOptions{
+			Command: "systemctl reload haproxy",
+			Targets: map[string][]string{"tag:Role": {"haproxy"}},
+		},
+		pollInterval: time.Millisecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cert := newTestCertificate()
+	cert.Group = "it's"
+
+	output, err := hook.Deploy(ctx, cert)
+	require.NoError(t, err)
+	require.Equal(t, "i-1: Success\nreloaded\n", output)
+	require.Empty(t, fake.statuses)
+
+	commands := aws.StringValueSlice(fake.input.Parameters["commands"])
+	require.Equal(t, `export CERT_GROUP='it'\''s'`, commands[0])
+	require.Equal(t, "systemctl reload haproxy", commands[len(commands)-1])
+	require.Equal(t, []string{"60"}, aws.StringValueSlice(fake.input.Parameters["executionTimeout"]))
+	require.Equal(t, "tag:Role", aws.StringValue(fake.input.Targets[0].Key))
+}
@@ -0,0 +1,84 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/cloudfront/cloudfrontiface"
+	"github.com/pkg/errors"
+)
+
+const (
+	// cloudFrontRegion is the region of ACM certificates used by CloudFront
+	cloudFrontRegion = "us-east-1"
+)
+
+// To make sure that distributionHook implements Hook interface
+var _ Hook = &distributionHook{}
+
+// distributionHook sets certificates of CloudFront distributions
+type distributionHook struct {
+	cloudfront   cloudfrontiface.CloudFrontAPI
+	distribution string
+}
+
+// NewDistribution is the constructor of the hook which sets the certificate as the viewer certificate of the given CloudFront distribution.
+// The certificate must be stored in ACM of us-east-1 region. Nothing is changed if the distribution already uses it.
+func NewDistribution(provider client.ConfigProvider, distribution string) Hook {
+	return &distributionHook{
+		cloudfront:   cloudfront.New(provider),
+		distribution: distribution,
+	}
+}
+
+// Deploy implements Hook interface
+func (h *distributionHook) Deploy(ctx context.Context, cert *Certificate) (string, error) {
+	certARN, err := certificateARN(cert, cloudFrontRegion)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := h.cloudfront.GetDistributionConfigWithContext(ctx, &cloudfront.GetDistributionConfigInput{Id: aws.String(h.distribution)})
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to get config of distribution '%s'", h.distribution)
+	}
+
+	distConfig := resp.DistributionConfig
+	current := distConfig.ViewerCertificate
+	if current == nil {
+		current = &cloudfront.ViewerCertificate{}
+	}
+
+	if aws.StringValue(current.ACMCertificateArn) == certARN {
+		return fmt.Sprintf("distribution '%s' already uses certificate '%s'", h.distribution, certARN), nil
+	}
+
+	// Keep TLS settings of distributions which use custom certificates, the default certificate supports TLSv1 only
+	viewerCert := &cloudfront.ViewerCertificate{
+		ACMCertificateArn:      aws.String(certARN),
+		SSLSupportMethod:       aws.String(cloudfront.SSLSupportMethodSniOnly),
+		MinimumProtocolVersion: aws.String(cloudfront.MinimumProtocolVersionTlsv122018),
+	}
+	if !aws.BoolValue(current.CloudFrontDefaultCertificate) {
+		if current.SSLSupportMethod != nil {
+			viewerCert.SSLSupportMethod = current.SSLSupportMethod
+		}
+		if current.MinimumProtocolVersion != nil {
+			viewerCert.MinimumProtocolVersion = current.MinimumProtocolVersion
+		}
+	}
+	distConfig.ViewerCertificate = viewerCert
+
+	if _, err := h.cloudfront.UpdateDistributionWithContext(ctx, &cloudfront.UpdateDistributionInput{
+		Id:                 aws.String(h.distribution),
+		IfMatch:            resp.ETag,
+		DistributionConfig: distConfig,
+	}); err != nil {
+		return "", errors.Wrapf(err, "unable to update distribution '%s'", h.distribution)
+	}
+
+	return fmt.Sprintf("distribution '%s' updated to use certificate '%s'", h.distribution, certARN), nil
+}
@@ -0,0 +1,126 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// To make sure that commandHook implements Hook interface
+var _ Hook = &commandHook{}
+
+// certificateFile is the file with the certificate written for commands
+type certificateFile struct {
+	env  string // The environment variable with the path of the file
+	name string
+	mode os.FileMode
+	data func(cert *Certificate) []byte
+}
+
+// certificateFiles are files written for commands
+var certificateFiles = []certificateFile{
+	{env: "CERT_PATH", name: "cert.pem", mode: 0644, data: func(c *Certificate) []byte { return c.Certificate }},
+	{env: "CERT_CHAIN_PATH", name: "chain.pem", mode: 0644, data: func(c *Certificate) []byte { return c.IssuerCertificate }},
+	{env: "CERT_FULLCHAIN_PATH", name: "fullchain.pem", mode: 0644, data: fullchain},
+	{env: "CERT_KEY_PATH", name: "privkey.pem", mode: 0600, data: func(c *Certificate) []byte { return c.PrivateKey }},
+	{env: "CERT_COMBINED_PATH", name: "combined.pem", mode: 0600, data: func(c *Certificate) []byte { return append(fullchain(c), c.PrivateKey...) }},
+}
+
+// commandHook runs the local shell command
+type commandHook struct {
+	command string
+	dir     string
+}
+
+// NewCommand is the constructor of the hook which runs the given shell command.
+// The certificate is written to PEM files in the given directory, which are kept,
+// or in the temporary directory, which is removed after the command, if the directory is empty.
+// Paths of the files and metadata of the certificate are passed in environment variables, see Env.
+func NewCommand(command, dir string) Hook {
+	return &commandHook{
+		command: command,
+		dir:     dir,
+	}
+}
+
+// Deploy implements Hook interface
+func (h *commandHook) Deploy(ctx context.Context, cert *Certificate) (string, error) {
+	tmpDir, err := ioutil.TempDir("", "acme-deploy")
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir := h.dir
+	if len(dir) == 0 {
+		dir = tmpDir
+	}
+
+	env := append(os.Environ(), Env(cert)...)
+	for _, file := range certificateFiles {
+		path := filepath.Join(dir, file.name)
+		if err := writeFile(path, file.data(cert), file.mode); err != nil {
+			return "", errors.Wrapf(err, "unable to write '%s'", path)
+		}
+
+		env = append(env, file.env+"="+path)
+	}
+
+	// The output is written to the file rather than the pipe,
+	// so the command does not wait for processes which it started in background and which keep the output open
+	output, err := os.Create(filepath.Join(tmpDir, "output"))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create output file")
+	}
+	defer output.Close()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	cmd.Env = env
+	cmd.Dir = dir
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	runErr := cmd.Run()
+
+	data, err := ioutil.ReadFile(output.Name())
+	if err != nil {
+		return "", errors.Wrap(err, "unable to read output")
+	}
+
+	return string(data), runErr
+}
+
+// fullchain returns the certificate followed by its issuer chain
+func fullchain(cert *Certificate) []byte {
+	data := append([]byte(nil), cert.Certificate...)
+	return append(data, cert.IssuerCertificate...)
+}
+
+// writeFile replaces the file with the given path by the given data atomically,
+// so services never read partially written certificates
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,58 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deploy-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	hook := NewCommand(`echo "$CERT_DOMAINS $CERT_EVENT"; cat "$CERT_COMBINED_PATH"; echo failed >&2; exit 3`, dir)
+
+	output, err := hook.Deploy(context.Background(), newTestCertificate())
+	require.EqualError(t, err, "exit status 3")
+	require.Equal(t, "example.com,www.example.com CertificateRenewed\nCERT\nISSUER\nKEY\nfailed\n", output)
+
+	// Files are kept in the given directory
+	data, err := ioutil.ReadFile(filepath.Join(dir, "fullchain.pem"))
+	require.NoError(t, err)
+	require.Equal(t, "CERT\nISSUER\n", string(data))
+
+	info, err := os.Stat(filepath.Join(dir, "privkey.pem"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestCommandTemporaryDir(t *testing.T) {
+	hook := NewCommand(`cat "$CERT_PATH"; echo "$CERT_KEY_PATH"`, "")
+
+	output, err := hook.Deploy(context.Background(), newTestCertificate())
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Equal(t, "CERT", lines[0])
+
+	// The temporary directory is removed after the command
+	_, err = os.Stat(lines[1])
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestCommandTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewCommand("sleep 10 & sleep 10", "").Deploy(ctx, newTestCertificate())
+	require.Error(t, err)
+	require.True(t, time.Since(start) < 5*time.Second)
+}
@@ -0,0 +1,141 @@
+package deploy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultTimeout is the timeout of hooks which do not set it
+	DefaultTimeout = 5 * time.Minute
+
+	// maxOutputLength is the maximum length of captured output of hooks, the end of longer output is kept
+	maxOutputLength = 4096
+)
+
+// Certificate is the issued or renewed certificate deployed by hooks
+type Certificate struct {
+	// Group is the name of the certificate group
+	Group string `json:"group,omitempty"`
+
+	// Event is the type of the event of the certificate, CertificateIssued or CertificateRenewed
+	Event string `json:"event"`
+
+	Domains []string `json:"domains"`
+
+	// CertificateIDs are identifiers of the certificate inside the stores, e.g. ARNs for ACM
+	CertificateIDs []string `json:"certificate_arns,omitempty"`
+
+	Serial   string    `json:"serial,omitempty"`
+	NotAfter time.Time `json:"expiry"`
+
+	// PEM encoded certificate, its issuer chain and the private key, they are written to files for local commands only
+	Certificate       []byte `json:"-"`
+	IssuerCertificate []byte `json:"-"`
+	PrivateKey        []byte `json:"-"`
+}
+
+// Hook deploys certificates after they have been stored, e.g. reloads services using them
+type Hook interface {
+	// Deploy deploys the given certificate and returns the captured output.
+	// The hook must stop when the context is done.
+	Deploy(ctx context.Context, cert *Certificate) (string, error)
+}
+
+// Target is the hook with its name and timeout
+type Target struct {
+	Name    string
+	Hook    Hook
+	Timeout time.Duration // DefaultTimeout if zero
+}
+
+// Result is the result of running a hook
+type Result struct {
+	Name     string  `json:"name"`
+	Output   string  `json:"output,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Run runs the given hooks one after another, a failed hook does not stop the next ones.
+// Returns results of all hooks and the error describing all failures.
+func Run(targets []Target, cert *Certificate, log *logrus.Logger) ([]*Result, error) {
+	domainsStr := strings.Join(cert.Domains, ", ")
+
+	var failures []string
+
+	results := make([]*Result, len(targets))
+	for i, target := range targets {
+		timeout := target.Timeout
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		output, err := target.Hook.Deploy(ctx, cert)
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			err = errors.Errorf("timed out after %s: %s", timeout, err)
+		}
+		cancel()
+
+		results[i] = &Result{
+			Name:     target.Name,
+			Output:   truncate(output),
+			Duration: time.Since(start).Seconds(),
+		}
+
+		if err != nil {
+			results[i].Error = err.Error()
+			failures = append(failures, "'"+target.Name+"': "+err.Error())
+			log.Errorf("[%s] deploy: hook '%s' failed: %s", domainsStr, target.Name, err)
+			continue
+		}
+
+		log.Infof("[%s] deploy: hook '%s' succeeded", domainsStr, target.Name)
+	}
+
+	if len(failures) > 0 {
+		return results, errors.Errorf("deploy: %d hook(s) failed: %s", len(failures), strings.Join(failures, "; "))
+	}
+
+	return results, nil
+}
+
+// truncate returns the end of the given output if it is too long
+func truncate(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) <= maxOutputLength {
+		return output
+	}
+
+	return "..." + output[len(output)-maxOutputLength:]
+}
+
+// Env returns metadata of the given certificate as environment variables
+func Env(cert *Certificate) []string {
+	return []string{
+		"CERT_GROUP=" + cert.Group,
+		"CERT_EVENT=" + cert.Event,
+		"CERT_DOMAINS=" + strings.Join(cert.Domains, ","),
+		"CERT_ARNS=" + strings.Join(cert.CertificateIDs, ","),
+		"CERT_SERIAL=" + cert.Serial,
+		"CERT_EXPIRY=" + cert.NotAfter.UTC().Format(time.RFC3339),
+	}
+}
+
+// certificateARN returns the ARN of the given certificate in ACM of the given region
+func certificateARN(cert *Certificate, region string) (string, error) {
+	for _, id := range cert.CertificateIDs {
+		if parsed, err := arn.Parse(id); err == nil && parsed.Service == "acm" && parsed.Region == region {
+			return id, nil
+		}
+	}
+
+	return "", errors.Errorf("certificate is not stored in ACM of region '%s', add the store of the region to the group", region)
+}
@@ -0,0 +1,83 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// hookFunc is the hook implemented by the function
+type hookFunc func(ctx context.Context, cert *Certificate) (string, error)
+
+func (f hookFunc) Deploy(ctx context.Context, cert *Certificate) (string, error) {
+	return f(ctx, cert)
+}
+
+func newTestLogger() *logrus.Logger {
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	return log
+}
+
+func newTestCertificate() *Certificate {
+	return &Certificate{
+		Group:             "web",
+		Event:             "CertificateRenewed",
+		Domains:           []string{"example.com", "www.example.com"},
+		CertificateIDs:    []string{"arn:aws:acm:eu-west-1:123456789012:certificate/1"},
+		Serial:            "3a1f",
+		NotAfter:          time.Date(2027, 1, 30, 10, 0, 0, 0, time.UTC),
+		Certificate:       []byte("CERT\n"),
+		IssuerCertificate: []byte("ISSUER\n"),
+		PrivateKey:        []byte("KEY\n"),
+	}
+}
+
+func TestRun(t *testing.T) {
+	var deployed []string
+
+	results, err := Run([]Target{
+		{Name: "first", Hook: hookFunc(func(ctx context.Context, cert *Certificate) (string, error) {
+			deployed = append(deployed, "first")
+			return "reloaded\n", nil
+		})},
+		{Name: "slow", Timeout: 10 * time.Millisecond, Hook: hookFunc(func(ctx context.Context, cert *Certificate) (string, error) {
+			deployed = append(deployed, "slow")
+			<-ctx.Done()
+			return "partial", ctx.Err()
+		})},
+		{Name: "failing", Hook: hookFunc(func(ctx context.Context, cert *Certificate) (string, error) {
+			deployed = append(deployed, "failing")
+			return strings.Repeat("x", maxOutputLength+10), errors.New("exit status 1")
+		})},
+	}, newTestCertificate(), newTestLogger())
+
+	require.Equal(t, []string{"first", "slow", "failing"}, deployed)
+	require.EqualError(t, err, "deploy: 2 hook(s) failed: 'slow': timed out after 10ms: context deadline exceeded; 'failing': exit status 1")
+
+	require.Len(t, results, 3)
+	require.Equal(t, "reloaded", results[0].Output)
+	require.Empty(t, results[0].Error)
+	require.Equal(t, "partial", results[1].Output)
+	require.Equal(t, "timed out after 10ms: context deadline exceeded", results[1].Error)
+	require.Len(t, results[2].Output, maxOutputLength+3)
+	require.True(t, strings.HasPrefix(results[2].Output, "..."))
+}
+
+func TestEnv(t *testing.T) {
+	require.Equal(t, []string{
+		"CERT_GROUP=web",
+		"CERT_EVENT=CertificateRenewed",
+		"CERT_DOMAINS=example.com,www.example.com",
+		"CERT_ARNS=arn:aws:acm:eu-west-1:123456789012:certificate/1",
+		"CERT_SERIAL=3a1f",
+		"CERT_EXPIRY=2027-01-30T10:00:00Z",
+	}, Env(newTestCertificate()))
+}
@@ -0,0 +1,118 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/pkg/errors"
+)
+
+// To make sure that listenerHook implements Hook interface
+var _ Hook = &listenerHook{}
+
+// listenerHook attaches certificates to the listener of Application or Network Load Balancer
+type listenerHook struct {
+	elbv2      elbv2iface.ELBV2API
+	listener   string
+	setDefault bool
+}
+
+// NewListener is the constructor of the hook which adds the certificate to the certificate list of the given ELBv2 listener,
+// which is used for SNI, or sets it as the default certificate of the listener if setDefault is true.
+// The certificate must be stored in ACM of the region of the listener. Nothing is changed if it is already attached.
+func NewListener(provider client.ConfigProvider, listener string, setDefault bool) Hook {
+	return &listenerHook{
+		elbv2:      elbv2.New(provider),
+		listener:   listener,
+		setDefault: setDefault,
+	}
+}
+
+// Deploy implements Hook interface
+func (h *listenerHook) Deploy(ctx context.Context, cert *Certificate) (string, error) {
+	parsed, err := arn.Parse(h.listener)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid listener ARN '%s'", h.listener)
+	}
+
+	certARN, err := certificateARN(cert, parsed.Region)
+	if err != nil {
+		return "", err
+	}
+
+	if h.setDefault {
+		return h.setDefaultCertificate(ctx, certARN)
+	}
+
+	attached := false
+	err = h.describeCertificates(ctx, func(certificate *elbv2.Certificate) {
+		attached = attached || aws.StringValue(certificate.CertificateArn) == certARN
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if attached {
+		return fmt.Sprintf("certificate '%s' is already attached to listener '%s'", certARN, h.listener), nil
+	}
+
+	if _, err := h.elbv2.AddListenerCertificatesWithContext(ctx, &elbv2.AddListenerCertificatesInput{
+		ListenerArn:  aws.String(h.listener),
+		Certificates: []*elbv2.Certificate{{CertificateArn: aws.String(certARN)}},
+	}); err != nil {
+		return "", errors.Wrapf(err, "unable to add certificate to listener '%s'", h.listener)
+	}
+
+	return fmt.Sprintf("certificate '%s' added to listener '%s'", certARN, h.listener), nil
+}
+
+// setDefaultCertificate sets the given certificate as the default certificate of the listener
+func (h *listenerHook) setDefaultCertificate(ctx context.Context, certARN string) (string, error) {
+	var current string
+	err := h.describeCertificates(ctx, func(certificate *elbv2.Certificate) {
+		if aws.BoolValue(certificate.IsDefault) {
+			current = aws.StringValue(certificate.CertificateArn)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if current == certARN {
+		return fmt.Sprintf("certificate '%s' is already the default certificate of listener '%s'", certARN, h.listener), nil
+	}
+
+	if _, err := h.elbv2.ModifyListenerWithContext(ctx, &elbv2.ModifyListenerInput{
+		ListenerArn:  aws.String(h.listener),
+		Certificates: []*elbv2.Certificate{{CertificateArn: aws.String(certARN)}},
+	}); err != nil {
+		return "", errors.Wrapf(err, "unable to set default certificate of listener '%s'", h.listener)
+	}
+
+	return fmt.Sprintf("certificate '%s' set as the default certificate of listener '%s', replacing '%s'", certARN, h.listener, current), nil
+}
+
+// describeCertificates calls the given function with each certificate of the listener, including the default one
+func (h *listenerHook) describeCertificates(ctx context.Context, fn func(certificate *elbv2.Certificate)) error {
+	input := &elbv2.DescribeListenerCertificatesInput{ListenerArn: aws.String(h.listener)}
+	for {
+		resp, err := h.elbv2.DescribeListenerCertificatesWithContext(ctx, input)
+		if err != nil {
+			return errors.Wrapf(err, "unable to describe certificates of listener '%s'", h.listener)
+		}
+
+		for _, certificate := range resp.Certificates {
+			fn(certificate)
+		}
+
+		if aws.StringValue(resp.NextMarker) == "" {
+			return nil
+		}
+		input.Marker = resp.NextMarker
+	}
+}
@@ -0,0 +1,66 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+	"github.com/pkg/errors"
+)
+
+const (
+	// EventSource is the source of events published to EventBridge
+	EventSource = "acme-dns-route53"
+)
+
+// To make sure that eventBridgeHook implements Hook interface
+var _ Hook = &eventBridgeHook{}
+
+// eventBridgeHook publishes events to the default event bus of EventBridge
+type eventBridgeHook struct {
+	events cloudwatcheventsiface.CloudWatchEventsAPI
+}
+
+// NewEventBridge is the constructor of the hook which publishes the certificate as the event to the default event bus of EventBridge.
+// The source of the event is EventSource, the detail type is the event of the certificate, e.g. CertificateRenewed,
+// resources are ARNs of the certificate, and the detail is the certificate as JSON without the private key.
+func NewEventBridge(provider client.ConfigProvider) Hook {
+	return &eventBridgeHook{
+		events: cloudwatchevents.New(provider),
+	}
+}
+
+// Deploy implements Hook interface. The output is the ID of the published event.
+func (h *eventBridgeHook) Deploy(ctx context.Context, cert *Certificate) (string, error) {
+	detail, err := json.Marshal(cert)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to encode certificate")
+	}
+
+	resp, err := h.events.PutEventsWithContext(ctx, &cloudwatchevents.PutEventsInput{
+		Entries: []*cloudwatchevents.PutEventsRequestEntry{{
+			Source:     aws.String(EventSource),
+			DetailType: aws.String(cert.Event),
+			Detail:     aws.String(string(detail)),
+			Resources:  aws.StringSlice(cert.CertificateIDs),
+		}},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to publish event")
+	}
+
+	if aws.Int64Value(resp.FailedEntryCount) > 0 && len(resp.Entries) > 0 {
+		entry := resp.Entries[0]
+		return "", errors.Errorf("event rejected: %s: %s", aws.StringValue(entry.ErrorCode), aws.StringValue(entry.ErrorMessage))
+	}
+
+	var id string
+	if len(resp.Entries) > 0 {
+		id = aws.StringValue(resp.Entries[0].EventId)
+	}
+
+	return "event " + id, nil
+}
@@ -0,0 +1,61 @@
+package deploy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/pkg/errors"
+)
+
+// To make sure that lambdaHook implements Hook interface
+var _ Hook = &lambdaHook{}
+
+// lambdaHook invokes the Lambda function
+type lambdaHook struct {
+	lambda   lambdaiface.LambdaAPI
+	function string
+}
+
+// NewLambda is the constructor of the hook which invokes the given Lambda function synchronously with the certificate as JSON.
+// The private key is not passed, the function may load the certificate from ACM by its ARN.
+func NewLambda(provider client.ConfigProvider, function string) Hook {
+	return &lambdaHook{
+		lambda:   lambda.New(provider),
+		function: function,
+	}
+}
+
+// Deploy implements Hook interface. The output is the tail of the function's log followed by its response.
+func (h *lambdaHook) Deploy(ctx context.Context, cert *Certificate) (string, error) {
+	payload, err := json.Marshal(cert)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to encode certificate")
+	}
+
+	resp, err := h.lambda.InvokeWithContext(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(h.function),
+		InvocationType: aws.String(lambda.InvocationTypeRequestResponse),
+		LogType:        aws.String(lambda.LogTypeTail),
+		Payload:        payload,
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to invoke function '%s'", h.function)
+	}
+
+	var output string
+	if logs, err := base64.StdEncoding.DecodeString(aws.StringValue(resp.LogResult)); err == nil {
+		output = string(logs)
+	}
+	output += string(resp.Payload)
+
+	if resp.FunctionError != nil {
+		return output, errors.Errorf("function '%s' failed: %s", h.function, aws.StringValue(resp.FunctionError))
+	}
+
+	return output, nil
+}
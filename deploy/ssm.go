@@ -0,0 +1,171 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ssmDocument is the document of commands run by SSM hooks
+	ssmDocument = "AWS-RunShellScript"
+
+	// ssmPollInterval is the interval of checks of the status of commands
+	ssmPollInterval = 5 * time.Second
+)
+
+// To make sure that ssmHook implements Hook interface
+var _ Hook = &ssmHook{}
+
+// SSMOptions is the options of the SSM Run Command hook
+type SSMOptions struct {
+	// Command is the shell command run on the instances
+	Command string
+
+	// InstanceIDs are IDs of instances the command is run on
+	InstanceIDs []string
+
+	// Targets select instances the command is run on by keys and values, e.g. "tag:Role": ["haproxy"]
+	Targets map[string][]string
+}
+
+// ssmHook runs the shell command on instances by SSM Run Command
+type ssmHook struct {
+	ssm          ssmiface.SSMAPI
+	opts         *SSMOptions
+	pollInterval time.Duration
+}
+
+// NewSSM is the constructor of the hook which runs the shell command on instances by SSM Run Command and waits for it.
+// Metadata of the certificate are exported as environment variables, see Env. Certificate files are not written,
+// the instances may load the certificate from ACM by its ARN.
+func NewSSM(provider client.ConfigProvider, opts *SSMOptions) Hook {
+	return &ssmHook{
+		ssm:          ssm.New(provider),
+		opts:         opts,
+		pollInterval: ssmPollInterval,
+	}
+}
+
+// Deploy implements Hook interface. The output is the output of the command on each instance.
+func (h *ssmHook) Deploy(ctx context.Context, cert *Certificate) (string, error) {
+	commands := make([]string, 0, len(Env(cert))+1)
+	for _, variable := range Env(cert) {
+		kv := strings.SplitN(variable, "=", 2)
+		commands = append(commands, fmt.Sprintf("export %s=%s", kv[0], shellQuote(kv[1])))
+	}
+	commands = append(commands, h.opts.Command)
+
+	input := &ssm.SendCommandInput{
+		DocumentName: aws.String(ssmDocument),
+		Comment:      aws.String(truncateComment("Deploy certificate of " + strings.Join(cert.Domains, ", "))),
+		InstanceIds:  aws.StringSlice(h.opts.InstanceIDs),
+		Parameters:   map[string][]*string{"commands": aws.StringSlice(commands)},
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		seconds := int64(math.Ceil(time.Until(deadline).Seconds()))
+		input.Parameters["executionTimeout"] = []*string{aws.String(strconv.FormatInt(seconds, 10))}
+	}
+
+	keys := make([]string, 0, len(h.opts.Targets))
+	for key := range h.opts.Targets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		input.Targets = append(input.Targets, &ssm.Target{Key: aws.String(key), Values: aws.StringSlice(h.opts.Targets[key])})
+	}
+
+	resp, err := h.ssm.SendCommandWithContext(ctx, input)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to send command")
+	}
+
+	commandID := aws.StringValue(resp.Command.CommandId)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", errors.Wrapf(ctx.Err(), "command '%s' has not finished", commandID)
+		case <-time.After(h.pollInterval):
+		}
+
+		output, status, err := h.commandOutput(ctx, commandID)
+		if err != nil {
+			return "", err
+		}
+
+		switch status {
+		case ssm.CommandStatusSuccess:
+			return output, nil
+		case ssm.CommandStatusFailed, ssm.CommandStatusCancelled, ssm.CommandStatusTimedOut:
+			return output, errors.Errorf("command '%s' finished with status %s", commandID, status)
+		}
+	}
+}
+
+// commandOutput returns outputs of invocations of the given command on each instance and the status of the command
+func (h *ssmHook) commandOutput(ctx context.Context, commandID string) (string, string, error) {
+	commands, err := h.ssm.ListCommandsWithContext(ctx, &ssm.ListCommandsInput{CommandId: aws.String(commandID)})
+	if err != nil {
+		return "", "", errors.Wrapf(err, "unable to get status of command '%s'", commandID)
+	}
+
+	if len(commands.Commands) == 0 {
+		return "", ssm.CommandStatusPending, nil
+	}
+
+	status := aws.StringValue(commands.Commands[0].Status)
+	switch status {
+	case ssm.CommandStatusPending, ssm.CommandStatusInProgress, ssm.CommandStatusCancelling:
+		return "", status, nil
+	}
+
+	var b strings.Builder
+	err = h.ssm.ListCommandInvocationsPagesWithContext(ctx, &ssm.ListCommandInvocationsInput{
+		CommandId: aws.String(commandID),
+		Details:   aws.Bool(true),
+	}, func(page *ssm.ListCommandInvocationsOutput, _ bool) bool {
+		for _, invocation := range page.CommandInvocations {
+			fmt.Fprintf(&b, "%s: %s\n", aws.StringValue(invocation.InstanceId), aws.StringValue(invocation.Status))
+			for _, plugin := range invocation.CommandPlugins {
+				if output := strings.TrimSpace(aws.StringValue(plugin.Output)); len(output) > 0 {
+					b.WriteString(output + "\n")
+				}
+			}
+		}
+
+		return true
+	})
+	if err != nil {
+		return "", "", errors.Wrapf(err, "unable to get output of command '%s'", commandID)
+	}
+
+	return b.String(), status, nil
+}
+
+// shellQuote quotes the given value for POSIX shells
+func shellQuote(val string) string {
+	return "'" + strings.Replace(val, "'", `'\''`, -1) + "'"
+}
+
+// truncateComment truncates the given comment to the maximum length of comments of commands
+func truncateComment(comment string) string {
+	if len(comment) > 100 {
+		return comment[:97] + "..."
+	}
+
+	return comment
+}
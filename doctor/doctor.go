@@ -0,0 +1,331 @@
+package doctor
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/pkg/errors"
+
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/iampolicy"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+const (
+	// directoryTimeout is the timeout of requests to ACME directories
+	directoryTimeout = 30 * time.Second
+)
+
+// Status is the status of a check
+type Status string
+
+const (
+	// StatusPass means that the check passed
+	StatusPass Status = "PASS"
+
+	// StatusWarn means that the check could not be done, or passed with a remark
+	StatusWarn Status = "WARN"
+
+	// StatusFail means that the check failed
+	StatusFail Status = "FAIL"
+)
+
+// Check is the result of a single check
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the checklist of all checks
+type Report struct {
+	Checks []*Check `json:"checks"`
+}
+
+// Options is the options of the doctor
+type Options struct {
+	Session *session.Session
+
+	// Jobs are certificate groups with their handlers to check
+	Jobs []*runner.Job
+
+	// ConfigLocation is the location of the configuration file, if any
+	ConfigLocation string
+}
+
+// Doctor verifies that the environment is able to process the configured certificate groups
+type Doctor struct {
+	opts       *Options
+	iam        iamiface.IAMAPI
+	sts        stsiface.STSAPI
+	httpClient *http.Client
+}
+
+// New is the constructor of Doctor
+func New(opts *Options) *Doctor {
+	return &Doctor{
+		opts:       opts,
+		iam:        iam.New(opts.Session),
+		sts:        sts.New(opts.Session),
+		httpClient: &http.Client{Timeout: directoryTimeout},
+	}
+}
+
+// Run runs all checks and returns the report
+func (d *Doctor) Run() *Report {
+	r := &Report{}
+
+	region := aws.StringValue(d.opts.Session.Config.Region)
+	if len(region) == 0 {
+		r.add("AWS region", StatusFail, "region is not configured, set AWS_REGION env var or the region of the profile")
+	} else {
+		r.add("AWS region", StatusPass, region)
+	}
+
+	identity, err := d.callerIdentity()
+	if err != nil {
+		r.add("AWS credentials", StatusFail, err.Error())
+	} else {
+		r.add("AWS credentials", StatusPass, aws.StringValue(identity.Arn))
+	}
+
+	zoneIDs := d.checkHostedZones(r)
+
+	if identity != nil {
+		d.checkPermissions(r, identity, region, zoneIDs)
+	}
+
+	d.checkCA(r)
+
+	return r
+}
+
+// callerIdentity returns the identity of the credentials of the session
+func (d *Doctor) callerIdentity() (*sts.GetCallerIdentityOutput, error) {
+	if _, err := d.opts.Session.Config.Credentials.Get(); err != nil {
+		return nil, errors.Wrap(err, "no credentials found")
+	}
+
+	identity, err := d.sts.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, errors.Wrap(err, "credentials are not valid")
+	}
+
+	return identity, nil
+}
+
+// checkHostedZones checks that the hosted zone of each domain is found, and returns IDs of found zones
+func (d *Doctor) checkHostedZones(r *Report) []string {
+	var zoneIDs []string
+	for _, job := range d.opts.Jobs {
+		for _, domain := range job.Group.Domains {
+			name := fmt.Sprintf("Hosted zone of %s", domain)
+
+			zoneID, err := job.DNS01.HostedZone(domain)
+			if err != nil {
+				r.add(name, StatusFail, err.Error())
+				continue
+			}
+
+			r.add(name, StatusPass, zoneID)
+			zoneIDs = append(zoneIDs, zoneID)
+		}
+	}
+
+	return zoneIDs
+}
+
+// checkPermissions simulates the policies of the given identity with the permissions required by each subsystem
+func (d *Doctor) checkPermissions(r *Report, identity *sts.GetCallerIdentityOutput, region string, zoneIDs []string) {
+	principal, err := d.principalARN(aws.StringValue(identity.Arn))
+	if err != nil {
+		r.add("IAM permissions", StatusWarn, err.Error())
+		return
+	}
+
+	groups := make([]*config.Group, 0, len(d.opts.Jobs))
+	for _, job := range d.opts.Jobs {
+		groups = append(groups, job.Group)
+	}
+
+	parsed, _ := arn.Parse(aws.StringValue(identity.Arn))
+	statements := iampolicy.Required(&iampolicy.Options{
+		Partition:      parsed.Partition,
+		AccountID:      aws.StringValue(identity.Account),
+		Region:         region,
+		Groups:         groups,
+		HostedZoneIDs:  zoneIDs,
+		ConfigLocation: d.opts.ConfigLocation,
+	})
+
+	// Subsystems are reported in the order of their first statement
+	var subsystems []string
+	denied := make(map[string][]string)
+	for _, statement := range statements {
+		if _, ok := denied[statement.Subsystem]; !ok {
+			subsystems = append(subsystems, statement.Subsystem)
+			denied[statement.Subsystem] = nil
+		}
+
+		deniedActions, err := d.simulate(principal, statement)
+		if err != nil {
+			r.add("IAM permissions", StatusWarn, fmt.Sprintf("unable to simulate policies of %s: %s", principal, err))
+			return
+		}
+
+		denied[statement.Subsystem] = append(denied[statement.Subsystem], deniedActions...)
+	}
+
+	for _, subsystem := range subsystems {
+		name := fmt.Sprintf("IAM permissions of %s", subsystem)
+		if len(denied[subsystem]) > 0 {
+			r.add(name, StatusFail, "denied "+strings.Join(denied[subsystem], ", "))
+		} else {
+			r.add(name, StatusPass, "")
+		}
+	}
+}
+
+// simulate simulates the policies of the given principal with the given statement and returns denied actions
+func (d *Doctor) simulate(principal string, statement *iampolicy.Statement) ([]string, error) {
+	var denied []string
+	err := d.iam.SimulatePrincipalPolicyPages(&iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(principal),
+		ActionNames:     aws.StringSlice(statement.Actions),
+		ResourceArns:    aws.StringSlice(statement.Resources),
+	}, func(page *iam.SimulatePolicyResponse, lastPage bool) bool {
+		for _, result := range page.EvaluationResults {
+			if aws.StringValue(result.EvalDecision) == iam.PolicyEvaluationDecisionTypeAllowed {
+				continue
+			}
+
+			resource := aws.StringValue(result.EvalResourceName)
+			if len(resource) == 0 || resource == "*" {
+				denied = append(denied, aws.StringValue(result.EvalActionName))
+			} else {
+				denied = append(denied, fmt.Sprintf("%s on %s", aws.StringValue(result.EvalActionName), resource))
+			}
+		}
+
+		return true
+	})
+
+	return denied, err
+}
+
+// principalARN returns the ARN of the IAM user or role of the given caller identity ARN.
+// Assumed role sessions are simulated by their roles.
+func (d *Doctor) principalARN(identityARN string) (string, error) {
+	parsed, err := arn.Parse(identityARN)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid identity ARN '%s'", identityARN)
+	}
+
+	if parsed.Service != sts.ServiceName || !strings.HasPrefix(parsed.Resource, "assumed-role/") {
+		return identityARN, nil
+	}
+
+	roleName := strings.Split(parsed.Resource, "/")[1]
+
+	// The role may have a path, which is not a part of the assumed role ARN
+	if role, err := d.iam.GetRole(&iam.GetRoleInput{RoleName: aws.String(roleName)}); err == nil {
+		return aws.StringValue(role.Role.Arn), nil
+	}
+
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", parsed.Partition, parsed.AccountID, roleName), nil
+}
+
+// checkCA checks that ACME directories of the groups are reachable, and accounts of the groups are valid
+func (d *Doctor) checkCA(r *Report) {
+	directories := make(map[string]bool)
+	accounts := make(map[string]bool)
+	for _, job := range d.opts.Jobs {
+		dirURL := job.Handler.CADirURL()
+		if !directories[dirURL] {
+			directories[dirURL] = true
+
+			if err := d.checkDirectory(dirURL); err != nil {
+				r.add(fmt.Sprintf("ACME directory %s", dirURL), StatusFail, err.Error())
+				continue
+			}
+			r.add(fmt.Sprintf("ACME directory %s", dirURL), StatusPass, "reachable")
+		}
+
+		account := dirURL + " " + job.Group.Email
+		if accounts[account] {
+			continue
+		}
+		accounts[account] = true
+
+		name := fmt.Sprintf("ACME account %s", job.Group.Email)
+
+		status, err := job.Handler.AccountStatus(job.Group.Email)
+		switch {
+		case err != nil:
+			r.add(name, StatusFail, err.Error())
+		case len(status) == 0:
+			r.add(name, StatusPass, "not registered yet, the account is registered by the first order")
+		case status == "valid":
+			r.add(name, StatusPass, status)
+		default:
+			r.add(name, StatusFail, fmt.Sprintf("account is %s", status))
+		}
+	}
+}
+
+// checkDirectory requests the ACME directory with the given URL
+func (d *Doctor) checkDirectory(dirURL string) error {
+	resp, err := d.httpClient.Get(dirURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// add adds the check with the given result
+func (r *Report) add(name string, status Status, detail string) {
+	r.Checks = append(r.Checks, &Check{Name: name, Status: status, Detail: detail})
+}
+
+// Count returns the number of checks with the given status
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, check := range r.Checks {
+		if check.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+// String returns the human-readable checklist
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "[%s] %s", check.Status, check.Name)
+		if len(check.Detail) > 0 {
+			fmt.Fprintf(&b, ": %s", check.Detail)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "Doctor: %d passed, %d warnings, %d failed\n", r.Count(StatusPass), r.Count(StatusWarn), r.Count(StatusFail))
+
+	return b.String()
+}
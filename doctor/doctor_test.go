@@ -0,0 +1,110 @@
+package doctor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/iampolicy"
+)
+
+// fakeIAM allows actions listed in the map and knows roles of the map
+type fakeIAM struct {
+	iamiface.IAMAPI
+
+	allowed map[string]bool
+	roles   map[string]string
+}
+
+func (f *fakeIAM) GetRole(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+	roleARN, ok := f.roles[aws.StringValue(input.RoleName)]
+	if !ok {
+		return nil, errors.New("AccessDenied")
+	}
+
+	return &iam.GetRoleOutput{Role: &iam.Role{Arn: aws.String(roleARN)}}, nil
+}
+
+func (f *fakeIAM) SimulatePrincipalPolicyPages(input *iam.SimulatePrincipalPolicyInput, fn func(*iam.SimulatePolicyResponse, bool) bool) error {
+	var results []*iam.EvaluationResult
+	for _, action := range input.ActionNames {
+		for _, resource := range input.ResourceArns {
+			decision := iam.PolicyEvaluationDecisionTypeImplicitDeny
+			if f.allowed[aws.StringValue(action)] {
+				decision = iam.PolicyEvaluationDecisionTypeAllowed
+			}
+
+			results = append(results, &iam.EvaluationResult{
+				EvalActionName:   action,
+				EvalResourceName: resource,
+				EvalDecision:     aws.String(decision),
+			})
+		}
+	}
+
+	fn(&iam.SimulatePolicyResponse{EvaluationResults: results}, true)
+
+	return nil
+}
+
+func TestPrincipalARN(t *testing.T) {
+	d := &Doctor{iam: &fakeIAM{roles: map[string]string{"with-path": "arn:aws:iam::123456789012:role/service/with-path"}}}
+
+	testTable := []*struct {
+		testName    string
+		identityARN string
+		expectedARN string
+	}{
+		{
+			testName:    "user",
+			identityARN: "arn:aws:iam::123456789012:user/admin",
+			expectedARN: "arn:aws:iam::123456789012:user/admin",
+		},
+		{
+			testName:    "assumed role with path",
+			identityARN: "arn:aws:sts::123456789012:assumed-role/with-path/session",
+			expectedARN: "arn:aws:iam::123456789012:role/service/with-path",
+		},
+		{
+			testName:    "assumed role without access to IAM",
+			identityARN: "arn:aws-cn:sts::123456789012:assumed-role/lambda-executor/acme-dns-route53",
+			expectedARN: "arn:aws-cn:iam::123456789012:role/lambda-executor",
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			principal, err := d.principalARN(tt.identityARN)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedARN, principal)
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	d := &Doctor{iam: &fakeIAM{allowed: map[string]bool{"route53:ListHostedZones": true}}}
+
+	denied, err := d.simulate("arn:aws:iam::123456789012:user/admin", &iampolicy.Statement{
+		Actions:   []string{"route53:ListHostedZones", "route53:ChangeResourceRecordSets"},
+		Resources: []string{"arn:aws:route53:::hostedzone/Z1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"route53:ChangeResourceRecordSets on arn:aws:route53:::hostedzone/Z1"}, denied)
+}
+
+func TestReportString(t *testing.T) {
+	r := &Report{}
+	r.add("AWS region", StatusPass, "eu-west-1")
+	r.add("IAM permissions of SNS", StatusFail, "denied sns:Publish on arn:aws:sns:eu-west-1:123456789012:certs")
+	r.add("IAM permissions of ACM", StatusPass, "")
+
+	require.Equal(t, `[PASS] AWS region: eu-west-1
+[FAIL] IAM permissions of SNS: denied sns:Publish on arn:aws:sns:eu-west-1:123456789012:certs
+[PASS] IAM permissions of ACM
+Doctor: 2 passed, 0 warnings, 1 failed
+`, r.String())
+}
@@ -3,19 +3,20 @@ module github.com/begmaroman/acme-dns-route53
 go 1.12
 
 require (
-	github.com/aws/aws-lambda-go v1.10.0
+	github.com/aws/aws-lambda-go v1.19.1
 	github.com/aws/aws-sdk-go v1.19.19
 	github.com/cenkalti/backoff v2.1.1+incompatible // indirect
 	github.com/go-acme/lego v2.5.0+incompatible
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
-	github.com/miekg/dns v1.1.8 // indirect
+	github.com/miekg/dns v1.1.8
 	github.com/pkg/errors v0.8.1
 	github.com/sirupsen/logrus v1.4.1
 	github.com/spf13/cobra v0.0.3
 	github.com/spf13/pflag v1.0.3 // indirect
-	github.com/stretchr/testify v1.3.0
+	github.com/stretchr/testify v1.6.1
 	golang.org/x/crypto v0.0.0-20190426145343-a29dc8fdc734 // indirect
 	golang.org/x/net v0.0.0-20190424112056-4829fb13d2c6 // indirect
 	golang.org/x/sync v0.0.0-20190423024810-112230192c58 // indirect
-	gopkg.in/square/go-jose.v2 v2.3.1 // indirect
+	gopkg.in/square/go-jose.v2 v2.3.1
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"hash/fnv"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/acme"
+	"github.com/pkg/errors"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	// ariTimeout is the timeout of requests to ACME Renewal Information endpoints
+	ariTimeout = 30 * time.Second
+
+	// problemAlreadyReplaced is the type of the problem when the certificate in "replaces" field has been replaced already
+	problemAlreadyReplaced = "urn:ietf:params:acme:error:alreadyReplaced"
+
+	// problemMalformed is the type of the problem when the request is malformed, e.g. the CA does not accept "replaces" field
+	problemMalformed = "urn:ietf:params:acme:error:malformed"
+)
+
+var (
+	// errARINotSupported is the error when the CA does not provide ACME Renewal Information
+	errARINotSupported = errors.New("CA does not support ACME Renewal Information")
+
+	// ariHTTPClient is the HTTP client to request ACME Renewal Information
+	ariHTTPClient = &http.Client{Timeout: ariTimeout}
+)
+
+// ariDirectory contains the ACME directory endpoints used by ACME Renewal Information (RFC 9773)
+type ariDirectory struct {
+	NewOrderURL    string `json:"newOrder"`
+	RenewalInfoURL string `json:"renewalInfo"`
+}
+
+// renewalInfo is the ACME Renewal Information of a certificate
+type renewalInfo struct {
+	SuggestedWindow struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	} `json:"suggestedWindow"`
+	ExplanationURL string `json:"explanationURL"`
+}
+
+// renewal describes the renewal of an existing certificate
+type renewal struct {
+	// replaces is the ARI identifier of the replaced certificate sent in the new order
+	replaces string
+
+	// newOrderURL is the URL of the newOrder endpoint of the CA
+	newOrderURL string
+
+	// explanationURL is the URL of the page which explains the renewal window suggested by the CA
+	explanationURL string
+}
+
+// getRenewalInfo retrieves ACME Renewal Information of the certificate with the given ID from the CA with the given directory URL
+func getRenewalInfo(caDirURL, certID string) (*renewalInfo, *ariDirectory, error) {
+	var dir ariDirectory
+	if err := getJSON(caDirURL, &dir); err != nil {
+		return nil, nil, errors.Wrap(err, "unable to get ACME directory")
+	}
+
+	if len(dir.RenewalInfoURL) == 0 {
+		return nil, nil, errARINotSupported
+	}
+
+	var info renewalInfo
+	if err := getJSON(strings.TrimSuffix(dir.RenewalInfoURL, "/")+"/"+certID, &info); err != nil {
+		return nil, nil, errors.Wrap(err, "unable to get renewal information")
+	}
+
+	if info.SuggestedWindow.End.Before(info.SuggestedWindow.Start) {
+		return nil, nil, errors.New("invalid suggested renewal window")
+	}
+
+	return &info, &dir, nil
+}
+
+// selectTime selects a random time within the suggested renewal window.
+// The selection is seeded with the given certificate ID, so the same time is selected on every run.
+func (i *renewalInfo) selectTime(certID string) time.Time {
+	window := i.SuggestedWindow.End.Sub(i.SuggestedWindow.Start)
+	if window <= 0 {
+		return i.SuggestedWindow.Start
+	}
+
+	seed := fnv.New64a()
+	seed.Write([]byte(certID))
+
+	return i.SuggestedWindow.Start.Add(time.Duration(rand.New(rand.NewSource(int64(seed.Sum64()))).Int63n(int64(window))))
+}
+
+// ariCertificateID builds the unique identifier of the certificate defined in RFC 9773, section 4.1
+func ariCertificateID(cert *x509.Certificate) (string, error) {
+	if len(cert.AuthorityKeyId) == 0 {
+		return "", errors.New("certificate does not contain authority key identifier")
+	}
+
+	// The serial number is DER encoded without tag and length, so the positive number with the high bit set is prefixed by zero
+	serial := cert.SerialNumber.Bytes()
+	if len(serial) == 0 || serial[0]&0x80 != 0 {
+		serial = append([]byte{0}, serial...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(cert.AuthorityKeyId) + "." + base64.RawURLEncoding.EncodeToString(serial), nil
+}
+
+// getJSON requests the given URL and decodes the JSON response into the given value
+func getJSON(url string, v interface{}) error {
+	resp, err := ariHTTPClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected response status %d from '%s'", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// replacesTransport adds the "replaces" field (RFC 9773, section 5) to newOrder requests.
+// lego does not support the field, so the signed request is decoded and re-signed with the account key.
+type replacesTransport struct {
+	http.RoundTripper
+
+	newOrderURL string
+	replaces    string
+	key         crypto.PrivateKey
+}
+
+// RoundTrip implements http.RoundTripper interface
+func (t *replacesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || req.Body == nil || req.URL.String() != t.newOrderURL {
+		return t.RoundTripper.RoundTrip(req)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read newOrder request")
+	}
+
+	if body, err = t.resign(body); err != nil {
+		return nil, errors.Wrap(err, "unable to add replaces field to newOrder request")
+	}
+
+	// The original request must not be modified
+	newReq := new(http.Request)
+	*newReq = *req
+	newReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	newReq.ContentLength = int64(len(body))
+	newReq.GetBody = nil
+
+	return t.RoundTripper.RoundTrip(newReq)
+}
+
+// resign adds the "replaces" field to the payload of the given JWS and signs it again with the same protected header
+func (t *replacesTransport) resign(body []byte) ([]byte, error) {
+	signed, err := jose.ParseSigned(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(signed.Signatures) != 1 {
+		return nil, errors.New("unexpected number of signatures")
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(signed.UnsafePayloadWithoutVerification(), &payload); err != nil {
+		return nil, err
+	}
+
+	payload["replaces"] = t.replaces
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	header := signed.Signatures[0].Header
+
+	options := jose.SignerOptions{
+		NonceSource: staticNonce(header.Nonce),
+		ExtraHeaders: map[jose.HeaderKey]interface{}{
+			"url": header.ExtraHeaders["url"],
+		},
+	}
+
+	if len(header.KeyID) == 0 {
+		options.EmbedJWK = true
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(header.Algorithm),
+		Key:       jose.JSONWebKey{Key: t.key, KeyID: header.KeyID},
+	}, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	resigned, err := signer.Sign(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(resigned.FullSerialize()), nil
+}
+
+// staticNonce is the jose.NonceSource which returns the nonce of the original request
+type staticNonce string
+
+// Nonce implements jose.NonceSource interface
+func (n staticNonce) Nonce() (string, error) {
+	return string(n), nil
+}
+
+// replacesRejected checks if the given error of obtaining the certificate replacing another one
+// is the CA rejecting the "replaces" field of the new order, so the order may be retried without it
+func replacesRejected(r *renewal, err error) bool {
+	if r == nil || len(r.replaces) == 0 {
+		return false
+	}
+
+	problem, ok := errors.Cause(err).(*acme.ProblemDetails)
+	if !ok {
+		return false
+	}
+
+	switch problem.Type {
+	case problemAlreadyReplaced:
+		return true
+	case problemMalformed:
+		return strings.Contains(strings.ToLower(problem.Detail), "replaces")
+	default:
+		return false
+	}
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/acme"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func TestARICertificateID(t *testing.T) {
+	testTable := []*struct {
+		testName   string
+		cert       *x509.Certificate
+		expectedID string
+		expectErr  bool
+	}{
+		{
+			testName: "RFC 9773 example",
+			cert: &x509.Certificate{
+				AuthorityKeyId: []byte{0x69, 0x88, 0x5B, 0x6B, 0x87, 0x46, 0x40, 0x41, 0xE1, 0xB3, 0x7B, 0x84, 0x7B, 0xA0, 0xAE, 0x2C, 0xDE, 0x01, 0xC8, 0xD4},
+				SerialNumber:   big.NewInt(0x87654321),
+			},
+			expectedID: "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE",
+		},
+		{
+			testName: "serial without high bit",
+			cert: &x509.Certificate{
+				AuthorityKeyId: []byte{0x01},
+				SerialNumber:   big.NewInt(0x0102),
+			},
+			expectedID: "AQ.AQI",
+		},
+		{
+			testName: "missing authority key identifier",
+			cert: &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			certID, err := ariCertificateID(tt.cert)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedID, certID)
+		})
+	}
+}
+
+func TestRenewalInfoSelectTime(t *testing.T) {
+	info := &renewalInfo{}
+	info.SuggestedWindow.Start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	info.SuggestedWindow.End = info.SuggestedWindow.Start.Add(48 * time.Hour)
+
+	selected := info.selectTime("aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE")
+
+	require.False(t, selected.Before(info.SuggestedWindow.Start))
+	require.True(t, selected.Before(info.SuggestedWindow.End))
+	require.Equal(t, selected, info.selectTime("aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE"))
+}
+
+func TestReplacesTransportResign(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key:       jose.JSONWebKey{Key: key, KeyID: "https://ca.test/acct/1"},
+	}, &jose.SignerOptions{
+		NonceSource:  staticNonce("nonce"),
+		ExtraHeaders: map[jose.HeaderKey]interface{}{"url": "https://ca.test/new-order"},
+	})
+	require.NoError(t, err)
+
+	signed, err := signer.Sign([]byte(`{"identifiers":[{"type":"dns","value":"example.com"}]}`))
+	require.NoError(t, err)
+
+	transport := &replacesTransport{replaces: "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE", key: key}
+
+	body, err := transport.resign([]byte(signed.FullSerialize()))
+	require.NoError(t, err)
+
+	resigned, err := jose.ParseSigned(string(body))
+	require.NoError(t, err)
+
+	payload, err := resigned.Verify(&key.PublicKey)
+	require.NoError(t, err)
+
+	var order map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &order))
+	require.Equal(t, "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE", order["replaces"])
+	require.NotNil(t, order["identifiers"])
+
+	header := resigned.Signatures[0].Header
+	require.Equal(t, "nonce", header.Nonce)
+	require.Equal(t, "https://ca.test/acct/1", header.KeyID)
+	require.Equal(t, "https://ca.test/new-order", header.ExtraHeaders["url"])
+}
+
+func TestReplacesRejected(t *testing.T) {
+	replacing := &renewal{replaces: "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE"}
+
+	testTable := []*struct {
+		testName       string
+		renewal        *renewal
+		err            error
+		expectedResult bool
+	}{
+		{
+			testName:       "already replaced",
+			renewal:        replacing,
+			err:            &acme.ProblemDetails{Type: problemAlreadyReplaced, Detail: "certificate has already been replaced"},
+			expectedResult: true,
+		},
+		{
+			testName:       "malformed replaces",
+			renewal:        replacing,
+			err:            errors.Wrap(&acme.ProblemDetails{Type: problemMalformed, Detail: "Invalid replaces field"}, "order"),
+			expectedResult: true,
+		},
+		{
+			testName: "malformed order",
+			renewal:  replacing,
+			err:      &acme.ProblemDetails{Type: problemMalformed, Detail: "Invalid identifiers"},
+		},
+		{
+			testName: "challenge failure",
+			renewal:  replacing,
+			err:      &acme.ProblemDetails{Type: "urn:ietf:params:acme:error:unauthorized", Detail: "Incorrect TXT record"},
+		},
+		{
+			testName: "not a problem",
+			renewal:  replacing,
+			err:      errors.New("time limit exceeded"),
+		},
+		{
+			testName: "not replacing",
+			err:      &acme.ProblemDetails{Type: problemAlreadyReplaced},
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			require.Equal(t, tt.expectedResult, replacesRejected(tt.renewal, tt.err))
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/lego"
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+const (
+	// caaTimeout is the timeout of DNS queries of CAA records
+	caaTimeout = 10 * time.Second
+
+	// caaFlagCritical is the issuer critical flag of CAA records (RFC 8659, section 4.1)
+	caaFlagCritical = 128
+
+	caaTagIssue     = "issue"
+	caaTagIssueWild = "issuewild"
+	caaTagIodef     = "iodef"
+)
+
+var (
+	// defaultCAANameservers are used if the system resolvers are not configured
+	defaultCAANameservers = []string{"8.8.8.8:53", "8.8.4.4:53"}
+
+	// lookupCAA looks up CAA records of the given domain, replaced by tests
+	lookupCAA = queryCAA
+
+	// knownCAAIdentities maps directory URLs of well-known CAs to their CAA identities,
+	// so they are not requested from the CA, e.g. by dry runs
+	knownCAAIdentities = map[string][]string{
+		lego.LEDirectoryProduction: {"letsencrypt.org"},
+		lego.LEDirectoryStaging:    {"letsencrypt.org"},
+	}
+)
+
+// CAAUpdater creates or updates CAA records
+type CAAUpdater interface {
+	// UpsertCAA replaces CAA records of the given domain with the given values, e.g. `0 issue "letsencrypt.org"`
+	UpsertCAA(domain string, values []string) error
+}
+
+// caaDirectory contains the ACME directory metadata used by the CAA preflight
+type caaDirectory struct {
+	Meta struct {
+		CAAIdentities []string `json:"caaIdentities"`
+	} `json:"meta"`
+}
+
+// caaViolation describes the CAA record set which does not authorize the CA to issue a certificate for the domain
+type caaViolation struct {
+	domain   string
+	name     string // The name where the relevant record set is found
+	wildcard bool
+	records  []*dns.CAA
+}
+
+// String implements fmt.Stringer interface
+func (v *caaViolation) String() string {
+	values := make([]string, len(v.records))
+	for i, record := range v.records {
+		values[i] = caaValue(record)
+	}
+
+	return fmt.Sprintf("CAA records of '%s' do not authorize the CA for '%s': %s", v.name, v.domain, strings.Join(values, ", "))
+}
+
+// checkCAA verifies that CAA records of all given domains authorize the CA of the handler (RFC 8659).
+// Unauthorized record sets are updated to authorize the CA if the CAA updater is configured,
+// or added to the given plan of the dry run instead.
+func (h *CertificateHandler) checkCAA(domains []string, plan *Plan) error {
+	if h.disableCAA {
+		return nil
+	}
+
+	domainsStr := strings.Join(domains, domainsJoinChar)
+
+	identities, err := h.resolveCAAIdentities(domainsStr)
+	if err != nil {
+		return err
+	}
+
+	if len(identities) == 0 {
+		return nil
+	}
+
+	var violations []string
+	for _, domain := range domains {
+		v, err := findCAAViolation(domain, identities)
+		if err != nil {
+			return errors.Wrapf(err, "handler: unable to verify CAA records of '%s'", domain)
+		}
+
+		if v == nil {
+			continue
+		}
+
+		if h.caaUpdater == nil {
+			violations = append(violations, v.String())
+			continue
+		}
+
+		if plan != nil {
+			plan.CAAUpdates = append(plan.CAAUpdates, v.name)
+			continue
+		}
+
+		if err := h.authorizeCAA(v, identities[0]); err != nil {
+			return err
+		}
+	}
+
+	if len(violations) > 0 {
+		return errors.Errorf("handler: CAA preflight failed: %s", strings.Join(violations, "; "))
+	}
+
+	return nil
+}
+
+// resolveCAAIdentities returns CAA identities of the CA: the configured ones, the known ones of well-known CAs,
+// or the ones published in the directory of the CA. Dry runs do not contact the CA, so CAA records are not verified
+// by them if identities of the CA are not known. Returns nil if CAA records cannot be verified.
+func (h *CertificateHandler) resolveCAAIdentities(domainsStr string) ([]string, error) {
+	if len(h.caaIdentities) > 0 {
+		return h.caaIdentities, nil
+	}
+
+	if identities, ok := knownCAAIdentities[h.caDirURL]; ok {
+		return identities, nil
+	}
+
+	if h.dryRun {
+		h.log.Warnf("[%s] handler: dry run, CAA identities of the CA are not known, CAA records are not verified, set caa_identities", domainsStr)
+		return nil, nil
+	}
+
+	var dir caaDirectory
+	if err := getJSON(h.caDirURL, &dir); err != nil {
+		return nil, errors.Wrap(err, "handler: unable to get ACME directory")
+	}
+
+	if len(dir.Meta.CAAIdentities) == 0 {
+		h.log.Warnf("[%s] handler: CA does not provide CAA identities, CAA records are not verified", domainsStr)
+	}
+
+	return dir.Meta.CAAIdentities, nil
+}
+
+// authorizeCAA adds the CAA record authorizing the CA with the given identity to the record set of the given violation
+func (h *CertificateHandler) authorizeCAA(v *caaViolation, identity string) error {
+	tag := caaTagIssue
+	if v.wildcard && hasCAATag(v.records, caaTagIssueWild) {
+		tag = caaTagIssueWild
+	}
+
+	values := make([]string, 0, len(v.records)+1)
+	for _, record := range v.records {
+		values = append(values, caaValue(record))
+	}
+	values = append(values, fmt.Sprintf("0 %s %q", tag, identity))
+
+	h.log.Infof("[%s] handler: updating CAA records of '%s' to authorize %s", v.domain, v.name, identity)
+
+	if err := h.caaUpdater.UpsertCAA(v.name, values); err != nil {
+		return errors.Wrapf(err, "handler: unable to update CAA records of '%s'", v.name)
+	}
+
+	return nil
+}
+
+// findCAAViolation finds the relevant CAA record set of the given domain by climbing the DNS tree,
+// and returns it if it does not authorize any of the given CA identities, otherwise nil
+func findCAAViolation(domain string, identities []string) (*caaViolation, error) {
+	name := strings.ToLower(strings.TrimSuffix(domain, "."))
+	wildcard := strings.HasPrefix(name, "*.")
+	name = strings.TrimPrefix(name, "*.")
+
+	for len(name) > 0 {
+		records, err := lookupCAA(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(records) > 0 {
+			if authorizesCAA(records, wildcard, identities) {
+				return nil, nil
+			}
+
+			return &caaViolation{domain: domain, name: name, wildcard: wildcard, records: records}, nil
+		}
+
+		// Climb to the parent domain if there are no records
+		if i := strings.Index(name, "."); i >= 0 {
+			name = name[i+1:]
+		} else {
+			name = ""
+		}
+	}
+
+	// Any CA is authorized without CAA records
+	return nil, nil
+}
+
+// authorizesCAA checks if the given relevant CAA record set authorizes any of the given CA identities
+// to issue a certificate, issuewild properties are used for wildcard domains if there are any
+func authorizesCAA(records []*dns.CAA, wildcard bool, identities []string) bool {
+	tag := caaTagIssue
+	if wildcard && hasCAATag(records, caaTagIssueWild) {
+		tag = caaTagIssueWild
+	}
+
+	restricted := false
+	authorized := false
+	for _, record := range records {
+		recordTag := strings.ToLower(record.Tag)
+
+		switch {
+		case recordTag == tag:
+			restricted = true
+			if matchesCAAIdentity(record.Value, identities) {
+				authorized = true
+			}
+		case recordTag != caaTagIssue && recordTag != caaTagIssueWild && recordTag != caaTagIodef && record.Flag&caaFlagCritical != 0:
+			// Unknown critical properties forbid issuance
+			return false
+		}
+	}
+
+	return !restricted || authorized
+}
+
+// matchesCAAIdentity checks if the issuer of the given issue property value is one of the given CA identities
+func matchesCAAIdentity(value string, identities []string) bool {
+	issuer := value
+	if i := strings.Index(issuer, ";"); i >= 0 {
+		issuer = issuer[:i]
+	}
+	issuer = strings.TrimSpace(issuer)
+
+	for _, identity := range identities {
+		if len(issuer) > 0 && strings.EqualFold(issuer, identity) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasCAATag checks if any of the given records has the given property tag
+func hasCAATag(records []*dns.CAA, tag string) bool {
+	for _, record := range records {
+		if strings.EqualFold(record.Tag, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// caaValue returns the given record in the presentation format without the header
+func caaValue(record *dns.CAA) string {
+	return fmt.Sprintf("%d %s %q", record.Flag, record.Tag, record.Value)
+}
+
+// queryCAA queries CAA records of the given domain from the recursive nameservers.
+// Aliases are resolved by the nameservers, only the records of the target are returned then.
+func queryCAA(domain string) ([]*dns.CAA, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(domain), dns.TypeCAA)
+	msg.SetEdns0(4096, false)
+
+	var lastErr error
+	for _, ns := range caaNameservers() {
+		in, err := exchangeDNS(msg, ns)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if in.Rcode != dns.RcodeSuccess && in.Rcode != dns.RcodeNameError {
+			lastErr = errors.Errorf("DNS query of '%s' failed with %s", domain, dns.RcodeToString[in.Rcode])
+			continue
+		}
+
+		var records []*dns.CAA
+		for _, rr := range in.Answer {
+			if caa, ok := rr.(*dns.CAA); ok {
+				records = append(records, caa)
+			}
+		}
+
+		return records, nil
+	}
+
+	return nil, lastErr
+}
+
+// exchangeDNS sends the given message to the given nameserver, retrying over TCP if the response is truncated
+func exchangeDNS(msg *dns.Msg, ns string) (*dns.Msg, error) {
+	client := &dns.Client{Timeout: caaTimeout}
+
+	in, _, err := client.Exchange(msg, ns)
+	if err == nil && in.Truncated {
+		client.Net = "tcp"
+		in, _, err = client.Exchange(msg, ns)
+	}
+
+	return in, err
+}
+
+// caaNameservers returns the system resolvers, or the default nameservers if they are not configured
+func caaNameservers() []string {
+	conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil || len(conf.Servers) == 0 {
+		return defaultCAANameservers
+	}
+
+	servers := make([]string, len(conf.Servers))
+	for i, server := range conf.Servers {
+		servers[i] = net.JoinHostPort(server, conf.Port)
+	}
+
+	return servers
+}
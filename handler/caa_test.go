@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCAAViolation(t *testing.T) {
+	zone := map[string][]*dns.CAA{
+		"example.com": {
+			{Tag: "issue", Value: "letsencrypt.org"},
+			{Tag: "issuewild", Value: ";"},
+			{Tag: "iodef", Value: "mailto:security@example.com"},
+		},
+		"other.example.com": {
+			{Tag: "issue", Value: "digicert.com; cansignhttpexchanges=yes"},
+		},
+		"critical.example.com": {
+			{Tag: "issue", Value: "letsencrypt.org"},
+			{Flag: caaFlagCritical, Tag: "tbs", Value: "unknown"},
+		},
+		"example.org": {
+			{Tag: "iodef", Value: "mailto:security@example.org"},
+		},
+	}
+
+	defer func(original func(string) ([]*dns.CAA, error)) { lookupCAA = original }(lookupCAA)
+	lookupCAA = func(domain string) ([]*dns.CAA, error) {
+		if domain == "broken.example.net" {
+			return nil, errors.New("SERVFAIL")
+		}
+
+		return zone[domain], nil
+	}
+
+	testTable := []*struct {
+		testName          string
+		domain            string
+		expectedViolation string
+		expectedErr       bool
+	}{
+		{
+			testName: "no records up to the root",
+			domain:   "www.example.net",
+		},
+		{
+			testName: "records of the parent authorize CA",
+			domain:   "www.example.com",
+		},
+		{
+			testName:          "issuewild of the parent forbids wildcard",
+			domain:            "*.example.com",
+			expectedViolation: `CAA records of 'example.com' do not authorize the CA for '*.example.com': 0 issue "letsencrypt.org", 0 issuewild ";", 0 iodef "mailto:security@example.com"`,
+		},
+		{
+			testName:          "records of the domain take precedence over the parent",
+			domain:            "api.other.example.com",
+			expectedViolation: `CAA records of 'other.example.com' do not authorize the CA for 'api.other.example.com': 0 issue "digicert.com; cansignhttpexchanges=yes"`,
+		},
+		{
+			testName:          "unknown critical property",
+			domain:            "critical.example.com",
+			expectedViolation: `CAA records of 'critical.example.com' do not authorize the CA for 'critical.example.com': 0 issue "letsencrypt.org", 128 tbs "unknown"`,
+		},
+		{
+			testName: "records without issue properties",
+			domain:   "*.example.org",
+		},
+		{
+			testName:    "lookup failure",
+			domain:      "broken.example.net",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			v, err := findCAAViolation(tt.domain, []string{"LetsEncrypt.org"})
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			if len(tt.expectedViolation) == 0 {
+				require.Nil(t, v)
+				return
+			}
+
+			require.NotNil(t, v)
+			require.Equal(t, tt.expectedViolation, v.String())
+		})
+	}
+}
@@ -1,69 +1,128 @@
 package handler
 
 import (
+	"time"
+
 	"github.com/go-acme/lego/certcrypto"
 	"github.com/go-acme/lego/challenge"
+	"github.com/go-acme/lego/lego"
 	"github.com/go-acme/lego/registration"
 	"github.com/sirupsen/logrus"
 
 	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/deploy"
 	"github.com/begmaroman/acme-dns-route53/notifier"
 )
 
 // CertificateHandlerOptions is the options of certificate handler
 type CertificateHandlerOptions struct {
+	GroupName         string // The name of the certificate group passed to deploy hooks
 	Staging           bool
+	CADirURL          string // Overrides Staging if set
+	KeyType           certcrypto.KeyType
 	ConfigDir         string
 	NotificationTopic string
 	RenewBefore       int
+	DisableARI        bool
+	Policy            *Policy  // Certificates are not requested if they violate the policy
+	DisableCAA        bool     // Disables verification of CAA records before requesting certificates
+	CAAIdentities     []string // CAA identities of the CA, known or taken from the directory if empty
+	DryRun            bool     // Plans changes without requesting certificates, changing records and stores or notifying
+
+	// FailureInterval is the period within which a failure of the same stage is notified once, every failure if zero
+	FailureInterval time.Duration
 
-	Store    certstore.CertStore
-	Notifier notifier.Notifier
-	DNS01    challenge.Provider
+	Store         certstore.CertStore
+	Notifier      notifier.Notifier
+	Notifications []notifier.Target // Additional notification targets
+	DNS01         challenge.Provider
+	CAAUpdater    CAAUpdater      // Authorizes the CA by CAA records if they do not, nil to fail instead
+	Hooks         []deploy.Target // Deploy the certificate after it has been stored
 
 	Log *logrus.Logger
 }
 
 // CertificateHandler is the certificates handler
 type CertificateHandler struct {
-	isStaging         bool
-	configDir         string
-	notificationTopic string
-	renewBefore       int
-
-	store    certstore.CertStore
-	notifier notifier.Notifier
-	dns01    challenge.Provider
-	log      *logrus.Logger
+	groupName   string
+	caDirURL    string
+	keyType     certcrypto.KeyType
+	configDir   string
+	renewBefore int
+	disableARI  bool
+	policy      *Policy
+	disableCAA  bool
+	dryRun      bool
+
+	// caaIdentities are CAA identities of the CA, resolved by the CAA preflight if empty
+	caaIdentities []string
+
+	failureInterval time.Duration
+
+	store         certstore.CertStore
+	notifications []notifier.Target
+	dns01         challenge.Provider
+	caaUpdater    CAAUpdater
+	hooks         []deploy.Target
+	log           *logrus.Logger
 }
 
 // NewCertificateHandler is the constructor of CertificateHandler
 func NewCertificateHandler(opts *CertificateHandlerOptions) *CertificateHandler {
+	caDirURL := opts.CADirURL
+	if len(caDirURL) == 0 {
+		caDirURL = lego.LEDirectoryProduction
+		if opts.Staging {
+			caDirURL = lego.LEDirectoryStaging
+		}
+	}
+
+	keyType := opts.KeyType
+	if len(keyType) == 0 {
+		keyType = certcrypto.RSA2048
+	}
+
+	var notifications []notifier.Target
+	if len(opts.NotificationTopic) > 0 {
+		notifications = append(notifications, notifier.Target{Notifier: opts.Notifier, Topic: opts.NotificationTopic})
+	}
+
 	return &CertificateHandler{
-		isStaging:         opts.Staging,
-		store:             opts.Store,
-		notificationTopic: opts.NotificationTopic,
-		renewBefore:       opts.RenewBefore,
-		notifier:          opts.Notifier,
-		dns01:             opts.DNS01,
-		configDir:         opts.ConfigDir,
-		log:               opts.Log,
+		groupName:     opts.GroupName,
+		caDirURL:      caDirURL,
+		keyType:       keyType,
+		store:         opts.Store,
+		notifications: append(notifications, opts.Notifications...),
+		renewBefore:   opts.RenewBefore,
+		disableARI:    opts.DisableARI,
+		policy:        opts.Policy,
+		disableCAA:    opts.DisableCAA,
+		caaIdentities: opts.CAAIdentities,
+		dryRun:        opts.DryRun,
+
+		failureInterval: opts.FailureInterval,
+		caaUpdater:      opts.CAAUpdater,
+		hooks:           opts.Hooks,
+		dns01:           opts.DNS01,
+		configDir:       opts.ConfigDir,
+		log:             opts.Log,
 	}
 }
 
 // toConfigParams creates a new configParams model
 func (h *CertificateHandler) toConfigParams(user registration.User) *configParams {
 	return &configParams{
-		user:      user,
-		isStaging: h.isStaging,
-		keyType:   certcrypto.RSA2048, // TODO: Create a flag to define key type
+		user:     user,
+		caDirURL: h.caDirURL,
+		keyType:  h.keyType,
 	}
 }
 
 // toUserParams creates a new userParams model
 func (h *CertificateHandler) toUserParams(email string) *userParams {
 	return &userParams{
-		email:   email,
-		keyType: certcrypto.RSA2048, // TODO: Create a flag to define key type
+		email:     email,
+		configDir: h.configDir,
+		keyType:   certcrypto.RSA2048, // TODO: Create a flag to define key type
 	}
 }
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"github.com/go-acme/lego/lego"
+	"github.com/pkg/errors"
+)
+
+// acmeClient is the ACME client on behalf of the registered user
+type acmeClient struct {
+	*lego.Client
+
+	user   *CertUser
+	config *lego.Config
+}
+
+// newACMEClient creates a new ACME client and registers the user with the given email.
+// If the given renewal replaces an existing certificate, new orders refer to the replaced certificate.
+func (h *CertificateHandler) newACMEClient(email string, r *renewal) (*acmeClient, error) {
+	// Load user
+	certUser, err := getUser(h.toUserParams(email))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load user")
+	}
+
+	// Create config
+	config, err := getConfig(h.toConfigParams(certUser))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create config")
+	}
+
+	if r != nil && len(r.replaces) > 0 {
+		config.HTTPClient.Transport = &replacesTransport{
+			RoundTripper: config.HTTPClient.Transport,
+			newOrderURL:  r.newOrderURL,
+			replaces:     r.replaces,
+			key:          certUser.key,
+		}
+	}
+
+	// Create a client facilitates communication with the CA server.
+	client, err := lego.NewClient(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create lego client")
+	}
+
+	// Use DNS-01 challenge to verify that the given domain belongs to the current server
+	if err = client.Challenge.SetDNS01Provider(h.dns01); err != nil {
+		return nil, errors.Wrap(err, "failed to set DNS-01 provider")
+	}
+
+	// New users will need to register, existing ones get their account back
+	if certUser.Registration, err = client.Registration.Register(registerOptions); err != nil {
+		return nil, errors.Wrap(err, "could not register Let's Encrypt account")
+	}
+
+	return &acmeClient{
+		Client: client,
+		user:   certUser,
+		config: config,
+	}, nil
+}
@@ -10,9 +10,9 @@ import (
 
 // configParams is the parameters which are needed for config creation
 type configParams struct {
-	isStaging bool
-	keyType   certcrypto.KeyType
-	user      registration.User
+	caDirURL string
+	keyType  certcrypto.KeyType
+	user     registration.User
 }
 
 // getConfig creates a config for the lego client
@@ -21,29 +21,37 @@ func getConfig(params *configParams) (*lego.Config, error) {
 	config := lego.NewConfig(params.user)
 
 	// This CA URL is configured for a local dev instance of Boulder running in Docker in a VM.
-	if params.isStaging {
+	if params.caDirURL == lego.LEDirectoryStaging {
 		log.Infof("acme: Using staging environment")
-		config.CADirURL = lego.LEDirectoryStaging
 	}
+	config.CADirURL = params.caDirURL
 	config.Certificate.KeyType = params.keyType
 
 	return config, nil
 }
 
-// configParams is the parameters which are needed for user loading
+// userParams is the parameters which are needed for user loading
 type userParams struct {
-	email   string
-	keyType certcrypto.KeyType
+	email     string
+	configDir string
+	keyType   certcrypto.KeyType
 }
 
-// getUser create a new getUser object
-// TODO: Load user based on a data in config directory
+// getUser loads the user from the config directory or creates a new one
 func getUser(params *userParams) (*CertUser, error) {
-	var err error
-
 	// Create a user
 	certUser := NewCertUser(params.email)
 
+	// Reuse the account's private key if it has been already stored
+	loaded, err := certUser.LoadPrivateKey(params.configDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load private key")
+	}
+
+	if loaded {
+		return certUser, nil
+	}
+
 	// New accounts need a private key to start
 	if certUser.key, err = certcrypto.GeneratePrivateKey(params.keyType); err != nil {
 		return nil, errors.Wrap(err, "unable to generate private key")
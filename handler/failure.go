@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+const (
+	// failuresFileName is the name of the file in the config directory with times of failure notifications
+	failuresFileName = "failure-notifications.json"
+)
+
+// Stage is the stage of processing a certificate
+type Stage string
+
+const (
+	// StagePolicy is the check of the certificate against the policy
+	StagePolicy Stage = "policy"
+
+	// StageCAA is the verification of CAA records
+	StageCAA Stage = "caa"
+
+	// StageRegistration is the registration or resolution of the ACME account
+	StageRegistration Stage = "registration"
+
+	// StageChallenge is the order of the certificate including DNS-01 challenges
+	StageChallenge Stage = "challenge"
+
+	// StageStore is loading or storing the certificate
+	StageStore Stage = "store"
+
+	// StageDeploy is running deploy hooks after the certificate has been stored
+	StageDeploy Stage = "deploy"
+)
+
+var (
+	// failuresLock serializes access to files with times of failure notifications
+	failuresLock sync.Mutex
+)
+
+// StageError is the error of the stage of processing a certificate
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+// Error implements error interface
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+// Cause returns the underlying error, see github.com/pkg/errors
+func (e *StageError) Cause() error {
+	return e.Err
+}
+
+// withStage returns *StageError of the given stage with the given error, nil if the error is nil
+func withStage(stage Stage, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &StageError{Stage: stage, Err: err}
+}
+
+// ErrorStage returns the stage of the given error, or empty string if the error is not a stage error
+func ErrorStage(err error) Stage {
+	for err != nil {
+		if stageErr, ok := err.(*StageError); ok {
+			return stageErr.Stage
+		}
+
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+
+	return ""
+}
+
+// notifyFailure notifies that processing of the certificate of the given domains failed with the given error.
+// The failure of the same stage is notified once within the failure notification interval.
+func (h *CertificateHandler) notifyFailure(domains []string, err error) {
+	if h.dryRun {
+		return
+	}
+
+	domainsStr := strings.Join(domains, domainsJoinChar)
+	stage := ErrorStage(err)
+
+	if h.failureInterval > 0 {
+		notify, stateErr := h.recordFailure(domainsStr, stage, time.Now())
+		if stateErr != nil {
+			h.log.Warnf("[%s] handler: unable to de-duplicate failure notifications: %s", domainsStr, stateErr)
+		} else if !notify {
+			h.log.Infof("[%s] handler: failure of stage '%s' has already been notified within %s", domainsStr, stage, h.failureInterval)
+			return
+		}
+	}
+
+	event := notifier.NewEvent(notifier.EventRenewalFailed, domains)
+	event.Stage = string(stage)
+	event.Error = err.Error()
+
+	h.notify(event)
+}
+
+// resetFailures forgets notified failures of the certificate of the given domains, so the next failure is notified at once
+func (h *CertificateHandler) resetFailures(domains []string) {
+	if h.dryRun || h.failureInterval == 0 {
+		return
+	}
+
+	domainsStr := strings.Join(domains, domainsJoinChar)
+	if err := h.updateFailures(func(failures map[string]time.Time) bool {
+		changed := false
+		for key := range failures {
+			if strings.HasPrefix(key, domainsStr+"|") {
+				delete(failures, key)
+				changed = true
+			}
+		}
+
+		return changed
+	}); err != nil {
+		h.log.Warnf("[%s] handler: unable to reset failure notifications: %s", domainsStr, err)
+	}
+}
+
+// recordFailure records the failure of the given stage at the given time,
+// and returns whether it must be notified since it has not been notified within the interval
+func (h *CertificateHandler) recordFailure(domainsStr string, stage Stage, now time.Time) (bool, error) {
+	key := domainsStr + "|" + string(stage)
+
+	notify := false
+	err := h.updateFailures(func(failures map[string]time.Time) bool {
+		if last, ok := failures[key]; ok && now.Sub(last) < h.failureInterval {
+			return false
+		}
+
+		failures[key] = now
+		notify = true
+
+		return true
+	})
+
+	return notify, err
+}
+
+// updateFailures loads times of failure notifications, updates them by the given function,
+// and stores them if the function reports a change
+func (h *CertificateHandler) updateFailures(update func(failures map[string]time.Time) bool) error {
+	failuresLock.Lock()
+	defer failuresLock.Unlock()
+
+	path := filepath.Join(h.configDir, failuresFileName)
+
+	failures := make(map[string]time.Time)
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &failures); err != nil {
+			return errors.Wrapf(err, "invalid file '%s'", path)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	if !update(failures) {
+		return nil
+	}
+
+	if data, err = json.Marshal(failures); err != nil {
+		return err
+	}
+
+	if len(h.configDir) > 0 {
+		if err := os.MkdirAll(h.configDir, 0700); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
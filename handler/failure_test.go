@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/certificate"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+// failingStore is the CertStore which fails to load certificates
+type failingStore struct{}
+
+func (failingStore) Store(*certificate.Resource, []string) ([]string, error) { return nil, nil }
+func (failingStore) Delete([]string) error                                   { return nil }
+func (failingStore) List() ([]*certstore.CertificateDetails, error)          { return nil, nil }
+
+func (failingStore) Load([]string) (*certstore.CertificateDetails, error) {
+	return nil, errors.New("AccessDeniedException")
+}
+
+// recordingNotifier is the Notifier which records events and fails to send them
+type recordingNotifier struct {
+	events []*notifier.Event
+}
+
+func (n *recordingNotifier) Notify(topic string, event *notifier.Event) error {
+	n.events = append(n.events, event)
+	return errors.New("notifier is down")
+}
+
+func TestErrorStage(t *testing.T) {
+	err := errors.Wrap(withStage(StageChallenge, errors.New("acme: error: 429")), "handler: unable to replace revoked certificate")
+
+	require.Equal(t, StageChallenge, ErrorStage(err))
+	require.Equal(t, "handler: unable to replace revoked certificate: acme: error: 429", err.Error())
+	require.Equal(t, Stage(""), ErrorStage(errors.New("unknown")))
+	require.Nil(t, withStage(StageStore, nil))
+}
+
+func TestNotifyFailure(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "handler")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+
+	n := &recordingNotifier{}
+	h := NewCertificateHandler(&CertificateHandlerOptions{
+		ConfigDir:       configDir,
+		FailureInterval: time.Hour,
+		Log:             logrus.New(),
+		Store:           failingStore{},
+		Notifications:   []notifier.Target{{Notifier: n, Topic: "certificates"}},
+	})
+
+	domains := []string{"example.com"}
+
+	// Failures of notifiers do not change the result
+	_, err = h.Obtain(domains, "admin@example.com")
+	require.EqualError(t, err, "handler: unable to load existing certificate: AccessDeniedException")
+	require.Equal(t, StageStore, ErrorStage(err))
+
+	require.Len(t, n.events, 1)
+	require.Equal(t, notifier.EventRenewalFailed, n.events[0].Type)
+	require.Equal(t, "store", n.events[0].Stage)
+	require.Equal(t, "handler: unable to load existing certificate: AccessDeniedException", n.events[0].Error)
+	require.Equal(t, domains, n.events[0].Domains)
+
+	// The same failure is notified once within the interval
+	_, err = h.Renew(domains, "admin@example.com", false)
+	require.Error(t, err)
+	require.Len(t, n.events, 1)
+
+	// Failures of other certificates are notified
+	_, err = h.Obtain([]string{"example.org"}, "admin@example.com")
+	require.Error(t, err)
+	require.Len(t, n.events, 2)
+
+	// The next failure is notified at once after success
+	h.resetFailures(domains)
+	_, err = h.Obtain(domains, "admin@example.com")
+	require.Error(t, err)
+	require.Len(t, n.events, 3)
+
+	// The failure is notified again after the interval
+	notify, err := h.recordFailure("example.com", StageStore, time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	require.True(t, notify)
+}
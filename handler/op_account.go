@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"github.com/go-acme/lego/lego"
+	"github.com/pkg/errors"
+)
+
+// AccountStatus returns the status of the CA account of the given email, e.g. "valid" or "deactivated".
+// Returns empty status if the account key is not stored in the config directory yet, the account is registered on the first order then.
+func (h *CertificateHandler) AccountStatus(email string) (string, error) {
+	certUser := NewCertUser(email)
+
+	loaded, err := certUser.LoadPrivateKey(h.configDir)
+	if err != nil {
+		return "", errors.Wrap(err, "handler: unable to load user")
+	}
+
+	if !loaded {
+		return "", nil
+	}
+
+	config, err := getConfig(h.toConfigParams(certUser))
+	if err != nil {
+		return "", errors.Wrap(err, "handler: unable to create config")
+	}
+
+	client, err := lego.NewClient(config)
+	if err != nil {
+		return "", errors.Wrap(err, "handler: unable to create lego client")
+	}
+
+	account, err := client.Registration.ResolveAccountByKey()
+	if err != nil {
+		return "", errors.Wrap(err, "handler: unable to resolve account by key")
+	}
+
+	return account.Body.Status, nil
+}
+
+// CADirURL returns the directory URL of the CA of the handler
+func (h *CertificateHandler) CADirURL() string {
+	return h.caDirURL
+}
@@ -1,14 +1,17 @@
 package handler
 
 import (
-	"fmt"
 	"strings"
 	"time"
 
+	"github.com/go-acme/lego/certcrypto"
 	"github.com/go-acme/lego/certificate"
-	"github.com/go-acme/lego/lego"
 	"github.com/go-acme/lego/registration"
 	"github.com/pkg/errors"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/deploy"
+	"github.com/begmaroman/acme-dns-route53/notifier"
 )
 
 const (
@@ -22,48 +25,139 @@ var (
 )
 
 // Obtain creates a new SSL certificate or renews existing one for the given domains with the given email
-func (h *CertificateHandler) Obtain(domains []string, email string) error {
+func (h *CertificateHandler) Obtain(domains []string, email string) (*Result, error) {
 	domainsStr := strings.Join(domains, domainsJoinChar)
 
+	// Check the policy before any request to the store or the CA
+	if err := h.enforcePolicy(domains); err != nil {
+		return rejected(err)
+	}
+
 	// Check if there is existing an certificate for the given domains
 	existingCert, err := h.store.Load(domains)
 	if err != nil {
-		return errors.Wrap(err, "handler: unable to load existing certificate")
+		err = withStage(StageStore, errors.Wrap(err, "handler: unable to load existing certificate"))
+		h.notifyFailure(domains, err)
+		return newResult(nil), err
+	}
+
+	result := newResult(existingCert)
+	if existingCert == nil {
+		return result.finish(OutcomeIssued, h.obtain(domains, email, nil, result))
 	}
 
-	if existingCert != nil {
-		if sub := existingCert.NotAfter.Sub(time.Now()).Hours(); int(sub) > h.renewBefore {
-			h.log.Infof("[%s] handler: left %d days to certificate will be expired", domainsStr, time.Duration(sub/24))
-			return nil
+	r, renew := h.checkRenewal(domainsStr, existingCert)
+	if !renew {
+		return h.skipped(domains, result)
+	}
+
+	return result.finish(OutcomeRenewed, h.obtain(domains, email, r, result))
+}
+
+// checkRenewal checks if the given certificate must be renewed.
+// The renewal window suggested by the CA is used if it supports ACME Renewal Information,
+// otherwise the certificate is renewed within the renewal period before expiration.
+// Dry runs do not contact the CA, so they use the renewal period.
+func (h *CertificateHandler) checkRenewal(domainsStr string, cert *certstore.CertificateDetails) (*renewal, bool) {
+	if h.dryRun && !h.disableARI {
+		h.log.Infof("[%s] handler: dry run, ACME Renewal Information is not requested, using renewal period", domainsStr)
+	}
+
+	if !h.disableARI && !h.dryRun {
+		r, renew, err := h.checkRenewalInfo(domainsStr, cert)
+		if err == nil {
+			return r, renew
 		}
+
+		h.log.Warnf("[%s] handler: unable to use ACME Renewal Information, falling back to renewal period: %s", domainsStr, err)
 	}
 
-	// Load user
-	certUser, err := getUser(h.toUserParams(email))
+	return nil, h.needsRenewal(domainsStr, cert)
+}
+
+// checkRenewalInfo checks if the given certificate must be renewed according to ACME Renewal Information
+func (h *CertificateHandler) checkRenewalInfo(domainsStr string, cert *certstore.CertificateDetails) (*renewal, bool, error) {
+	x509Cert, err := certcrypto.ParsePEMCertificate(cert.Certificate)
 	if err != nil {
-		return errors.Wrap(err, "handler: unable to load user")
+		return nil, false, errors.Wrap(err, "unable to parse existing certificate")
 	}
 
-	// Create config
-	config, err := getConfig(h.toConfigParams(certUser))
+	certID, err := ariCertificateID(x509Cert)
 	if err != nil {
-		return errors.Wrap(err, "handler: unable to create config")
+		return nil, false, err
 	}
 
-	// Create a client facilitates communication with the CA server.
-	client, err := lego.NewClient(config)
+	info, dir, err := getRenewalInfo(h.caDirURL, certID)
 	if err != nil {
-		return errors.Wrap(err, "handler: unable to create lego client")
+		return nil, false, err
 	}
 
-	// Use DNS-01 challenge to verify that the given domain belongs to the current server
-	if err = client.Challenge.SetDNS01Provider(h.dns01); err != nil {
-		return errors.Wrap(err, "handler: failed to set DNS-01 provider")
+	if len(info.ExplanationURL) > 0 {
+		h.log.Infof("[%s] handler: CA provided explanation of the suggested renewal window: %s", domainsStr, info.ExplanationURL)
 	}
 
-	// New users will need to register
-	if certUser.Registration, err = client.Registration.Register(registerOptions); err != nil {
-		return errors.Wrap(err, "handler: could not register Let's Encrypt account")
+	renewAt := info.selectTime(certID)
+	if time.Now().Before(renewAt) {
+		h.log.Infof("[%s] handler: certificate will be renewed at %s within the suggested window from %s to %s", domainsStr,
+			renewAt.Format(time.RFC3339), info.SuggestedWindow.Start.Format(time.RFC3339), info.SuggestedWindow.End.Format(time.RFC3339))
+		return nil, false, nil
+	}
+
+	return &renewal{
+		replaces:       certID,
+		newOrderURL:    dir.NewOrderURL,
+		explanationURL: info.ExplanationURL,
+	}, true, nil
+}
+
+// needsRenewal checks if the given certificate expires within the renewal period
+func (h *CertificateHandler) needsRenewal(domainsStr string, cert *certstore.CertificateDetails) bool {
+	if sub := cert.NotAfter.Sub(time.Now()).Hours(); int(sub) > h.renewBefore {
+		h.log.Infof("[%s] handler: left %d days to certificate will be expired", domainsStr, time.Duration(sub/24))
+		return false
+	}
+
+	return true
+}
+
+// obtain verifies CAA records of the given domains, requests a new SSL certificate for them from the CA and stores it.
+// The given renewal is nil if the certificate is not renewed according to ACME Renewal Information.
+// Details of the new certificate are set to the given result. The failure is notified.
+func (h *CertificateHandler) obtain(domains []string, email string, r *renewal, result *Result) error {
+	var plan *Plan
+	if h.dryRun {
+		plan = &Plan{}
+	}
+
+	// Fail before any change of DNS records if the CA is not authorized to issue the certificate
+	if err := h.checkCAA(domains, plan); err != nil {
+		err = withStage(StageCAA, err)
+		h.notifyFailure(domains, err)
+		return err
+	}
+
+	if h.dryRun {
+		return h.plan(domains, plan, result)
+	}
+
+	if err := h.order(domains, email, r, result); err != nil {
+		h.notifyFailure(domains, err)
+		return err
+	}
+
+	h.resetFailures(domains)
+
+	return nil
+}
+
+// order requests a new SSL certificate for the given domains from the CA and stores it
+func (h *CertificateHandler) order(domains []string, email string, r *renewal, result *Result) error {
+	domainsStr := strings.Join(domains, domainsJoinChar)
+
+	// Create a client registered with the given email
+	client, err := h.newACMEClient(email, r)
+	if err != nil {
+		return withStage(StageRegistration, errors.Wrap(err, "handler: unable to create ACME client"))
 	}
 
 	// Create a new request to obtain certificate
@@ -74,24 +168,45 @@ func (h *CertificateHandler) Obtain(domains []string, email string) error {
 		MustStaple: false,
 	})
 	if err != nil {
-		return errors.Wrap(err, "handler: unable to obtain certificate")
+		// The CA may refuse to replace the certificate, e.g. if it was issued to another account or replaced already
+		if replacesRejected(r, err) {
+			h.log.Warnf("[%s] handler: unable to obtain certificate replacing '%s', retrying as a new order: %s", domainsStr, r.replaces, err)
+			return h.order(domains, email, &renewal{explanationURL: r.explanationURL}, result)
+		}
+
+		return withStage(StageChallenge, errors.Wrap(err, "handler: unable to obtain certificate"))
 	}
 
 	// Store the obtained certificate
-	if err := h.store.Store(crt, domains); err != nil {
-		return errors.Wrap(err, "handler: unable to store certificates")
+	ids, err := h.store.Store(crt, domains)
+	if err != nil {
+		return withStage(StageStore, errors.Wrap(err, "handler: unable to store certificates"))
+	}
+
+	if err := result.setCertificate(crt.Certificate, ids); err != nil {
+		h.log.Warnf("[%s] handler: unable to read details of the obtained certificate: %s", domainsStr, err)
 	}
 
 	// Notify that the certificate has been obtained for the given domains
-	if len(h.notificationTopic) > 0 {
-		if err := h.notifier.Notify(h.notificationTopic, h.buildPublishMessage(domainsStr)); err != nil {
-			return errors.Wrap(err, "handler: failed to publish notification")
-		}
+	eventType := notifier.EventCertificateRenewed
+	if result.OldNotAfter.IsZero() {
+		eventType = notifier.EventCertificateIssued
+	}
+
+	event := notifier.NewEvent(eventType, domains).SetExpiry(result.NewNotAfter)
+	event.CertificateARNs = result.CertificateIDs
+	event.Serial = result.Serial
+	if r != nil {
+		event.ExplanationURL = r.explanationURL
 	}
 
+	h.notify(event)
+
+	h.deploy(crt, event, result)
+
 	// Store user's private key into config file by the config path
-	if err := certUser.StorePrivateKey(h.configDir); err != nil {
-		return errors.Wrap(err, "handler: unable to store user's private key")
+	if err := client.user.StorePrivateKey(h.configDir); err != nil {
+		return withStage(StageRegistration, errors.Wrap(err, "handler: unable to store user's private key"))
 	}
 
 	h.log.Infof("[%s] handler: certificate successfully obtained and stored", domainsStr)
@@ -99,7 +214,59 @@ func (h *CertificateHandler) Obtain(domains []string, email string) error {
 	return nil
 }
 
-// buildPublishMessage builsd a message to publish by the given params
-func (h *CertificateHandler) buildPublishMessage(domains string) string {
-	return fmt.Sprintf("Certificates for the following domains successfully obtained: %s", domains)
+// deploy runs deploy hooks of the given certificate stored by the given event, and sets their results to the given result.
+// Failures of hooks are notified, they do not fail processing of the certificate since it has been stored.
+func (h *CertificateHandler) deploy(crt *certificate.Resource, event *notifier.Event, result *Result) {
+	if len(h.hooks) == 0 {
+		return
+	}
+
+	cert := &deploy.Certificate{
+		Group:             h.groupName,
+		Event:             string(event.Type),
+		Domains:           event.Domains,
+		CertificateIDs:    result.CertificateIDs,
+		Serial:            result.Serial,
+		NotAfter:          result.NewNotAfter,
+		Certificate:       crt.Certificate,
+		IssuerCertificate: crt.IssuerCertificate,
+		PrivateKey:        crt.PrivateKey,
+	}
+
+	var err error
+	if result.Deployments, err = deploy.Run(h.hooks, cert, h.log); err != nil {
+		h.notifyFailure(event.Domains, withStage(StageDeploy, err))
+	}
+}
+
+// notify publishes the given event to all configured notification targets which accept it,
+// nothing is published by dry runs. Failures of notifiers are logged, they do not fail processing of the certificate.
+func (h *CertificateHandler) notify(event *notifier.Event) {
+	if h.dryRun {
+		return
+	}
+
+	event.CA = h.caDirURL
+
+	for _, target := range h.notifications {
+		if !target.Accepts(event.Type) {
+			continue
+		}
+
+		if err := target.Notifier.Notify(target.Topic, event); err != nil {
+			h.log.Errorf("[%s] handler: failed to publish notification: %s", strings.Join(event.Domains, domainsJoinChar), err)
+		}
+	}
+}
+
+// skipped notifies that the certificate of the given domains does not need renewal,
+// and returns the given result with the skipped outcome
+func (h *CertificateHandler) skipped(domains []string, result *Result) (*Result, error) {
+	event := notifier.NewEvent(notifier.EventRenewalSkipped, domains).SetExpiry(result.OldNotAfter)
+	event.CertificateARNs = result.CertificateIDs
+	event.Serial = result.Serial
+
+	h.notify(event)
+
+	return result.finish(OutcomeSkipped, nil)
 }
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+)
+
+// Renew renews the existing SSL certificate for the given domains with the given email.
+// Unlike Obtain, it never issues a certificate which is not in the store yet.
+// If force is true, the certificate is renewed regardless of its expiration date.
+func (h *CertificateHandler) Renew(domains []string, email string, force bool) (*Result, error) {
+	domainsStr := strings.Join(domains, domainsJoinChar)
+
+	// Check the policy before any request to the store or the CA
+	if err := h.enforcePolicy(domains); err != nil {
+		return rejected(err)
+	}
+
+	// Load the certificate to renew
+	existingCert, err := h.store.Load(domains)
+	if err != nil {
+		err = withStage(StageStore, errors.Wrap(err, "handler: unable to load existing certificate"))
+		h.notifyFailure(domains, err)
+		return newResult(nil), err
+	}
+
+	if existingCert == nil {
+		return newResult(nil), ErrCertificateNotFound
+	}
+
+	result := newResult(existingCert)
+
+	if force {
+		h.log.Infof("[%s] handler: forcing renewal of certificate with ID '%s'", domainsStr, existingCert.ID)
+		return result.finish(OutcomeRenewed, h.obtain(domains, email, nil, result))
+	}
+
+	r, renew := h.checkRenewal(domainsStr, existingCert)
+	if !renew {
+		return h.skipped(domains, result)
+	}
+
+	return result.finish(OutcomeRenewed, h.obtain(domains, email, r, result))
+}
+
+// ManagedDomains returns domains lists of all certificates in the store which are managed by this tool
+func (h *CertificateHandler) ManagedDomains() ([][]string, error) {
+	list, err := h.store.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "handler: unable to list certificates")
+	}
+
+	domainsList := make([][]string, 0, len(list))
+	for _, cert := range list {
+		domainsList = append(domainsList, cert.Domains)
+	}
+
+	return domainsList, nil
+}
+
+// Existing returns details of the certificate of the given domains in the store.
+// Returns nil if there is no certificate for the given domains.
+func (h *CertificateHandler) Existing(domains []string) (*certstore.CertificateDetails, error) {
+	existingCert, err := h.store.Load(domains)
+	if err != nil {
+		return nil, errors.Wrap(err, "handler: unable to load existing certificate")
+	}
+
+	return existingCert, nil
+}
+
+// NeedsRenewal loads the certificate of the given domains from the store and checks if it must be renewed
+// the same way as Obtain does, by ACME Renewal Information or within the renewal period.
+// Returns nil and true if there is no certificate for the given domains, so it must be issued.
+func (h *CertificateHandler) NeedsRenewal(domains []string) (*certstore.CertificateDetails, bool, error) {
+	existingCert, err := h.Existing(domains)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existingCert == nil {
+		return nil, true, nil
+	}
+
+	_, renew := h.checkRenewal(strings.Join(domains, domainsJoinChar), existingCert)
+	return existingCert, renew, nil
+}
+
+// NotAfter returns the expiration time of the certificate of the given domains in the store.
+// Returns zero time if there is no certificate for the given domains.
+func (h *CertificateHandler) NotAfter(domains []string) (time.Time, error) {
+	existingCert, err := h.Existing(domains)
+	if err != nil || existingCert == nil {
+		return time.Time{}, err
+	}
+
+	return existingCert.NotAfter, nil
+}
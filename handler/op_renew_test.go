@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/certificate"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/utils/strsl"
+)
+
+// renewStore is the CertStore which contains the given certificates and fails on any change
+type renewStore struct {
+	certs []*certstore.CertificateDetails
+}
+
+func (s *renewStore) Store(*certificate.Resource, []string) ([]string, error) {
+	panic("certificate must not be stored")
+}
+
+func (s *renewStore) Delete([]string) error {
+	panic("certificate must not be deleted")
+}
+
+func (s *renewStore) List() ([]*certstore.CertificateDetails, error) { return s.certs, nil }
+
+func (s *renewStore) Load(domains []string) (*certstore.CertificateDetails, error) {
+	for _, cert := range s.certs {
+		if strsl.Equal(cert.Domains, domains) {
+			return cert, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func TestRenew(t *testing.T) {
+	// The CA fails every request, so the order fails as soon as it is started
+	var requests int32
+	ca := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ca.Close()
+
+	configDir, err := ioutil.TempDir("", "handler")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+
+	store := &renewStore{certs: []*certstore.CertificateDetails{
+		{ID: "valid", Domains: []string{"example.com", "www.example.com"}, NotAfter: time.Now().Add(60 * 24 * time.Hour)},
+	}}
+
+	testTable := []*struct {
+		testName         string
+		domains          []string
+		force            bool
+		expectedOutcome  Outcome
+		expectedErr      error
+		expectedStage    Stage
+		expectedRequests bool
+	}{
+		{
+			testName:    "missing certificate",
+			domains:     []string{"example.org"},
+			expectedErr: ErrCertificateNotFound,
+		},
+		{
+			testName:        "valid certificate",
+			domains:         []string{"example.com", "www.example.com"},
+			expectedOutcome: OutcomeSkipped,
+		},
+		{
+			testName:         "forced renewal",
+			domains:          []string{"example.com", "www.example.com"},
+			force:            true,
+			expectedOutcome:  OutcomeFailed,
+			expectedStage:    StageRegistration,
+			expectedRequests: true,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			h := NewCertificateHandler(&CertificateHandlerOptions{
+				CADirURL:    ca.URL + "/directory",
+				ConfigDir:   configDir,
+				RenewBefore: 30 * 24,
+				DisableARI:  true,
+				DisableCAA:  true,
+				Log:         logrus.New(),
+				Store:       store,
+			})
+
+			result, err := h.Renew(tt.domains, "admin@example.com", tt.force)
+			switch {
+			case tt.expectedErr != nil:
+				require.Equal(t, tt.expectedErr, err)
+			case len(tt.expectedStage) > 0:
+				require.Error(t, err)
+				require.Equal(t, tt.expectedStage, ErrorStage(err))
+			default:
+				require.NoError(t, err)
+			}
+
+			if len(tt.expectedOutcome) > 0 {
+				require.Equal(t, tt.expectedOutcome, result.Outcome)
+			}
+
+			// The order is started only when the certificate is renewed
+			require.Equal(t, tt.expectedRequests, atomic.LoadInt32(&requests) > 0)
+		})
+	}
+}
+
+func TestManagedDomains(t *testing.T) {
+	h := NewCertificateHandler(&CertificateHandlerOptions{
+		Log: logrus.New(),
+		Store: &renewStore{certs: []*certstore.CertificateDetails{
+			{ID: "first", Domains: []string{"example.com", "www.example.com"}},
+			{ID: "second", Domains: []string{"*.example.org"}},
+		}},
+	})
+
+	domains, err := h.ManagedDomains()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"example.com", "www.example.com"}, {"*.example.org"}}, domains)
+
+	h = NewCertificateHandler(&CertificateHandlerOptions{Log: logrus.New(), Store: &renewStore{}})
+
+	domains, err = h.ManagedDomains()
+	require.NoError(t, err)
+	require.Empty(t, domains)
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/go-acme/lego/acme"
+	"github.com/go-acme/lego/acme/api"
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/pkg/errors"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+// RevocationReason is the revocation reason code defined in RFC 5280, section 5.3.1
+type RevocationReason uint
+
+// Revocation reasons defined in RFC 5280, section 5.3.1
+const (
+	ReasonUnspecified          RevocationReason = 0
+	ReasonKeyCompromise        RevocationReason = 1
+	ReasonCACompromise         RevocationReason = 2
+	ReasonAffiliationChanged   RevocationReason = 3
+	ReasonSuperseded           RevocationReason = 4
+	ReasonCessationOfOperation RevocationReason = 5
+	ReasonCertificateHold      RevocationReason = 6
+	ReasonRemoveFromCRL        RevocationReason = 8
+	ReasonPrivilegeWithdrawn   RevocationReason = 9
+	ReasonAACompromise         RevocationReason = 10
+)
+
+var (
+	// ErrCertificateNotFound is the error when there is no certificate for the given domains in the store
+	ErrCertificateNotFound = errors.New("certificate not found")
+
+	// ErrRevokeDeleteAndReplace is the error when the revoked certificate is requested to be deleted and replaced at once
+	ErrRevokeDeleteAndReplace = errors.New("revoked certificate can be either deleted or replaced")
+
+	// revocationReasonNames contains names of revocation reasons as they are defined in RFC 5280
+	revocationReasonNames = map[RevocationReason]string{
+		ReasonUnspecified:          "unspecified",
+		ReasonKeyCompromise:        "keyCompromise",
+		ReasonCACompromise:         "cACompromise",
+		ReasonAffiliationChanged:   "affiliationChanged",
+		ReasonSuperseded:           "superseded",
+		ReasonCessationOfOperation: "cessationOfOperation",
+		ReasonCertificateHold:      "certificateHold",
+		ReasonRemoveFromCRL:        "removeFromCRL",
+		ReasonPrivilegeWithdrawn:   "privilegeWithdrawn",
+		ReasonAACompromise:         "aACompromise",
+	}
+
+	// subscriberReasons are revocation reasons which subscribers may request, the CA rejects the rest (RFC 8555, section 7.6)
+	subscriberReasons = []RevocationReason{
+		ReasonUnspecified,
+		ReasonKeyCompromise,
+		ReasonAffiliationChanged,
+		ReasonSuperseded,
+		ReasonCessationOfOperation,
+	}
+)
+
+// String returns the RFC 5280 name of the revocation reason
+func (r RevocationReason) String() string {
+	if name, ok := revocationReasonNames[r]; ok {
+		return name
+	}
+
+	return strconv.Itoa(int(r))
+}
+
+// ParseRevocationReason parses the revocation reason by its RFC 5280 name (case insensitive) or its code.
+// Only reasons which subscribers may request are accepted, the CA would reject the others.
+func ParseRevocationReason(val string) (RevocationReason, error) {
+	if len(val) == 0 {
+		return ReasonUnspecified, nil
+	}
+
+	names := make([]string, 0, len(subscriberReasons))
+	for _, reason := range subscriberReasons {
+		if strings.EqualFold(reason.String(), val) || val == strconv.Itoa(int(reason)) {
+			return reason, nil
+		}
+
+		names = append(names, reason.String())
+	}
+
+	for reason, name := range revocationReasonNames {
+		if strings.EqualFold(name, val) || val == strconv.Itoa(int(reason)) {
+			return ReasonUnspecified, errors.Errorf("revocation reason '%s' cannot be requested by subscribers, expected one of %s", val, strings.Join(names, ", "))
+		}
+	}
+
+	return ReasonUnspecified, errors.Errorf("unknown revocation reason '%s', expected one of %s", val, strings.Join(names, ", "))
+}
+
+// RevokeOptions is the options of certificate revocation
+type RevokeOptions struct {
+	// Reason is the revocation reason sent to the CA
+	Reason RevocationReason
+
+	// Delete removes the revoked certificate from the store
+	Delete bool
+
+	// Replace obtains a new certificate instead of the revoked one
+	Replace bool
+}
+
+// Revoke revokes the certificate of the given domains using the account with the given email.
+// The revoked certificate is kept in the store unless it is requested to be deleted or replaced.
+func (h *CertificateHandler) Revoke(domains []string, email string, opts *RevokeOptions) (*Result, error) {
+	domainsStr := strings.Join(domains, domainsJoinChar)
+
+	if opts.Delete && opts.Replace {
+		return newResult(nil), ErrRevokeDeleteAndReplace
+	}
+
+	// Load the certificate to revoke
+	existingCert, err := h.store.Load(domains)
+	if err != nil {
+		return newResult(nil), errors.Wrap(err, "handler: unable to load existing certificate")
+	}
+
+	if existingCert == nil {
+		return newResult(nil), ErrCertificateNotFound
+	}
+
+	result := newResult(existingCert)
+
+	x509Cert, err := certcrypto.ParsePEMCertificate(existingCert.Certificate)
+	if err != nil {
+		return result, errors.Wrap(err, "handler: unable to parse existing certificate")
+	}
+
+	// Create a client registered with the given email
+	client, err := h.newACMEClient(email, nil)
+	if err != nil {
+		return result, errors.Wrap(err, "handler: unable to create ACME client")
+	}
+
+	// lego's certifier does not support revocation reasons, so the request is sent through the ACME API directly
+	core, err := api.New(client.config.HTTPClient, client.config.UserAgent, client.config.CADirURL, client.user.Registration.URI, client.user.key)
+	if err != nil {
+		return result, errors.Wrap(err, "handler: unable to create ACME API client")
+	}
+
+	reason := uint(opts.Reason)
+	if err := core.Certificates.Revoke(acme.RevokeCertMessage{
+		Certificate: base64.RawURLEncoding.EncodeToString(x509Cert.Raw),
+		Reason:      &reason,
+	}); err != nil {
+		return result, errors.Wrap(err, "handler: unable to revoke certificate")
+	}
+
+	h.log.Infof("[%s] handler: certificate with ID '%s' revoked with reason '%s'", domainsStr, existingCert.ID, opts.Reason)
+
+	// Notify that the certificate has been revoked
+	event := notifier.NewEvent(notifier.EventRevoked, domains).SetExpiry(existingCert.NotAfter)
+	event.CertificateARNs = result.CertificateIDs
+	event.Serial = result.Serial
+	event.Reason = opts.Reason.String()
+
+	h.notify(event)
+
+	// Store user's private key into config file by the config path
+	if err := client.user.StorePrivateKey(h.configDir); err != nil {
+		return result, errors.Wrap(err, "handler: unable to store user's private key")
+	}
+
+	switch {
+	case opts.Delete:
+		if err := h.store.Delete(domains); err != nil {
+			return result, errors.Wrap(err, "handler: unable to delete revoked certificate")
+		}
+
+		h.log.Infof("[%s] handler: revoked certificate deleted from the store", domainsStr)
+	case opts.Replace:
+		if err := h.obtain(domains, email, nil, result); err != nil {
+			return result, errors.Wrap(err, "handler: unable to replace revoked certificate")
+		}
+	}
+
+	return result.finish(OutcomeRevoked, nil)
+}
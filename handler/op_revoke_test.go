@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRevocationReason(t *testing.T) {
+	testTable := []*struct {
+		testName       string
+		value          string
+		expectedReason RevocationReason
+		expectedErr    bool
+	}{
+		{
+			testName:       "empty value",
+			value:          "",
+			expectedReason: ReasonUnspecified,
+		},
+		{
+			testName:       "RFC 5280 name",
+			value:          "keyCompromise",
+			expectedReason: ReasonKeyCompromise,
+		},
+		{
+			testName:       "case insensitive name",
+			value:          "SUPERSEDED",
+			expectedReason: ReasonSuperseded,
+		},
+		{
+			testName:       "reason code",
+			value:          "5",
+			expectedReason: ReasonCessationOfOperation,
+		},
+		{
+			testName:    "unknown name",
+			value:       "stolen",
+			expectedErr: true,
+		},
+		{
+			testName:    "unassigned code",
+			value:       "7",
+			expectedErr: true,
+		},
+		{
+			testName:    "cACompromise code",
+			value:       "2",
+			expectedErr: true,
+		},
+		{
+			testName:    "certificateHold name",
+			value:       "certificateHold",
+			expectedErr: true,
+		},
+		{
+			testName:    "removeFromCRL code",
+			value:       "8",
+			expectedErr: true,
+		},
+		{
+			testName:    "privilegeWithdrawn code",
+			value:       "9",
+			expectedErr: true,
+		},
+		{
+			testName:    "aACompromise code",
+			value:       "10",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			reason, err := ParseRevocationReason(tt.value)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedReason, reason)
+		})
+	}
+}
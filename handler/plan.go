@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+// Plan describes changes which obtaining the certificate would make, it is set to results of dry runs
+type Plan struct {
+	// HostedZones are IDs of hosted zones where challenge records would be written
+	HostedZones []string
+
+	// CAAUpdates are names whose CAA record sets would be updated to authorize the CA
+	CAAUpdates []string
+
+	// Notifications are topics which would be notified
+	Notifications []string
+
+	// Deployments are names of deploy hooks which would be run
+	Deployments []string
+}
+
+// hostedZoneResolver resolves hosted zones of challenge records, it is implemented by Route 53 DNS-01 provider
+type hostedZoneResolver interface {
+	HostedZone(domain string) (string, error)
+}
+
+// plan sets the plan of obtaining the certificate for the given domains to the given result instead of obtaining it
+func (h *CertificateHandler) plan(domains []string, plan *Plan, result *Result) error {
+	domainsStr := strings.Join(domains, domainsJoinChar)
+
+	if resolver, ok := h.dns01.(hostedZoneResolver); ok {
+		seen := make(map[string]bool)
+		for _, domain := range domains {
+			zoneID, err := resolver.HostedZone(domain)
+			if err != nil {
+				return errors.Wrapf(err, "handler: unable to resolve hosted zone of '%s'", domain)
+			}
+
+			if !seen[zoneID] {
+				seen[zoneID] = true
+				plan.HostedZones = append(plan.HostedZones, zoneID)
+			}
+		}
+	}
+
+	// Only targets accepting the event of the obtained certificate would be notified
+	eventType := notifier.EventCertificateRenewed
+	if result.OldNotAfter.IsZero() {
+		eventType = notifier.EventCertificateIssued
+	}
+
+	for _, target := range h.notifications {
+		if !target.Accepts(eventType) {
+			continue
+		}
+
+		if router, ok := target.Notifier.(*notifier.Router); ok {
+			plan.Notifications = append(plan.Notifications, router.Topics(eventType)...)
+			continue
+		}
+
+		plan.Notifications = append(plan.Notifications, target.Topic)
+	}
+
+	for _, hook := range h.hooks {
+		plan.Deployments = append(plan.Deployments, hook.Name)
+	}
+
+	result.Plan = plan
+
+	h.log.Infof("[%s] handler: dry run, certificate is not requested", domainsStr)
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/certificate"
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+// planStore is the CertStore which fails on any change and contains the certificate expiring at the given time, if any
+type planStore struct {
+	notAfter time.Time
+}
+
+func (s *planStore) Store(*certificate.Resource, []string) ([]string, error) {
+	panic("store must not be changed by dry runs")
+}
+
+func (s *planStore) Delete([]string) error {
+	panic("store must not be changed by dry runs")
+}
+
+func (s *planStore) List() ([]*certstore.CertificateDetails, error) { return nil, nil }
+
+func (s *planStore) Load(domains []string) (*certstore.CertificateDetails, error) {
+	if s.notAfter.IsZero() {
+		return nil, nil
+	}
+
+	return &certstore.CertificateDetails{Domains: domains, NotAfter: s.notAfter}, nil
+}
+
+// planDNS01 is the DNS-01 provider which fails on any change and resolves hosted zones by the domain suffix
+type planDNS01 map[string]string
+
+func (p planDNS01) Present(domain, token, keyAuth string) error {
+	panic("records must not be changed by dry runs")
+}
+
+func (p planDNS01) CleanUp(domain, token, keyAuth string) error {
+	panic("records must not be changed by dry runs")
+}
+
+func (p planDNS01) HostedZone(domain string) (string, error) {
+	for suffix, zoneID := range p {
+		if strings.HasSuffix(domain, suffix) {
+			return zoneID, nil
+		}
+	}
+
+	return "", errors.Errorf("no hosted zone of '%s'", domain)
+}
+
+// planNotifier is the Notifier which fails on any notification
+type planNotifier struct{}
+
+func (planNotifier) Notify(topic string, event *notifier.Event) error {
+	panic("notifications must not be sent by dry runs")
+}
+
+// planCAAUpdater is the CAAUpdater which fails on any change
+type planCAAUpdater struct{}
+
+func (planCAAUpdater) UpsertCAA(domain string, values []string) error {
+	panic("CAA records must not be changed by dry runs")
+}
+
+func TestObtainDryRun(t *testing.T) {
+	// Dry runs must not contact the CA for its directory or renewal information
+	ca := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("CA must not be contacted by dry runs: %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ca.Close()
+
+	router := notifier.NewRouter(
+		&notifier.Route{
+			Events:  []notifier.EventType{notifier.EventCertificateIssued},
+			Targets: []notifier.Target{{Notifier: planNotifier{}, Topic: "issued", Events: notifier.EventTypes}},
+		},
+		&notifier.Route{
+			Events:  []notifier.EventType{notifier.EventRenewalFailed},
+			Targets: []notifier.Target{{Notifier: planNotifier{}, Topic: "failures", Events: notifier.EventTypes}},
+		},
+	)
+
+	// CAA records are verified with the configured identities of the CA
+	defer func(original func(string) ([]*dns.CAA, error)) { lookupCAA = original }(lookupCAA)
+	lookupCAA = func(domain string) ([]*dns.CAA, error) {
+		if domain == "example.net" {
+			return []*dns.CAA{{Tag: "issue", Value: "digicert.com"}}, nil
+		}
+
+		return nil, nil
+	}
+
+	testTable := []*struct {
+		testName        string
+		notAfter        time.Time
+		domains         []string
+		caaUpsert       bool
+		expectedOutcome Outcome
+		expectedPlan    *Plan
+		expectedStage   Stage
+		expectedErr     bool
+	}{
+		{
+			testName:        "new certificate",
+			domains:         []string{"example.com", "www.example.org"},
+			expectedOutcome: OutcomeIssued,
+			expectedPlan: &Plan{
+				HostedZones:   []string{"Z1", "Z2"},
+				Notifications: []string{"arn:aws:sns:us-east-1:123456789012:certificates", "issued"},
+			},
+		},
+		{
+			testName:        "expiring certificate",
+			notAfter:        time.Now().Add(24 * time.Hour),
+			domains:         []string{"example.com", "*.example.com"},
+			expectedOutcome: OutcomeRenewed,
+			expectedPlan: &Plan{
+				HostedZones:   []string{"Z1"},
+				Notifications: []string{"arn:aws:sns:us-east-1:123456789012:certificates"},
+			},
+		},
+		{
+			testName:        "valid certificate",
+			notAfter:        time.Now().Add(60 * 24 * time.Hour),
+			domains:         []string{"example.com"},
+			expectedOutcome: OutcomeSkipped,
+		},
+		{
+			testName:        "unknown hosted zone",
+			domains:         []string{"example.io"},
+			expectedOutcome: OutcomeFailed,
+			expectedErr:     true,
+		},
+		{
+			testName:      "CAA records do not authorize CA",
+			domains:       []string{"example.com", "example.net"},
+			expectedStage: StageCAA,
+			expectedErr:   true,
+		},
+		{
+			testName:        "CAA records to upsert",
+			domains:         []string{"example.com", "example.net"},
+			caaUpsert:       true,
+			expectedOutcome: OutcomeIssued,
+			expectedPlan: &Plan{
+				HostedZones:   []string{"Z1", "Z3"},
+				CAAUpdates:    []string{"example.net"},
+				Notifications: []string{"arn:aws:sns:us-east-1:123456789012:certificates", "issued"},
+			},
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			var caaUpdater CAAUpdater
+			if tt.caaUpsert {
+				caaUpdater = planCAAUpdater{}
+			}
+
+			h := NewCertificateHandler(&CertificateHandlerOptions{
+				CADirURL:      ca.URL + "/directory",
+				RenewBefore:   30 * 24,
+				CAAIdentities: []string{"letsencrypt.org"},
+				CAAUpdater:    caaUpdater,
+				DryRun:        true,
+				Log:           logrus.New(),
+				DNS01:         planDNS01{"example.com": "Z1", "example.org": "Z2", "example.net": "Z3"},
+				Store:         &planStore{notAfter: tt.notAfter},
+				Notifications: []notifier.Target{
+					{Notifier: planNotifier{}, Topic: "arn:aws:sns:us-east-1:123456789012:certificates"},
+					{Notifier: planNotifier{}, Topic: "failures-only", Events: []notifier.EventType{notifier.EventRenewalFailed}},
+					{Notifier: router, Events: notifier.EventTypes},
+				},
+			})
+
+			result, err := h.Obtain(tt.domains, "admin@example.com")
+			if tt.expectedErr {
+				require.Error(t, err)
+				if len(tt.expectedStage) > 0 {
+					require.Equal(t, tt.expectedStage, ErrorStage(err))
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedOutcome, result.Outcome)
+			require.Equal(t, tt.expectedPlan, result.Plan)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-acme/lego/certcrypto"
+)
+
+// Policy is the set of rules certificates must comply with before they are requested from the CA
+type Policy struct {
+	AllowedSuffixes  []string // Domains must belong to one of the zones, any domain is allowed if empty
+	DeniedNames      []string // Domains which must not be included into certificates
+	DenyWildcard     bool
+	MaxSANs          int // Unlimited if zero
+	AllowedKeyTypes  []certcrypto.KeyType
+	AllowedCADirURLs []string
+}
+
+// PolicyError is the error returned when a certificate violates the policy
+type PolicyError struct {
+	Violations []string
+}
+
+// Error implements error interface
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("handler: rejected by policy: %s", strings.Join(e.Violations, "; "))
+}
+
+// Check checks the certificate of the given domains with the given key type and CA against the policy.
+// Returns *PolicyError describing all violations.
+func (p *Policy) Check(domains []string, keyType certcrypto.KeyType, caDirURL string) error {
+	if p == nil {
+		return nil
+	}
+
+	var violations []string
+	if p.MaxSANs > 0 && len(domains) > p.MaxSANs {
+		violations = append(violations, fmt.Sprintf("%d domains exceed the maximum of %d", len(domains), p.MaxSANs))
+	}
+
+	for _, domain := range domains {
+		name := strings.ToLower(strings.TrimSuffix(domain, "."))
+
+		if strings.HasPrefix(name, "*.") && p.DenyWildcard {
+			violations = append(violations, fmt.Sprintf("wildcard domain '%s' is not allowed", domain))
+		}
+
+		if p.isDenied(name) {
+			violations = append(violations, fmt.Sprintf("domain '%s' is denied", domain))
+		} else if !p.isAllowed(name) {
+			violations = append(violations, fmt.Sprintf("domain '%s' is not within allowed suffixes %s", domain, strings.Join(p.AllowedSuffixes, ", ")))
+		}
+	}
+
+	if len(p.AllowedKeyTypes) > 0 && !containsKeyType(p.AllowedKeyTypes, keyType) {
+		violations = append(violations, fmt.Sprintf("key type '%s' is not allowed", keyType))
+	}
+
+	if len(p.AllowedCADirURLs) > 0 && !containsString(p.AllowedCADirURLs, caDirURL) {
+		violations = append(violations, fmt.Sprintf("CA '%s' is not allowed", caDirURL))
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// isDenied checks if the given normalized domain is one of the denied names
+func (p *Policy) isDenied(name string) bool {
+	for _, denied := range p.DeniedNames {
+		if strings.ToLower(strings.TrimSuffix(denied, ".")) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAllowed checks if the given normalized domain belongs to one of the allowed suffixes
+func (p *Policy) isAllowed(name string) bool {
+	if len(p.AllowedSuffixes) == 0 {
+		return true
+	}
+
+	name = strings.TrimPrefix(name, "*.")
+	for _, suffix := range p.AllowedSuffixes {
+		suffix = strings.ToLower(strings.Trim(suffix, "."))
+		if name == suffix || strings.HasSuffix(name, "."+suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckPolicy checks the certificate of the given domains against the policy of the handler
+func (h *CertificateHandler) CheckPolicy(domains []string) error {
+	return h.policy.Check(domains, h.keyType, h.caDirURL)
+}
+
+// enforcePolicy checks the certificate of the given domains against the policy of the handler,
+// and notifies about the rejection
+func (h *CertificateHandler) enforcePolicy(domains []string) error {
+	err := h.CheckPolicy(domains)
+	if err == nil {
+		return nil
+	}
+
+	h.log.Errorf("[%s] %s", strings.Join(domains, domainsJoinChar), err)
+
+	h.notifyFailure(domains, withStage(StagePolicy, err))
+
+	return err
+}
+
+func containsKeyType(list []certcrypto.KeyType, keyType certcrypto.KeyType) bool {
+	for _, item := range list {
+		if item == keyType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/lego"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &Policy{
+		AllowedSuffixes:  []string{"example.com", ".example.org"},
+		DeniedNames:      []string{"admin.example.com"},
+		DenyWildcard:     true,
+		MaxSANs:          3,
+		AllowedKeyTypes:  []certcrypto.KeyType{certcrypto.EC256, certcrypto.RSA2048},
+		AllowedCADirURLs: []string{lego.LEDirectoryProduction},
+	}
+
+	testTable := []*struct {
+		testName           string
+		policy             *Policy
+		domains            []string
+		keyType            certcrypto.KeyType
+		caDirURL           string
+		expectedViolations []string
+	}{
+		{
+			testName: "no policy",
+			domains:  []string{"*.example.net"},
+			keyType:  certcrypto.RSA8192,
+			caDirURL: lego.LEDirectoryStaging,
+		},
+		{
+			testName: "compliant",
+			policy:   policy,
+			domains:  []string{"example.com", "www.example.com", "api.example.org"},
+			keyType:  certcrypto.EC256,
+			caDirURL: lego.LEDirectoryProduction,
+		},
+		{
+			testName: "domains",
+			policy:   policy,
+			domains:  []string{"*.example.com", "Admin.example.com", "badexample.com"},
+			keyType:  certcrypto.EC256,
+			caDirURL: lego.LEDirectoryProduction,
+			expectedViolations: []string{
+				"wildcard domain '*.example.com' is not allowed",
+				"domain 'Admin.example.com' is denied",
+				"domain 'badexample.com' is not within allowed suffixes example.com, .example.org",
+			},
+		},
+		{
+			testName: "certificate settings",
+			policy:   policy,
+			domains:  []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"},
+			keyType:  certcrypto.RSA4096,
+			caDirURL: lego.LEDirectoryStaging,
+			expectedViolations: []string{
+				"4 domains exceed the maximum of 3",
+				"key type '4096' is not allowed",
+				"CA '" + lego.LEDirectoryStaging + "' is not allowed",
+			},
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			err := tt.policy.Check(tt.domains, tt.keyType, tt.caDirURL)
+			if len(tt.expectedViolations) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			require.IsType(t, &PolicyError{}, err)
+			require.Equal(t, tt.expectedViolations, err.(*PolicyError).Violations)
+		})
+	}
+}
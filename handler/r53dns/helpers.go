@@ -1,6 +1,10 @@
 package r53dns
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
 
 // buildQuotedValue quotes the given value
 func buildQuotedValue(value string) string {
@@ -11,3 +15,43 @@ func buildQuotedValue(value string) string {
 func buildDNSComment(action, domainName string) string {
 	return fmt.Sprintf("acme-dns-route53 certificate validation, action = %s and domain = %s", action, domainName)
 }
+
+// lookupHostedZone looks up the hosted zone ID for the given domain in the given overrides by domain names.
+// The override of the longest domain which is equal to the given domain or its parent is used.
+func lookupHostedZone(hostedZones map[string]string, domainName string) string {
+	domainName = strings.TrimSuffix(domainName, ".")
+
+	var zoneDomain, zoneID string
+	for domain, id := range hostedZones {
+		domain = strings.TrimSuffix(domain, ".")
+		if domain != domainName && !strings.HasSuffix(domainName, "."+domain) {
+			continue
+		}
+
+		if len(domain) > len(zoneDomain) {
+			zoneDomain, zoneID = domain, id
+		}
+	}
+
+	return zoneID
+}
+
+// keyedMutex is the set of mutexes identified by keys
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the mutex of the given key and returns the function unlocking it
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		k.locks[key] = l
+	}
+	k.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
@@ -32,3 +32,44 @@ func TestBuildQuotedValue(t *testing.T) {
 		})
 	}
 }
+
+func TestLookupHostedZone(t *testing.T) {
+	hostedZones := map[string]string{
+		"example.com":     "Z1",
+		"sub.example.com": "Z2",
+		"example.org.":    "Z3",
+	}
+
+	testTable := []*struct {
+		testName       string
+		domainName     string
+		expectedZoneID string
+	}{
+		{
+			testName:       "challenge record of the zone apex",
+			domainName:     "_acme-challenge.example.com.",
+			expectedZoneID: "Z1",
+		},
+		{
+			testName:       "the longest parent domain",
+			domainName:     "_acme-challenge.www.sub.example.com.",
+			expectedZoneID: "Z2",
+		},
+		{
+			testName:       "override with trailing dot",
+			domainName:     "_acme-challenge.example.org",
+			expectedZoneID: "Z3",
+		},
+		{
+			testName:       "domain with the same suffix",
+			domainName:     "_acme-challenge.notexample.com.",
+			expectedZoneID: "",
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			require.Equal(t, tt.expectedZoneID, lookupHostedZone(hostedZones, tt.domainName))
+		})
+	}
+}
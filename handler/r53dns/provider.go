@@ -1,6 +1,9 @@
 package r53dns
 
 import (
+	"strings"
+	"sync"
+
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/go-acme/lego/challenge"
@@ -9,17 +12,52 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// To make sure that dnsProvider implements Provider interface
+var _ Provider = &dnsProvider{}
+
+// Provider is the DNS-01 challenge provider by Route 53
+type Provider interface {
+	challenge.Provider
+
+	// CleanUpPending removes TXT records which have been created but not removed yet,
+	// e.g. if the challenge is interrupted.
+	CleanUpPending() error
+
+	// UpsertCAA replaces CAA records of the given domain with the given values
+	UpsertCAA(domain string, values []string) error
+
+	// HostedZone returns the ID of the hosted zone where challenge records of the given domain are created
+	HostedZone(domain string) (string, error)
+}
+
+// pendingRecord is the TXT record which has been created but not removed yet
+type pendingRecord struct {
+	domain string
+	fqdn   string
+	value  string
+}
+
 // dnsProvider is the custom implementation of the challenge.Provider interface
 type dnsProvider struct {
 	r53Worker *r53ResourceWorker
 	log       *logrus.Logger
+
+	pendingLock sync.Mutex
+	pending     map[pendingRecord]struct{}
 }
 
 // New is the constructor of DNSProvider
-func New(provider client.ConfigProvider, log *logrus.Logger) challenge.Provider {
+func New(provider client.ConfigProvider, log *logrus.Logger) Provider {
+	return NewWithHostedZones(provider, nil, log)
+}
+
+// NewWithHostedZones is the constructor of DNSProvider which uses the given hosted zone IDs
+// for the given domains and their subdomains instead of looking them up
+func NewWithHostedZones(provider client.ConfigProvider, hostedZones map[string]string, log *logrus.Logger) Provider {
 	return &dnsProvider{
-		r53Worker: newR53ResourceWorker(route53.New(provider), log),
+		r53Worker: newR53ResourceWorker(route53.New(provider), hostedZones, log),
 		log:       log,
+		pending:   make(map[pendingRecord]struct{}),
 	}
 }
 
@@ -34,6 +72,9 @@ func (p *dnsProvider) Present(domain, token, keyAuth string) error {
 
 	p.log.Infof("[%s] acme: Creating TXT record in %s zone", domain, authZone)
 
+	// Track the record before creating, so it is removed even if the creation is interrupted
+	p.track(pendingRecord{domain: domain, fqdn: fqdn, value: value}, true)
+
 	// Create a subdomain
 	recordID, err := p.r53Worker.changeDNSRecord(route53.ChangeActionUpsert, fqdn, buildQuotedValue(value))
 	if err != nil {
@@ -63,7 +104,67 @@ func (p *dnsProvider) CleanUp(domain, token, keyAuth string) error {
 		return errors.Wrapf(err, "unable to delete a record with FQDN = '%s'", fqdn)
 	}
 
+	p.track(pendingRecord{domain: domain, fqdn: fqdn, value: value}, false)
+
 	p.log.Infof("[%s] acme: Removed TXT record in %s zone with ID %s", domain, authZone, recordID)
 
 	return nil
 }
+
+// CleanUpPending removes TXT records which have been created but not removed yet
+func (p *dnsProvider) CleanUpPending() error {
+	p.pendingLock.Lock()
+	records := make([]pendingRecord, 0, len(p.pending))
+	for record := range p.pending {
+		records = append(records, record)
+	}
+	p.pendingLock.Unlock()
+
+	var lastErr error
+	for _, record := range records {
+		p.log.Infof("[%s] acme: Removing pending TXT record with FQDN = '%s'", record.domain, record.fqdn)
+
+		if _, err := p.r53Worker.changeDNSRecord(route53.ChangeActionDelete, record.fqdn, buildQuotedValue(record.value)); err != nil {
+			p.log.Errorf("[%s] acme: unable to remove pending TXT record with FQDN = '%s': %s", record.domain, record.fqdn, err)
+			lastErr = errors.Wrapf(err, "unable to delete a record with FQDN = '%s'", record.fqdn)
+			continue
+		}
+
+		p.track(record, false)
+	}
+
+	return lastErr
+}
+
+// UpsertCAA replaces CAA records of the given domain with the given values, e.g. `0 issue "letsencrypt.org"`
+func (p *dnsProvider) UpsertCAA(domain string, values []string) error {
+	fqdn := dns01.ToFqdn(domain)
+
+	recordID, err := p.r53Worker.changeRecordSet(route53.ChangeActionUpsert, fqdn, route53.RRTypeCaa, values)
+	if err != nil {
+		return errors.Wrapf(err, "unable to upsert CAA records with FQDN = '%s'", fqdn)
+	}
+
+	p.log.Infof("[%s] acme: Upserted CAA records with ID %s", domain, recordID)
+
+	return nil
+}
+
+// HostedZone returns the ID of the hosted zone where challenge records of the given domain are created
+func (p *dnsProvider) HostedZone(domain string) (string, error) {
+	fqdn, _ := dns01.GetRecord(strings.TrimPrefix(domain, "*."), "")
+
+	return p.r53Worker.getHostedZone(fqdn)
+}
+
+// track adds the given record to the pending records or removes it from them
+func (p *dnsProvider) track(record pendingRecord, add bool) {
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+
+	if add {
+		p.pending[record] = struct{}{}
+	} else {
+		delete(p.pending, record)
+	}
+}
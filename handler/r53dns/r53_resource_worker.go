@@ -3,6 +3,7 @@ package r53dns
 import (
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -18,22 +19,34 @@ const (
 	recordTTL int64 = 60
 )
 
+// zoneLocks serializes changes of the same hosted zone made by all workers of the process,
+// since Route 53 rejects changes of a zone while its previous change is being submitted (PriorRequestNotComplete)
+var zoneLocks = &keyedMutex{locks: make(map[string]*sync.Mutex)}
+
 // r53ResourceWorker represents the functionality to work with Route53 API
 type r53ResourceWorker struct {
-	r53 *route53.Route53
-	log *logrus.Logger
+	r53         *route53.Route53
+	hostedZones map[string]string
+	log         *logrus.Logger
 }
 
-// newR53ResourceWorker is the constructor of r53ResourceWorker
-func newR53ResourceWorker(r53 *route53.Route53, log *logrus.Logger) *r53ResourceWorker {
+// newR53ResourceWorker is the constructor of r53ResourceWorker.
+// hostedZones overrides hosted zone IDs of the given domains and their subdomains.
+func newR53ResourceWorker(r53 *route53.Route53, hostedZones map[string]string, log *logrus.Logger) *r53ResourceWorker {
 	return &r53ResourceWorker{
-		r53: r53,
-		log: log,
+		r53:         r53,
+		hostedZones: hostedZones,
+		log:         log,
 	}
 }
 
 // changeDNSRecord changed the record in DNS Route53 by the given params
 func (r *r53ResourceWorker) changeDNSRecord(action, domainName, value string) (string, error) {
+	return r.changeRecordSet(action, domainName, route53.RRTypeTxt, []string{value})
+}
+
+// changeRecordSet changes the record set of the given type with the given values in DNS Route53
+func (r *r53ResourceWorker) changeRecordSet(action, domainName, recordType string, values []string) (string, error) {
 	// Retrieve a hosted zone ID
 	hostedZoneID, err := r.getHostedZone(domainName)
 	if err != nil {
@@ -45,7 +58,13 @@ func (r *r53ResourceWorker) changeDNSRecord(action, domainName, value string) (s
 	// Build comment for the current action
 	comment := buildDNSComment(action, domainName)
 
-	// Change the record
+	records := make([]*route53.ResourceRecord, len(values))
+	for i, value := range values {
+		records[i] = &route53.ResourceRecord{Value: aws.String(value)}
+	}
+
+	// Change the record, waiting for the change is not serialized to let orders be validated in parallel
+	unlock := zoneLocks.lock(hostedZoneID)
 	result, err := r.r53.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
 		HostedZoneId: aws.String(hostedZoneID),
 		ChangeBatch: &route53.ChangeBatch{
@@ -54,19 +73,16 @@ func (r *r53ResourceWorker) changeDNSRecord(action, domainName, value string) (s
 				{
 					Action: aws.String(action),
 					ResourceRecordSet: &route53.ResourceRecordSet{
-						Name: aws.String(domainName),
-						Type: aws.String(route53.RRTypeTxt),
-						TTL:  aws.Int64(recordTTL),
-						ResourceRecords: []*route53.ResourceRecord{
-							{
-								Value: aws.String(value), // TXT record
-							},
-						},
+						Name:            aws.String(domainName),
+						Type:            aws.String(recordType),
+						TTL:             aws.Int64(recordTTL),
+						ResourceRecords: records,
 					},
 				},
 			},
 		},
 	})
+	unlock()
 	if err != nil {
 		return "", errors.Wrapf(err, "unable to change DNS record with HostedZoneId = '%s', Name = '%s', and Action = '%s'", hostedZoneID, domainName, action)
 	}
@@ -82,6 +98,10 @@ func (r *r53ResourceWorker) changeDNSRecord(action, domainName, value string) (s
 // getHostedZone retrieves the zone id responsible a given FQDN.
 // That is, the id for the zone whose name is the longest parent of the domain.
 func (r *r53ResourceWorker) getHostedZone(domainName string) (string, error) {
+	if hostedZoneID := lookupHostedZone(r.hostedZones, domainName); len(hostedZoneID) > 0 {
+		return hostedZoneID, nil
+	}
+
 	zonesList, err := r.r53.ListHostedZones(nil)
 	if err != nil {
 		return "", errors.Wrap(err, "unable to list hosted zones")
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-acme/lego/certcrypto"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/deploy"
+)
+
+// Outcome is the outcome of processing a certificate
+type Outcome string
+
+const (
+	// OutcomeIssued means that a new certificate has been issued
+	OutcomeIssued Outcome = "issued"
+
+	// OutcomeRenewed means that the existing certificate has been renewed
+	OutcomeRenewed Outcome = "renewed"
+
+	// OutcomeSkipped means that the existing certificate does not need renewal yet
+	OutcomeSkipped Outcome = "skipped"
+
+	// OutcomeRevoked means that the existing certificate has been revoked
+	OutcomeRevoked Outcome = "revoked"
+
+	// OutcomeRejected means that the certificate has not been requested since it violates the policy
+	OutcomeRejected Outcome = "rejected"
+
+	// OutcomeFailed means that processing of the certificate failed
+	OutcomeFailed Outcome = "failed"
+
+	// OutcomeUnprocessed means that processing of the certificate was not started or finished in time
+	OutcomeUnprocessed Outcome = "unprocessed"
+)
+
+// Result is the result of processing a certificate
+type Result struct {
+	Outcome Outcome
+
+	// CertificateIDs are identifiers of the certificate inside the store, e.g. ARNs for ACM.
+	CertificateIDs []string
+
+	// Serial is the hex encoded serial number of the certificate.
+	Serial string
+
+	// OldNotAfter is the expiration time of the existing certificate, zero if there was no certificate.
+	OldNotAfter time.Time
+
+	// NewNotAfter is the expiration time of the obtained certificate, zero if no certificate was obtained.
+	NewNotAfter time.Time
+
+	// Deployments are results of deploy hooks run after the certificate has been stored.
+	Deployments []*deploy.Result
+
+	// Plan describes changes which would be made, it is set by dry runs only.
+	Plan *Plan
+}
+
+// newResult creates the failed result with details of the given existing certificate
+func newResult(existing *certstore.CertificateDetails) *Result {
+	result := &Result{Outcome: OutcomeFailed}
+	if existing == nil {
+		return result
+	}
+
+	result.CertificateIDs = []string{existing.ID}
+	result.OldNotAfter = existing.NotAfter
+
+	if x509Cert, err := certcrypto.ParsePEMCertificate(existing.Certificate); err == nil {
+		result.Serial = fmt.Sprintf("%x", x509Cert.SerialNumber)
+	}
+
+	return result
+}
+
+// setCertificate sets details of the given PEM encoded certificate stored with the given identifiers
+func (r *Result) setCertificate(cert []byte, ids []string) error {
+	r.CertificateIDs = ids
+
+	x509Cert, err := certcrypto.ParsePEMCertificate(cert)
+	if err != nil {
+		return err
+	}
+
+	r.Serial = fmt.Sprintf("%x", x509Cert.SerialNumber)
+	r.NewNotAfter = x509Cert.NotAfter
+
+	return nil
+}
+
+// rejected returns the result with the rejected outcome and the given policy error
+func rejected(err error) (*Result, error) {
+	return &Result{Outcome: OutcomeRejected}, err
+}
+
+// finish sets the given outcome if err is nil, and returns the result with the error
+func (r *Result) finish(outcome Outcome, err error) (*Result, error) {
+	if err == nil {
+		r.Outcome = outcome
+	}
+
+	return r, err
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"crypto"
 	"encoding/pem"
+	"io/ioutil"
 	"os"
 
 	"github.com/go-acme/lego/certcrypto"
@@ -39,6 +40,26 @@ func (u *CertUser) GetPrivateKey() crypto.PrivateKey {
 	return u.key
 }
 
+// LoadPrivateKey loads the private key of the user from the file in the given config directory.
+// Returns false if the private key has not been stored yet.
+func (u *CertUser) LoadPrivateKey(configDir string) (bool, error) {
+	filePath := privateKeyPath(configDir, u.Email)
+
+	keyBytes, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to read file with path '%s'", filePath)
+	}
+
+	if u.key, err = certcrypto.ParsePEMPrivateKey(keyBytes); err != nil {
+		return false, errors.Wrapf(err, "unable to parse private key from file with path '%s'", filePath)
+	}
+
+	return true, nil
+}
+
 // StorePrivateKey stores the private key to the file by the given path
 // configDir - is the root of configs. Must be present without "/" in the end.
 // TODO: Create an interface for storing user's private key
@@ -49,11 +70,9 @@ func (u *CertUser) StorePrivateKey(configDir string) error {
 				return errors.Wrap(err, "unable to create config directory")
 			}
 		}
-
-		configDir += "/"
 	}
 
-	filePath := configDir + u.Email + ".pem"
+	filePath := privateKeyPath(configDir, u.Email)
 
 	certOut, err := os.Create(filePath)
 	if err != nil {
@@ -68,3 +87,12 @@ func (u *CertUser) StorePrivateKey(configDir string) error {
 
 	return nil
 }
+
+// privateKeyPath builds the path to the private key file of the user with the given email
+func privateKeyPath(configDir, email string) string {
+	if len(configDir) > 0 {
+		configDir += "/"
+	}
+
+	return configDir + email + ".pem"
+}
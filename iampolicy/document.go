@@ -0,0 +1,41 @@
+package iampolicy
+
+const (
+	// policyVersion is the version of the IAM policy language
+	policyVersion = "2012-10-17"
+)
+
+// Document is the IAM policy document
+type Document struct {
+	Version   string               `json:"Version"`
+	Statement []*DocumentStatement `json:"Statement"`
+}
+
+// DocumentStatement is the statement of the IAM policy document
+type DocumentStatement struct {
+	Sid       string                         `json:"Sid"`
+	Effect    string                         `json:"Effect"`
+	Action    []string                       `json:"Action"`
+	Resource  []string                       `json:"Resource"`
+	Condition map[string]map[string][]string `json:"Condition,omitempty"`
+}
+
+// NewDocument creates the IAM policy document which allows the given statements
+func NewDocument(statements []*Statement) *Document {
+	doc := &Document{
+		Version:   policyVersion,
+		Statement: make([]*DocumentStatement, len(statements)),
+	}
+
+	for i, statement := range statements {
+		doc.Statement[i] = &DocumentStatement{
+			Sid:       statement.Sid,
+			Effect:    "Allow",
+			Action:    statement.Actions,
+			Resource:  statement.Resources,
+			Condition: statement.Conditions,
+		}
+	}
+
+	return doc
+}
@@ -0,0 +1,445 @@
+package iampolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/begmaroman/acme-dns-route53/config"
+)
+
+const (
+	// SubsystemRoute53 is the subsystem which creates challenge records in Route 53
+	SubsystemRoute53 = "Route 53"
+
+	// SubsystemACM is the subsystem which stores certificates in ACM
+	SubsystemACM = "ACM"
+
+	// SubsystemSNS is the subsystem which publishes notifications to SNS
+	SubsystemSNS = "SNS"
+
+	// SubsystemSES is the subsystem which sends notification emails via SES
+	SubsystemSES = "SES"
+
+	// SubsystemDeploy is the subsystem which runs deploy hooks after certificates have been stored
+	SubsystemDeploy = "Deploy"
+
+	// SubsystemLambda is the subsystem which runs the tool in Lambda, e.g. invokes the function asynchronously
+	SubsystemLambda = "Lambda"
+
+	// SubsystemConfig is the subsystem which loads the configuration file
+	SubsystemConfig = "Configuration"
+)
+
+const (
+	// conditionRecordNames is the condition key of names of records changed in Route 53
+	conditionRecordNames = "route53:ChangeResourceRecordSetsNormalizedRecordNames"
+
+	// conditionRecordTypes is the condition key of types of records changed in Route 53
+	conditionRecordTypes = "route53:ChangeResourceRecordSetsRecordTypes"
+
+	// conditionOperator is the operator of conditions which must match all changed records
+	conditionOperator = "ForAllValues:StringEquals"
+)
+
+// Statement is the set of actions required on the resources by a subsystem
+type Statement struct {
+	Sid       string
+	Subsystem string
+	Actions   []string
+	Resources []string
+
+	// Conditions maps condition operators to condition keys and their values
+	Conditions map[string]map[string][]string
+}
+
+// Options is the options of required permissions
+type Options struct {
+	Partition string
+	AccountID string
+	Region    string // The default region of the session
+
+	// Groups are certificate groups which define stores and notification targets
+	Groups []*config.Group
+
+	// HostedZoneIDs are IDs of hosted zones where challenge records of the groups are created,
+	// any hosted zone is used if empty
+	HostedZoneIDs []string
+
+	// ConfigLocation is the location of the configuration file, local files need no permissions
+	ConfigLocation string
+
+	// RestrictRecords limits changes of Route 53 records to challenge TXT records of the domains of the groups,
+	// and CAA records of the domains and their parents if the groups update CAA records
+	RestrictRecords bool
+
+	// Delete allows deleting certificates from ACM, required to revoke certificates with deletion or replacement
+	Delete bool
+
+	// Function is the name or ARN of the Lambda function running the tool, required by ContinueAsync and HTTP
+	Function string
+
+	// ContinueAsync allows the function to invoke itself to process groups which are not processed in time
+	ContinueAsync bool
+
+	// HTTP allows the function to obtain certificates requested by HTTP,
+	// it invokes itself and keeps in-flight jobs in SSM parameters
+	HTTP bool
+
+	// Queue is the ARN of the SQS queue triggering the function, empty if it is not triggered by SQS
+	Queue string
+}
+
+// Required returns the statements with permissions required to process the configured groups
+func Required(opts *Options) []*Statement {
+	partition := opts.Partition
+	if len(partition) == 0 {
+		partition = "aws"
+	}
+
+	ids := make([]string, len(opts.HostedZoneIDs))
+	for i, id := range opts.HostedZoneIDs {
+		ids[i] = strings.TrimPrefix(id, "/hostedzone/")
+	}
+
+	zones := make([]string, 0, len(ids))
+	for _, id := range unique(ids) {
+		zones = append(zones, fmt.Sprintf("arn:%s:route53:::hostedzone/%s", partition, id))
+	}
+	if len(zones) == 0 {
+		zones = []string{fmt.Sprintf("arn:%s:route53:::hostedzone/*", partition)}
+	}
+
+	statements := []*Statement{
+		{
+			Sid:       "Route53ListHostedZones",
+			Subsystem: SubsystemRoute53,
+			Actions:   []string{"route53:ListHostedZones"},
+			Resources: []string{"*"},
+		},
+		{
+			Sid:        "Route53ChangeRecords",
+			Subsystem:  SubsystemRoute53,
+			Actions:    []string{"route53:ChangeResourceRecordSets"},
+			Resources:  zones,
+			Conditions: recordConditions(opts),
+		},
+		{
+			Sid:       "Route53GetChange",
+			Subsystem: SubsystemRoute53,
+			Actions:   []string{"route53:GetChange"},
+			Resources: []string{fmt.Sprintf("arn:%s:route53:::change/*", partition)},
+		},
+		{
+			Sid:       "ACMListCertificates",
+			Subsystem: SubsystemACM,
+			Actions:   []string{"acm:ListCertificates"},
+			Resources: []string{"*"},
+		},
+	}
+
+	var regions, topics, sesRegions []string
+	for _, group := range opts.Groups {
+		for _, store := range group.Stores {
+			region := store.Region
+			if len(region) == 0 {
+				region = opts.Region
+			}
+			regions = append(regions, region)
+		}
+
+		notifications := append([]*config.Notification(nil), group.Notifications...)
+		for _, route := range group.Routes() {
+			notifications = append(notifications, route.Notifications...)
+		}
+
+		for _, notification := range notifications {
+			switch notification.Type {
+			case config.NotificationTypeSNS:
+				topics = append(topics, notification.Topic)
+			case config.NotificationTypeSES:
+				region := notification.Region
+				if len(region) == 0 {
+					region = opts.Region
+				}
+				sesRegions = append(sesRegions, region)
+			}
+		}
+	}
+
+	if regions = unique(regions); len(regions) > 0 {
+		certificates := make([]string, len(regions))
+		for i, region := range regions {
+			certificates[i] = fmt.Sprintf("arn:%s:acm:%s:%s:certificate/*", partition, region, opts.AccountID)
+		}
+
+		statements = append(statements, &Statement{
+			Sid:       "ACMManageCertificates",
+			Subsystem: SubsystemACM,
+			Actions: []string{
+				"acm:ImportCertificate",
+				"acm:DescribeCertificate",
+				"acm:GetCertificate",
+				"acm:AddTagsToCertificate",
+				"acm:ListTagsForCertificate",
+			},
+			Resources: certificates,
+		})
+
+		if opts.Delete {
+			statements = append(statements, &Statement{
+				Sid:       "ACMDeleteCertificates",
+				Subsystem: SubsystemACM,
+				Actions:   []string{"acm:DeleteCertificate"},
+				Resources: certificates,
+			})
+		}
+	}
+
+	if topics = unique(topics); len(topics) > 0 {
+		statements = append(statements, &Statement{
+			Sid:       "SNSPublish",
+			Subsystem: SubsystemSNS,
+			Actions:   []string{"sns:Publish"},
+			Resources: topics,
+		})
+	}
+
+	if sesRegions = unique(sesRegions); len(sesRegions) > 0 {
+		identities := make([]string, len(sesRegions))
+		for i, region := range sesRegions {
+			identities[i] = fmt.Sprintf("arn:%s:ses:%s:%s:identity/*", partition, region, opts.AccountID)
+		}
+
+		statements = append(statements, &Statement{
+			Sid:       "SESSendEmail",
+			Subsystem: SubsystemSES,
+			Actions:   []string{"ses:SendEmail"},
+			Resources: identities,
+		})
+	}
+
+	statements = append(statements, deployStatements(opts, partition)...)
+	statements = append(statements, lambdaStatements(opts, partition)...)
+
+	if statement := configStatement(opts, partition); statement != nil {
+		statements = append(statements, statement)
+	}
+
+	return statements
+}
+
+// configStatement returns the statement required to load the configuration file, nil for local files
+func configStatement(opts *Options, partition string) *Statement {
+	switch location := opts.ConfigLocation; {
+	case strings.HasPrefix(location, "s3://"):
+		return &Statement{
+			Sid:       "ConfigurationRead",
+			Subsystem: SubsystemConfig,
+			Actions:   []string{"s3:GetObject"},
+			Resources: []string{fmt.Sprintf("arn:%s:s3:::%s", partition, strings.TrimPrefix(location, "s3://"))},
+		}
+	case strings.HasPrefix(location, "ssm:"):
+		name := strings.TrimPrefix(strings.TrimPrefix(location, "ssm:"), "/")
+		return &Statement{
+			Sid:       "ConfigurationRead",
+			Subsystem: SubsystemConfig,
+			Actions:   []string{"ssm:GetParameter"},
+			Resources: []string{fmt.Sprintf("arn:%s:ssm:%s:%s:parameter/%s", partition, opts.Region, opts.AccountID, name)},
+		}
+	default:
+		return nil
+	}
+}
+
+// deployStatements returns statements required to run deploy hooks of the groups, local commands need no permissions
+func deployStatements(opts *Options, partition string) []*Statement {
+	var functions, documents, instances, eventBuses, listeners, distributions, domainNames []string
+	for _, group := range opts.Groups {
+		for _, hook := range group.Deploy {
+			region := hook.Region
+			if len(region) == 0 {
+				region = opts.Region
+			}
+
+			switch hook.Type {
+			case config.HookTypeLambda:
+				functions = append(functions, functionARN(hook.Function, partition, region, opts.AccountID))
+			case config.HookTypeSSM:
+				documents = append(documents, fmt.Sprintf("arn:%s:ssm:%s::document/AWS-RunShellScript", partition, region))
+				if len(hook.Targets) > 0 {
+					instances = append(instances, fmt.Sprintf("arn:%s:ec2:%s:%s:instance/*", partition, region, opts.AccountID))
+				}
+				for _, id := range hook.InstanceIDs {
+					instances = append(instances, fmt.Sprintf("arn:%s:ec2:%s:%s:instance/%s", partition, region, opts.AccountID, id))
+				}
+			case config.HookTypeEventBridge:
+				eventBuses = append(eventBuses, fmt.Sprintf("arn:%s:events:%s:%s:event-bus/default", partition, region, opts.AccountID))
+			case config.HookTypeELBv2:
+				listeners = append(listeners, hook.Listener)
+			case config.HookTypeCloudFront:
+				distributions = append(distributions, fmt.Sprintf("arn:%s:cloudfront::%s:distribution/%s", partition, opts.AccountID, hook.Distribution))
+			case config.HookTypeAPIGateway:
+				domainNames = append(domainNames, fmt.Sprintf("arn:%s:apigateway:%s::/domainnames/%s", partition, region, hook.DomainName))
+			}
+		}
+	}
+
+	var statements []*Statement
+
+	if functions = unique(functions); len(functions) > 0 {
+		statements = append(statements, &Statement{
+			Sid:       "DeployInvokeFunction",
+			Subsystem: SubsystemDeploy,
+			Actions:   []string{"lambda:InvokeFunction"},
+			Resources: functions,
+		})
+	}
+
+	if documents = unique(documents); len(documents) > 0 {
+		statements = append(statements, &Statement{
+			Sid:       "DeploySendCommand",
+			Subsystem: SubsystemDeploy,
+			Actions:   []string{"ssm:SendCommand"},
+			Resources: append(documents, unique(instances)...),
+		}, &Statement{
+			Sid:       "DeployListCommands",
+			Subsystem: SubsystemDeploy,
+			Actions:   []string{"ssm:ListCommands", "ssm:ListCommandInvocations"},
+			Resources: []string{"*"},
+		})
+	}
+
+	if eventBuses = unique(eventBuses); len(eventBuses) > 0 {
+		statements = append(statements, &Statement{
+			Sid:       "DeployPutEvents",
+			Subsystem: SubsystemDeploy,
+			Actions:   []string{"events:PutEvents"},
+			Resources: eventBuses,
+		})
+	}
+
+	if listeners = unique(listeners); len(listeners) > 0 {
+		statements = append(statements, &Statement{
+			Sid:       "DeployDescribeListenerCertificates",
+			Subsystem: SubsystemDeploy,
+			Actions:   []string{"elasticloadbalancing:DescribeListenerCertificates"},
+			Resources: []string{"*"},
+		}, &Statement{
+			Sid:       "DeployAttachListenerCertificates",
+			Subsystem: SubsystemDeploy,
+			Actions:   []string{"elasticloadbalancing:AddListenerCertificates", "elasticloadbalancing:ModifyListener"},
+			Resources: listeners,
+		})
+	}
+
+	if distributions = unique(distributions); len(distributions) > 0 {
+		statements = append(statements, &Statement{
+			Sid:       "DeployUpdateDistributions",
+			Subsystem: SubsystemDeploy,
+			Actions:   []string{"cloudfront:GetDistributionConfig", "cloudfront:UpdateDistribution"},
+			Resources: distributions,
+		})
+	}
+
+	if domainNames = unique(domainNames); len(domainNames) > 0 {
+		statements = append(statements, &Statement{
+			Sid:       "DeployUpdateDomainNames",
+			Subsystem: SubsystemDeploy,
+			Actions:   []string{"apigateway:GET", "apigateway:PATCH"},
+			Resources: domainNames,
+		})
+	}
+
+	return statements
+}
+
+// lambdaStatements returns statements required by the Lambda function running the tool
+func lambdaStatements(opts *Options, partition string) []*Statement {
+	var statements []*Statement
+
+	if len(opts.Function) > 0 && (opts.ContinueAsync || opts.HTTP) {
+		statements = append(statements, &Statement{
+			Sid:       "LambdaInvokeItself",
+			Subsystem: SubsystemLambda,
+			Actions:   []string{"lambda:InvokeFunction"},
+			Resources: []string{functionARN(opts.Function, partition, opts.Region, opts.AccountID)},
+		})
+	}
+
+	if opts.HTTP {
+		statements = append(statements, &Statement{
+			Sid:       "LambdaManageJobs",
+			Subsystem: SubsystemLambda,
+			Actions:   []string{"ssm:PutParameter", "ssm:GetParameter", "ssm:DeleteParameter"},
+			Resources: []string{fmt.Sprintf("arn:%s:ssm:%s:%s:parameter/acme-dns-route53/jobs/*", partition, opts.Region, opts.AccountID)},
+		})
+	}
+
+	if len(opts.Queue) > 0 {
+		statements = append(statements, &Statement{
+			Sid:       "LambdaReceiveMessages",
+			Subsystem: SubsystemLambda,
+			Actions:   []string{"sqs:ReceiveMessage", "sqs:DeleteMessage", "sqs:GetQueueAttributes"},
+			Resources: []string{opts.Queue},
+		})
+	}
+
+	return statements
+}
+
+// functionARN returns the ARN of the Lambda function with the given name or ARN
+func functionARN(function, partition, region, accountID string) string {
+	if strings.HasPrefix(function, "arn:") {
+		return function
+	}
+
+	return fmt.Sprintf("arn:%s:lambda:%s:%s:function:%s", partition, region, accountID, function)
+}
+
+// recordConditions returns conditions limiting changes of Route 53 records if they are restricted, otherwise nil
+func recordConditions(opts *Options) map[string]map[string][]string {
+	if !opts.RestrictRecords {
+		return nil
+	}
+
+	var names []string
+	types := []string{"TXT"}
+	for _, group := range opts.Groups {
+		for _, domain := range group.Domains {
+			domain = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(domain, "*."), "."))
+			names = append(names, "_acme-challenge."+domain)
+
+			// CAA records are updated where the relevant record set is found, i.e. the domain or any of its parents
+			if group.CAA == config.CAAUpsert {
+				types = append(types, "CAA")
+				for labels := strings.Split(domain, "."); len(labels) >= 2; labels = labels[1:] {
+					names = append(names, strings.Join(labels, "."))
+				}
+			}
+		}
+	}
+
+	return map[string]map[string][]string{
+		conditionOperator: {
+			conditionRecordNames: unique(names),
+			conditionRecordTypes: unique(types),
+		},
+	}
+}
+
+// unique returns sorted non-empty values without duplicates
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if len(value) > 0 && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
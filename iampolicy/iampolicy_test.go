@@ -0,0 +1,196 @@
+package iampolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/config"
+)
+
+func TestRequired(t *testing.T) {
+	statements := Required(&Options{
+		AccountID: "123456789012",
+		Region:    "eu-west-1",
+		Groups: []*config.Group{
+			{
+				Stores:        []*config.Store{{Type: config.StoreTypeACM}, {Type: config.StoreTypeACM, Region: "us-east-1"}},
+				Notifications: []*config.Notification{{Type: config.NotificationTypeSNS, Topic: "arn:aws:sns:eu-west-1:123456789012:certs"}},
+			},
+			{
+				Stores:        []*config.Store{{Type: config.StoreTypeACM}},
+				Notifications: []*config.Notification{{Type: config.NotificationTypeSES, From: "certs@example.com", To: []string{"admin@example.com"}}},
+			},
+		},
+		HostedZoneIDs:  []string{"/hostedzone/Z2", "Z1", "/hostedzone/Z2"},
+		ConfigLocation: "ssm:/acme/config",
+	})
+
+	require.Equal(t, []*Statement{
+		{Sid: "Route53ListHostedZones", Subsystem: SubsystemRoute53, Actions: []string{"route53:ListHostedZones"}, Resources: []string{"*"}},
+		{Sid: "Route53ChangeRecords", Subsystem: SubsystemRoute53, Actions: []string{"route53:ChangeResourceRecordSets"}, Resources: []string{
+			"arn:aws:route53:::hostedzone/Z1",
+			"arn:aws:route53:::hostedzone/Z2",
+		}},
+		{Sid: "Route53GetChange", Subsystem: SubsystemRoute53, Actions: []string{"route53:GetChange"}, Resources: []string{"arn:aws:route53:::change/*"}},
+		{Sid: "ACMListCertificates", Subsystem: SubsystemACM, Actions: []string{"acm:ListCertificates"}, Resources: []string{"*"}},
+		{
+			Sid:       "ACMManageCertificates",
+			Subsystem: SubsystemACM,
+			Actions:   []string{"acm:ImportCertificate", "acm:DescribeCertificate", "acm:GetCertificate", "acm:AddTagsToCertificate", "acm:ListTagsForCertificate"},
+			Resources: []string{
+				"arn:aws:acm:eu-west-1:123456789012:certificate/*",
+				"arn:aws:acm:us-east-1:123456789012:certificate/*",
+			},
+		},
+		{Sid: "SNSPublish", Subsystem: SubsystemSNS, Actions: []string{"sns:Publish"}, Resources: []string{"arn:aws:sns:eu-west-1:123456789012:certs"}},
+		{Sid: "SESSendEmail", Subsystem: SubsystemSES, Actions: []string{"ses:SendEmail"}, Resources: []string{"arn:aws:ses:eu-west-1:123456789012:identity/*"}},
+		{Sid: "ConfigurationRead", Subsystem: SubsystemConfig, Actions: []string{"ssm:GetParameter"}, Resources: []string{"arn:aws:ssm:eu-west-1:123456789012:parameter/acme/config"}},
+	}, statements)
+}
+
+func TestRequiredRestrictRecords(t *testing.T) {
+	statements := Required(&Options{
+		Groups: []*config.Group{
+			{Domains: []string{"example.com", "*.example.com"}},
+			{Domains: []string{"API.example.org."}, CAA: config.CAAUpsert},
+		},
+		RestrictRecords: true,
+	})
+
+	require.Equal(t, "Route53ChangeRecords", statements[1].Sid)
+	require.Equal(t, []string{"arn:aws:route53:::hostedzone/*"}, statements[1].Resources)
+	require.Equal(t, map[string]map[string][]string{
+		"ForAllValues:StringEquals": {
+			"route53:ChangeResourceRecordSetsNormalizedRecordNames": {
+				"_acme-challenge.api.example.org",
+				"_acme-challenge.example.com",
+				"api.example.org",
+				"example.org",
+			},
+			"route53:ChangeResourceRecordSetsRecordTypes": {"CAA", "TXT"},
+		},
+	}, statements[1].Conditions)
+}
+
+func TestRequiredDeploy(t *testing.T) {
+	statements := Required(&Options{
+		AccountID: "123456789012",
+		Region:    "eu-west-1",
+		Groups: []*config.Group{
+			{
+				Deploy: []*config.Hook{
+					{Type: config.HookTypeCommand, Command: "systemctl reload haproxy"},
+					{Type: config.HookTypeLambda, Function: "deploy-certificate"},
+					{Type: config.HookTypeLambda, Function: "arn:aws:lambda:us-east-1:123456789012:function:edge"},
+					{Type: config.HookTypeSSM, Command: "systemctl reload haproxy", InstanceIDs: []string{"i-0123456789abcdef0"}},
+				},
+			},
+			{
+				Deploy: []*config.Hook{
+					{Type: config.HookTypeSSM, Command: "systemctl restart app", Targets: map[string][]string{"tag:Role": {"app"}}},
+					{Type: config.HookTypeEventBridge, Region: "us-east-1"},
+					{Type: config.HookTypeELBv2, Listener: "arn:aws:elasticloadbalancing:eu-west-1:123456789012:listener/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2"},
+					{Type: config.HookTypeCloudFront, Distribution: "E2QWRUHAPOMQZL"},
+					{Type: config.HookTypeAPIGateway, DomainName: "api.example.com"},
+				},
+			},
+		},
+	})
+
+	require.Equal(t, []*Statement{
+		{Sid: "DeployInvokeFunction", Subsystem: SubsystemDeploy, Actions: []string{"lambda:InvokeFunction"}, Resources: []string{
+			"arn:aws:lambda:eu-west-1:123456789012:function:deploy-certificate",
+			"arn:aws:lambda:us-east-1:123456789012:function:edge",
+		}},
+		{Sid: "DeploySendCommand", Subsystem: SubsystemDeploy, Actions: []string{"ssm:SendCommand"}, Resources: []string{
+			"arn:aws:ssm:eu-west-1::document/AWS-RunShellScript",
+			"arn:aws:ec2:eu-west-1:123456789012:instance/*",
+			"arn:aws:ec2:eu-west-1:123456789012:instance/i-0123456789abcdef0",
+		}},
+		{Sid: "DeployListCommands", Subsystem: SubsystemDeploy, Actions: []string{"ssm:ListCommands", "ssm:ListCommandInvocations"}, Resources: []string{"*"}},
+		{Sid: "DeployPutEvents", Subsystem: SubsystemDeploy, Actions: []string{"events:PutEvents"}, Resources: []string{"arn:aws:events:us-east-1:123456789012:event-bus/default"}},
+		{Sid: "DeployDescribeListenerCertificates", Subsystem: SubsystemDeploy, Actions: []string{"elasticloadbalancing:DescribeListenerCertificates"}, Resources: []string{"*"}},
+		{
+			Sid:       "DeployAttachListenerCertificates",
+			Subsystem: SubsystemDeploy,
+			Actions:   []string{"elasticloadbalancing:AddListenerCertificates", "elasticloadbalancing:ModifyListener"},
+			Resources: []string{"arn:aws:elasticloadbalancing:eu-west-1:123456789012:listener/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2"},
+		},
+		{Sid: "DeployUpdateDistributions", Subsystem: SubsystemDeploy, Actions: []string{"cloudfront:GetDistributionConfig", "cloudfront:UpdateDistribution"}, Resources: []string{"arn:aws:cloudfront::123456789012:distribution/E2QWRUHAPOMQZL"}},
+		{Sid: "DeployUpdateDomainNames", Subsystem: SubsystemDeploy, Actions: []string{"apigateway:GET", "apigateway:PATCH"}, Resources: []string{"arn:aws:apigateway:eu-west-1::/domainnames/api.example.com"}},
+	}, statements[4:])
+}
+
+func TestRequiredLambda(t *testing.T) {
+	testTable := []*struct {
+		testName     string
+		opts         *Options
+		expectedSids []string
+	}{
+		{
+			testName:     "defaults",
+			opts:         &Options{Function: "acme"},
+			expectedSids: nil,
+		},
+		{
+			testName:     "delete",
+			opts:         &Options{Delete: true},
+			expectedSids: []string{"ACMDeleteCertificates"},
+		},
+		{
+			testName:     "continue async",
+			opts:         &Options{Function: "acme", ContinueAsync: true},
+			expectedSids: []string{"LambdaInvokeItself"},
+		},
+		{
+			testName:     "http",
+			opts:         &Options{Function: "acme", HTTP: true},
+			expectedSids: []string{"LambdaInvokeItself", "LambdaManageJobs"},
+		},
+		{
+			testName:     "sqs",
+			opts:         &Options{Queue: "arn:aws:sqs:eu-west-1:123456789012:certificates"},
+			expectedSids: []string{"LambdaReceiveMessages"},
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			tt.opts.AccountID = "123456789012"
+			tt.opts.Region = "eu-west-1"
+			tt.opts.Groups = []*config.Group{{Stores: []*config.Store{{Type: config.StoreTypeACM}}}}
+
+			// Statements of Route 53 and ACM are required anyway
+			var sids []string
+			for _, statement := range Required(tt.opts)[5:] {
+				sids = append(sids, statement.Sid)
+			}
+
+			require.Equal(t, tt.expectedSids, sids)
+		})
+	}
+
+	statements := Required(&Options{
+		AccountID:     "123456789012",
+		Region:        "eu-west-1",
+		Groups:        []*config.Group{{Stores: []*config.Store{{Type: config.StoreTypeACM}}}},
+		Delete:        true,
+		Function:      "acme",
+		ContinueAsync: true,
+		HTTP:          true,
+		Queue:         "arn:aws:sqs:eu-west-1:123456789012:certificates",
+	})
+
+	require.Equal(t, []*Statement{
+		{Sid: "ACMDeleteCertificates", Subsystem: SubsystemACM, Actions: []string{"acm:DeleteCertificate"}, Resources: []string{"arn:aws:acm:eu-west-1:123456789012:certificate/*"}},
+		{Sid: "LambdaInvokeItself", Subsystem: SubsystemLambda, Actions: []string{"lambda:InvokeFunction"}, Resources: []string{"arn:aws:lambda:eu-west-1:123456789012:function:acme"}},
+		{
+			Sid:       "LambdaManageJobs",
+			Subsystem: SubsystemLambda,
+			Actions:   []string{"ssm:PutParameter", "ssm:GetParameter", "ssm:DeleteParameter"},
+			Resources: []string{"arn:aws:ssm:eu-west-1:123456789012:parameter/acme-dns-route53/jobs/*"},
+		},
+		{Sid: "LambdaReceiveMessages", Subsystem: SubsystemLambda, Actions: []string{"sqs:ReceiveMessage", "sqs:DeleteMessage", "sqs:GetQueueAttributes"}, Resources: []string{"arn:aws:sqs:eu-west-1:123456789012:certificates"}},
+	}, statements[5:])
+}
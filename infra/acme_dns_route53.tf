@@ -143,7 +143,7 @@ resource "aws_iam_role" "lambda_acme_dns_route53_executor" {
 EOF
 }
 
-# Provides permissions to the lambda
+# Provides permissions to the lambda, use `acme-dns-route53 iam-policy` to generate the policy limited to the hosted zones of the domains
 # - Write permissions to CloudWatch logs
 # - Permissions to read and import certificates to ACM
 # - Permissions to create and delete records in Route53
@@ -172,31 +172,51 @@ resource "aws_iam_role_policy" "lambda_acme_dns_route53_executor" {
       "Resource": "arn:aws:logs:${var.region}:${var.account_id}:log-group:/aws/lambda/${local.acme_dns_route53_function_name}:*"
     },
     {
-      "Sid": "",
+      "Sid": "ListResources",
       "Effect": "Allow",
       "Action": [
         "route53:ListHostedZones",
         "cloudwatch:PutMetricData",
-        "acm:ImportCertificate",
         "acm:ListCertificates"
       ],
       "Resource": "*"
     },
     {
-      "Sid": "",
+      "Sid": "Route53ChangeRecords",
+      "Effect": "Allow",
+      "Action": [
+        "route53:ChangeResourceRecordSets"
+      ],
+      "Resource": "arn:aws:route53:::hostedzone/*"
+    },
+    {
+      "Sid": "Route53GetChange",
+      "Effect": "Allow",
+      "Action": [
+        "route53:GetChange"
+      ],
+      "Resource": "arn:aws:route53:::change/*"
+    },
+    {
+      "Sid": "ACMManageCertificates",
       "Effect": "Allow",
       "Action": [
-        "route53:GetChange",
-        "route53:ChangeResourceRecordSets",
         "acm:ImportCertificate",
-        "acm:DescribeCertificate"
+        "acm:DescribeCertificate",
+        "acm:GetCertificate",
+        "acm:DeleteCertificate",
+        "acm:AddTagsToCertificate",
+        "acm:ListTagsForCertificate"
+      ],
+      "Resource": "arn:aws:acm:${var.region}:${var.account_id}:certificate/*"
+    },
+    {
+      "Sid": "SNSPublish",
+      "Effect": "Allow",
+      "Action": [
+        "sns:Publish"
       ],
-      "Resource": [
-        "arn:aws:sns:${var.region}:${var.account_id}:*",
-        "arn:aws:route53:::hostedzone/*",
-        "arn:aws:route53:::change/*",
-        "arn:aws:acm:${var.region}:${var.account_id}:certificate/*"
-      ]
+      "Resource": "arn:aws:sns:${var.region}:${var.account_id}:${local.acme_dns_route53_sns_topic}"
     }
   ]
 }
@@ -0,0 +1,45 @@
+package lambda
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/audit"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// auditCertificates notifies certificates of the stores and the configured groups approaching expiry.
+// The response is returned along with the error if any source could not be scanned,
+// expiring certificates do not fail the invocation since they are notified.
+func auditCertificates(conf *Config, log *logrus.Logger) (*Response, error) {
+	groupsConf, err := loadConfig(conf, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply top-level defaults
+	defaults := groupsConf.NewGroup(nil)
+
+	opts := newRunnerOptions(conf, log)
+
+	report := audit.New(&audit.Options{
+		Session:    AWSSession,
+		Jobs:       runner.NewJobs(groupsConf.Groups, opts),
+		Stores:     defaults.Stores,
+		Targets:    runner.NewGroupTargets(defaults, opts),
+		Thresholds: groupsConf.Expiry.Thresholds,
+		Endpoints:  groupsConf.Expiry.Endpoints,
+		ConfigDir:  conf.ConfigDir,
+		DryRun:     conf.DryRun,
+		Log:        log,
+	}).Run()
+
+	log.Info(report.String())
+
+	resp := &Response{Action: ActionAudit, Status: StatusSucceeded, Audit: report}
+	if len(report.Errors) > 0 {
+		resp.Status = StatusPartiallyFailed
+		return resp, report.Err()
+	}
+
+	return resp, nil
+}
@@ -4,11 +4,29 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
 	// DefaultRenewBefore is the default value of RENEW_BEFORE env var
 	DefaultRenewBefore = 30
+
+	// DefaultDeadlineThreshold is the default value of DEADLINE_THRESHOLD env var
+	DefaultDeadlineThreshold = 180 * time.Second
+)
+
+const (
+	// ActionObtain is the action which obtains new certificates or renews existing ones
+	ActionObtain = "obtain"
+
+	// ActionRevoke is the action which revokes existing certificates
+	ActionRevoke = "revoke"
+
+	// ActionRenew is the action which renews existing certificates only
+	ActionRenew = "renew"
+
+	// ActionAudit is the action which notifies certificates approaching expiry regardless of their issuance
+	ActionAudit = "audit"
 )
 
 const (
@@ -26,15 +44,56 @@ const (
 
 	// RenewBeforeEnvVar is the name of env var which contains the number of days defining the period before expiration within which a certificate must be renewed
 	RenewBeforeEnvVar = "RENEW_BEFORE"
+
+	// DisableARIEnvVar is the name of env var which contains 1 value for ignoring ACME Renewal Information
+	DisableARIEnvVar = "DISABLE_ARI"
+
+	// CAAEnvVar is the name of env var which contains the mode of CAA records verification: check, upsert or disabled
+	CAAEnvVar = "CAA"
+
+	// ConfigDirEnvVar is the name of env var which contains the directory of account keys and notification state,
+	// e.g. a mounted EFS file system to keep them between invocations
+	ConfigDirEnvVar = "CONFIG_DIR"
+
+	// ConfigLocationEnvVar is the name of env var which contains the location of the configuration file
+	ConfigLocationEnvVar = "CONFIG_LOCATION"
+
+	// ParallelismEnvVar is the name of env var which contains the maximum number of certificate groups processed at once
+	ParallelismEnvVar = "PARALLELISM"
+
+	// DeadlineThresholdEnvVar is the name of env var which contains the number of seconds before the function timeout
+	// within which new certificate orders are not started
+	DeadlineThresholdEnvVar = "DEADLINE_THRESHOLD"
+
+	// ContinueAsyncEnvVar is the name of env var which contains 1 value for passing unprocessed groups
+	// to the next asynchronous invocation of the function
+	ContinueAsyncEnvVar = "CONTINUE_ASYNC"
 )
 
 // Config contains configuration data
 type Config struct {
-	Domains     []string
-	Email       string
-	Staging     bool
-	Topic       string
-	RenewBefore int
+	Action         string
+	ConfigLocation string
+	ConfigDir      string
+	Groups         []string
+	Certificate    []string
+	Domains        []string
+	Email          string
+	Staging        bool
+	Topic          string
+	RenewBefore    int
+	CAA            string
+	Reason         string
+	Delete         bool
+	Replace        bool
+	Force          bool
+	Discovered     [][]string
+	DisableARI     bool
+	DryRun         bool
+	Parallelism    int
+
+	DeadlineThreshold time.Duration
+	ContinueAsync     bool
 }
 
 // InitConfig initializes configuration of the lambda function
@@ -44,12 +103,50 @@ func InitConfig(payload Payload) *Config {
 		renewBefore = DefaultRenewBefore
 	}
 
+	parallelism, _ := strconv.Atoi(os.Getenv(ParallelismEnvVar))
+
+	deadlineThreshold := DefaultDeadlineThreshold
+	if seconds, err := strconv.Atoi(os.Getenv(DeadlineThresholdEnvVar)); err == nil && seconds >= 0 {
+		deadlineThreshold = time.Duration(seconds) * time.Second
+	}
+
 	config := &Config{
-		Domains:     strings.Split(os.Getenv(DomainsEnvVar), ","),
-		Email:       os.Getenv(LetsEncryptEnvVar),
-		Staging:     isStaging(os.Getenv(StagingEnvVar)),
-		Topic:       os.Getenv(TopicEnvVar),
-		RenewBefore: renewBefore,
+		Action:         ActionObtain,
+		ConfigLocation: os.Getenv(ConfigLocationEnvVar),
+		ConfigDir:      os.Getenv(ConfigDirEnvVar),
+		Groups:         payload.Groups,
+		Certificate:    payload.Certificate,
+		Domains:        splitDomains(os.Getenv(DomainsEnvVar)),
+		Email:          os.Getenv(LetsEncryptEnvVar),
+		Staging:        isStaging(os.Getenv(StagingEnvVar)),
+		Topic:          os.Getenv(TopicEnvVar),
+		RenewBefore:    renewBefore,
+		CAA:            os.Getenv(CAAEnvVar),
+		DisableARI:     os.Getenv(DisableARIEnvVar) == "1" || payload.DisableARI,
+		Reason:         payload.Reason,
+		Delete:         payload.Delete,
+		Replace:        payload.Replace,
+		Force:          payload.Force,
+		Discovered:     payload.Discovered,
+		DryRun:         payload.DryRun,
+		Parallelism:    parallelism,
+
+		DeadlineThreshold: deadlineThreshold,
+		ContinueAsync:     os.Getenv(ContinueAsyncEnvVar) == "1",
+	}
+
+	if len(config.ConfigDir) == 0 {
+		config.ConfigDir = ConfigDir
+	}
+
+	// Load action
+	if len(payload.Action) > 0 {
+		config.Action = payload.Action
+	}
+
+	// Load configuration file location
+	if len(payload.Config) > 0 {
+		config.ConfigLocation = payload.Config
 	}
 
 	// Load domains
@@ -72,6 +169,11 @@ func InitConfig(payload Payload) *Config {
 		config.Topic = payload.Topic
 	}
 
+	// Load parallelism
+	if payload.Parallelism > 0 {
+		config.Parallelism = payload.Parallelism
+	}
+
 	// Load renew before days value
 	if payload.RenewBefore > 0 {
 		config.RenewBefore = payload.RenewBefore
@@ -80,6 +182,15 @@ func InitConfig(payload Payload) *Config {
 	return config
 }
 
+// splitDomains splits the given comma-separated domains list
+func splitDomains(val string) []string {
+	if len(val) == 0 {
+		return nil
+	}
+
+	return strings.Split(val, ",")
+}
+
 func isStaging(val string) bool {
 	return val == "1"
 }
@@ -0,0 +1,175 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go/aws"
+	awslambda "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+const (
+	// cleanUpMargin is the time before the function timeout reserved for removing challenge records of in-flight orders
+	cleanUpMargin = 10 * time.Second
+
+	// maxContinuations is the maximum number of invocations continuing the original one,
+	// it prevents endless invocations if a group is never processed in time
+	maxContinuations = 10
+)
+
+// ErrTooManyContinuations is the error when unprocessed groups are not passed to the next invocation to avoid endless invocations
+var ErrTooManyContinuations = errors.New("too many continuations")
+
+// batch is the list of jobs processed by one invocation
+type batch struct {
+	jobs        []*runner.Job
+	parallelism int
+	fn          runner.JobFunc
+}
+
+// run processes the given batch within the deadline of the given context.
+// New orders are not started when the remaining time is below the configured threshold.
+// If in-flight orders do not finish in time, their challenge records are removed and their groups are reported as unprocessed.
+func run(ctx context.Context, conf *Config, b *batch, log *logrus.Logger) *runner.Summary {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return runner.Run(ctx, b.jobs, b.parallelism, b.fn)
+	}
+
+	startCtx, cancel := context.WithDeadline(ctx, deadline.Add(-conf.DeadlineThreshold))
+	defer cancel()
+
+	t := &tracker{results: make(map[*runner.Job]*runner.Result, len(b.jobs))}
+
+	finished := make(chan *runner.Summary, 1)
+	go func() {
+		finished <- runner.Run(startCtx, b.jobs, b.parallelism, t.wrap(b.fn))
+	}()
+
+	abort := time.NewTimer(time.Until(deadline.Add(-cleanUpMargin)))
+	defer abort.Stop()
+
+	select {
+	case summary := <-finished:
+		return summary
+	case <-abort.C:
+	}
+
+	log.Warnf("lambda: in-flight orders did not finish before the function timeout, removing their challenge records")
+
+	for _, job := range b.jobs {
+		if err := job.DNS01.CleanUpPending(); err != nil {
+			log.Errorf("[%s] lambda: unable to clean up DNS records: %s", job.DomainsString(), err)
+		}
+	}
+
+	return t.summary(b.jobs)
+}
+
+// tracker collects results of finished jobs
+type tracker struct {
+	lock    sync.Mutex
+	results map[*runner.Job]*runner.Result
+}
+
+// wrap returns the function which records the result of the given function
+func (t *tracker) wrap(fn runner.JobFunc) runner.JobFunc {
+	return func(job *runner.Job) (*handler.Result, error) {
+		start := time.Now()
+		result, err := fn(job)
+
+		t.lock.Lock()
+		t.results[job] = runner.NewResult(job, result, err, time.Since(start))
+		t.lock.Unlock()
+
+		return result, err
+	}
+}
+
+// summary returns the summary of the given jobs, the jobs which are not finished yet are reported as unprocessed
+func (t *tracker) summary(jobs []*runner.Job) *runner.Summary {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	results := make([]*runner.Result, len(jobs))
+	for i, job := range jobs {
+		if results[i] = t.results[job]; results[i] == nil {
+			results[i] = runner.UnprocessedResult(job)
+		}
+	}
+
+	return runner.NewSummary(results)
+}
+
+// continueAsync invokes the function asynchronously with the given payload changed to process the given unprocessed groups only
+func continueAsync(ctx context.Context, payload Payload, conf *Config, unprocessed []*runner.Result) error {
+	next, err := nextPayload(payload, conf, unprocessed)
+	if err != nil {
+		return err
+	}
+
+	return invokeAsync(ctx, next)
+}
+
+// nextPayload returns the payload of the invocation continuing the one with the given payload,
+// it processes the given unprocessed groups only
+func nextPayload(payload Payload, conf *Config, unprocessed []*runner.Result) (Payload, error) {
+	if payload.Continuation >= maxContinuations {
+		return Payload{}, ErrTooManyContinuations
+	}
+
+	next := payload
+	next.Continuation++
+
+	switch {
+	case len(conf.Certificate) > 0:
+		// The only group is unprocessed
+	case len(conf.ConfigLocation) > 0:
+		next.Groups = nil
+		for _, result := range unprocessed {
+			next.Groups = append(next.Groups, result.Group)
+		}
+	case len(conf.Domains) > 0:
+		next.Domains = nil
+		for _, result := range unprocessed {
+			next.Domains = append(next.Domains, result.Domains...)
+		}
+	default:
+		// Discovered certificates are passed explicitly, otherwise forced renewal would renew processed ones again
+		next.Discovered = nil
+		for _, result := range unprocessed {
+			next.Discovered = append(next.Discovered, result.Domains)
+		}
+	}
+
+	return next, nil
+}
+
+// invokeAsync invokes the function asynchronously with the given payload
+func invokeAsync(ctx context.Context, payload Payload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	functionName := lambdacontext.FunctionName
+	if lc, ok := lambdacontext.FromContext(ctx); ok && len(lc.InvokedFunctionArn) > 0 {
+		functionName = lc.InvokedFunctionArn
+	}
+
+	_, err = awslambda.New(AWSSession).Invoke(&awslambda.InvokeInput{
+		FunctionName:   aws.String(functionName),
+		InvocationType: aws.String(awslambda.InvocationTypeEvent),
+		Payload:        data,
+	})
+
+	return err
+}
@@ -0,0 +1,49 @@
+package lambda
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+func TestNextPayloadForcedDiscovery(t *testing.T) {
+	jobs := []*runner.Job{
+		{Group: &config.Group{Name: "first", Domains: []string{"example.com", "www.example.com"}}},
+		{Group: &config.Group{Name: "second", Domains: []string{"example.org"}}},
+		{Group: &config.Group{Name: "third", Domains: []string{"a.example.net", "b.example.net"}}},
+	}
+
+	// The first group is renewed before the deadline, the rest are not processed in time
+	tr := &tracker{results: map[*runner.Job]*runner.Result{
+		jobs[0]: runner.NewResult(jobs[0], &handler.Result{Outcome: handler.OutcomeRenewed}, nil, time.Second),
+	}}
+	summary := tr.summary(jobs)
+	require.Equal(t, 2, summary.Unprocessed)
+
+	payload := Payload{Action: ActionRenew, Force: true}
+	conf := InitConfig(payload)
+
+	next, err := nextPayload(payload, conf, summary.UnprocessedResults())
+	require.NoError(t, err)
+	require.True(t, next.Force)
+	require.Equal(t, 1, next.Continuation)
+	require.Equal(t, [][]string{{"example.org"}, {"a.example.net", "b.example.net"}}, next.Discovered)
+
+	// The continuing invocation passes on the rest of its own domain sets only
+	conf = InitConfig(next)
+	require.Equal(t, next.Discovered, conf.Discovered)
+
+	next, err = nextPayload(next, conf, summary.UnprocessedResults()[1:])
+	require.NoError(t, err)
+	require.Equal(t, 2, next.Continuation)
+	require.Equal(t, [][]string{{"a.example.net", "b.example.net"}}, next.Discovered)
+
+	next.Continuation = maxContinuations
+	_, err = nextPayload(next, conf, summary.UnprocessedResults())
+	require.Equal(t, ErrTooManyContinuations, err)
+}
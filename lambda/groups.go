@@ -0,0 +1,147 @@
+package lambda
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// loadConfig loads the configuration file and selects the configured groups.
+// Without configuration file, the configuration is built from the function settings with a separate group for each domain.
+// The certificate setting defines the only group with all its domains, the configuration file provides its defaults then.
+// The configuration without groups is returned as is if allowEmpty is true.
+// The policy of the configuration file of the function settings applies regardless of the payload.
+func loadConfig(conf *Config, allowEmpty bool) (*config.Config, error) {
+	groupsConf, err := loadGroupsConfig(conf, allowEmpty)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := functionPolicy(conf)
+	if err != nil || policy == nil {
+		return groupsConf, err
+	}
+
+	groupsConf.Policy = policy
+	if len(groupsConf.Groups) == 0 {
+		return groupsConf, nil
+	}
+
+	if err := groupsConf.Init(); err != nil {
+		return nil, err
+	}
+
+	return groupsConf, nil
+}
+
+// functionPolicy returns the policy of the configuration file of the function settings
+// if the payload uses another configuration, otherwise nil
+func functionPolicy(conf *Config) (*config.Policy, error) {
+	location := os.Getenv(ConfigLocationEnvVar)
+	if len(location) == 0 || location == conf.ConfigLocation {
+		return nil, nil
+	}
+
+	functionConf, err := config.Load(location, AWSSession)
+	if err != nil {
+		return nil, errors.Wrap(err, "lambda: unable to load policy of the function configuration")
+	}
+
+	return functionConf.Policy, nil
+}
+
+// loadGroupsConfig loads the configuration of groups given by the payload or the function settings
+func loadGroupsConfig(conf *Config, allowEmpty bool) (*config.Config, error) {
+	if len(conf.ConfigLocation) > 0 {
+		groupsConf, err := config.Load(conf.ConfigLocation, AWSSession)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(conf.Certificate) > 0 {
+			groupsConf.Groups = []*config.Group{groupsConf.NewGroup(conf.Certificate)}
+			if err := groupsConf.Init(); err != nil {
+				return nil, err
+			}
+		} else if groupsConf.Groups, err = groupsConf.GroupsByName(conf.Groups); err != nil {
+			return nil, err
+		}
+
+		// Staging environment requested by the payload switches all groups to it
+		if conf.Staging {
+			for _, group := range groupsConf.Groups {
+				group.CA = config.CAStaging
+			}
+		}
+
+		return groupsConf, nil
+	}
+
+	// Email must be filled
+	if len(conf.Email) == 0 {
+		return nil, ErrEmailMissing
+	}
+
+	groupsConf := &config.Config{
+		Email:       conf.Email,
+		RenewBefore: conf.RenewBefore,
+		CAA:         conf.CAA,
+	}
+
+	if conf.Staging {
+		groupsConf.CA = config.CAStaging
+	}
+
+	if len(conf.Topic) > 0 {
+		groupsConf.Notifications = []*config.Notification{{Type: config.NotificationTypeSNS, Topic: conf.Topic}}
+	}
+
+	if len(conf.Certificate) > 0 {
+		groupsConf.Groups = []*config.Group{groupsConf.NewGroup(conf.Certificate)}
+	} else {
+		// Each domain is a separate certificate
+		for _, domain := range conf.Domains {
+			groupsConf.Groups = append(groupsConf.Groups, groupsConf.NewGroup([]string{domain}))
+		}
+	}
+
+	if len(groupsConf.Groups) == 0 {
+		if allowEmpty {
+			return groupsConf, nil
+		}
+
+		return nil, ErrDomainsMissing
+	}
+
+	if err := groupsConf.Init(); err != nil {
+		return nil, err
+	}
+
+	return groupsConf, nil
+}
+
+// newRunnerOptions creates options of certificate handlers
+func newRunnerOptions(conf *Config, log *logrus.Logger) *runner.Options {
+	return &runner.Options{
+		Session:    AWSSession,
+		ConfigDir:  conf.ConfigDir,
+		DisableARI: conf.DisableARI,
+		DryRun:     conf.DryRun,
+		Log:        log,
+
+		DisableCommands: true,
+	}
+}
+
+// getParallelism returns the number of certificate groups processed at once given by the function settings or the configuration
+func getParallelism(conf *Config, groupsConf *config.Config) int {
+	if conf.Parallelism > 0 {
+		return conf.Parallelism
+	}
+
+	return groupsConf.Parallelism
+}
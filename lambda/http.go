@@ -0,0 +1,351 @@
+package lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+const (
+	// CallerHostedZonesEnvVar is the name of env var which contains the JSON object mapping ARNs of IAM principals
+	// to names of hosted zones which domains of certificates requested by them over HTTP may belong to
+	CallerHostedZonesEnvVar = "CALLER_HOSTED_ZONES"
+
+	// httpEventVersion is the payload format version of HTTP requests of API Gateway HTTP API and function URLs
+	httpEventVersion = "2.0"
+
+	// certificatesPath is the path of the certificates resource
+	certificatesPath = "/certificates"
+
+	// issueTimeout is the time after which the requested certificate is considered failed if it is still not issued
+	issueTimeout = 15 * time.Minute
+)
+
+const (
+	// StatusPending is the status of the requested certificate which is being obtained
+	StatusPending = "pending"
+
+	// StatusIssued is the status of the requested certificate which is stored
+	StatusIssued = "issued"
+)
+
+// CertificateRequest is the body of the HTTP request of a certificate
+type CertificateRequest struct {
+	Domains []string `json:"domains"`
+	Email   string   `json:"email"`
+	Staging bool     `json:"staging"`
+}
+
+// CertificateResponse is the body of the HTTP response with the status of the requested certificate
+type CertificateResponse struct {
+	JobID          string     `json:"job_id,omitempty"`
+	Status         string     `json:"status"`
+	Domains        []string   `json:"domains"`
+	CertificateARN string     `json:"certificate_arn,omitempty"`
+	Expiry         *time.Time `json:"expiry,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// errorResponse is the body of the HTTP response with the error
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// HandleHTTPRequest handles the HTTP request of API Gateway HTTP API or the function URL:
+// - POST /certificates requests the certificate for the domains of the body,
+// the existing valid certificate is returned at once, otherwise obtaining is started asynchronously and the job ID is returned,
+// the job in flight for the same certificate is returned instead of starting another one;
+// - GET /certificates/{job_id} returns the status of the requested certificate.
+// Requests must be signed by the IAM principal with the given ARN, i.e. the route must use AWS_IAM authorization.
+// Domains must belong to the hosted zones allowed for the caller by CALLER_HOSTED_ZONES env var.
+func HandleHTTPRequest(ctx context.Context, req events.APIGatewayV2HTTPRequest, callerARN string) (events.APIGatewayV2HTTPResponse, error) {
+	path := strings.TrimSuffix(req.RawPath, "/")
+
+	if len(callerARN) == 0 {
+		return jsonResponse(http.StatusUnauthorized, &errorResponse{Error: "requests must be signed, use AWS_IAM authorization"})
+	}
+
+	secret, err := jobSecret()
+	if err != nil {
+		logrus.Errorf("lambda: %s", err)
+		return jsonResponse(http.StatusInternalServerError, &errorResponse{Error: "certificate requests are not configured"})
+	}
+
+	zones, err := callerHostedZones(callerARN)
+	if err != nil {
+		logrus.Errorf("lambda: %s", err)
+		return jsonResponse(http.StatusInternalServerError, &errorResponse{Error: "certificate requests are not configured"})
+	}
+
+	if len(zones) == 0 {
+		logrus.Warnf("lambda: '%s' is not allowed to request certificates", callerARN)
+		return jsonResponse(http.StatusForbidden, &errorResponse{Error: "caller is not allowed to request certificates"})
+	}
+
+	switch method := req.RequestContext.HTTP.Method; {
+	case method == http.MethodPost && strings.HasSuffix(path, certificatesPath):
+		return requestCertificate(ctx, req.Body, req.IsBase64Encoded, callerARN, zones, secret)
+	case method == http.MethodGet && strings.Contains(path, certificatesPath+"/"):
+		return certificateStatus(path[strings.LastIndex(path, "/")+1:], zones, secret)
+	default:
+		return jsonResponse(http.StatusNotFound, &errorResponse{Error: "route not found"})
+	}
+}
+
+// callerARN returns the ARN of the IAM principal which signed the given HTTP request event, empty if it is not authorized by IAM
+func callerARN(event []byte) string {
+	var probe struct {
+		RequestContext struct {
+			Authorizer struct {
+				IAM struct {
+					UserARN string `json:"userArn"`
+				} `json:"iam"`
+			} `json:"authorizer"`
+		} `json:"requestContext"`
+	}
+
+	if err := json.Unmarshal(event, &probe); err != nil {
+		return ""
+	}
+
+	return probe.RequestContext.Authorizer.IAM.UserARN
+}
+
+// requestCertificate validates the certificate request and starts obtaining the certificate if there is no valid one,
+// domains must belong to the given hosted zones allowed for the caller
+func requestCertificate(ctx context.Context, body string, isBase64Encoded bool, callerARN string, zones []string, secret []byte) (events.APIGatewayV2HTTPResponse, error) {
+	if isBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return jsonResponse(http.StatusBadRequest, &errorResponse{Error: "invalid body encoding"})
+		}
+		body = string(decoded)
+	}
+
+	var certReq CertificateRequest
+	if err := json.Unmarshal([]byte(body), &certReq); err != nil {
+		return jsonResponse(http.StatusBadRequest, &errorResponse{Error: "invalid JSON body: " + err.Error()})
+	}
+
+	if len(certReq.Domains) == 0 {
+		return jsonResponse(http.StatusBadRequest, &errorResponse{Error: "domains must not be empty"})
+	}
+
+	if denied := deniedDomains(certReq.Domains, zones); len(denied) > 0 {
+		return jsonResponse(http.StatusForbidden, &errorResponse{Error: "domains are not in the allowed hosted zones: " + strings.Join(denied, ", ")})
+	}
+
+	payload := Payload{
+		Action:      ActionObtain,
+		Certificate: certReq.Domains,
+		Email:       certReq.Email,
+	}
+	if certReq.Staging {
+		payload.Staging = "1"
+	}
+
+	job, err := newHTTPJob(payload)
+	if err != nil {
+		return jsonResponse(http.StatusBadRequest, &errorResponse{Error: err.Error()})
+	}
+
+	if err := job.Handler.CheckPolicy(job.Group.Domains); err != nil {
+		return jsonResponse(http.StatusForbidden, &errorResponse{Error: err.Error()})
+	}
+
+	// Return the existing certificate if it does not need renewal, decided as by the invocation obtaining it
+	existing, renew, err := job.Handler.NeedsRenewal(job.Group.Domains)
+	if err != nil {
+		return jsonResponse(http.StatusBadGateway, &errorResponse{Error: err.Error()})
+	}
+
+	if !renew {
+		return jsonResponse(http.StatusOK, &CertificateResponse{
+			Status:         StatusIssued,
+			Domains:        job.Group.Domains,
+			CertificateARN: existing.ID,
+			Expiry:         &existing.NotAfter,
+		})
+	}
+
+	j := &httpJob{
+		Domains:     job.Group.Domains,
+		Staging:     certReq.Staging,
+		RequestedAt: time.Now().Unix(),
+	}
+
+	jobID, err := encodeJobID(j, secret)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+
+	store := &jobStore{ssm: ssm.New(AWSSession), secret: secret}
+
+	jobID, reused, err := store.reserve(j, jobID)
+	if err != nil {
+		logrus.Errorf("[%s] lambda: unable to record job: %s", job.DomainsString(), err)
+		return jsonResponse(http.StatusBadGateway, &errorResponse{Error: "unable to record job"})
+	}
+
+	if reused {
+		logrus.Infof("[%s] lambda: certificate requested by '%s' is already being obtained", job.DomainsString(), callerARN)
+	} else {
+		logrus.Infof("[%s] lambda: certificate requested by '%s'", job.DomainsString(), callerARN)
+
+		// Obtaining takes longer than API Gateway waits for the response, so it is done by another invocation
+		if err := invokeAsync(ctx, payload); err != nil {
+			logrus.Errorf("[%s] lambda: unable to start obtaining certificate: %s", job.DomainsString(), err)
+
+			if err := store.release(j, jobID); err != nil {
+				logrus.Errorf("[%s] lambda: unable to remove job: %s", job.DomainsString(), err)
+			}
+
+			return jsonResponse(http.StatusBadGateway, &errorResponse{Error: "unable to start obtaining certificate"})
+		}
+	}
+
+	return jsonResponse(http.StatusAccepted, &CertificateResponse{
+		JobID:   jobID,
+		Status:  StatusPending,
+		Domains: job.Group.Domains,
+	})
+}
+
+// certificateStatus returns the status of the certificate requested by the job with the given ID,
+// domains of the job must belong to the given hosted zones allowed for the caller
+func certificateStatus(jobID string, zones []string, secret []byte) (events.APIGatewayV2HTTPResponse, error) {
+	j, err := decodeJobID(jobID, secret)
+	if err != nil {
+		return jsonResponse(http.StatusNotFound, &errorResponse{Error: "job not found"})
+	}
+
+	// The job may have been requested by another caller, or the allowed hosted zones may have changed since then
+	if denied := deniedDomains(j.Domains, zones); len(denied) > 0 {
+		return jsonResponse(http.StatusForbidden, &errorResponse{Error: "domains are not in the allowed hosted zones: " + strings.Join(denied, ", ")})
+	}
+
+	payload := Payload{Action: ActionObtain, Certificate: j.Domains}
+	if j.Staging {
+		payload.Staging = "1"
+	}
+
+	job, err := newHTTPJob(payload)
+	if err != nil {
+		return jsonResponse(http.StatusBadRequest, &errorResponse{Error: err.Error()})
+	}
+
+	existing, err := job.Handler.Existing(job.Group.Domains)
+	if err != nil {
+		return jsonResponse(http.StatusBadGateway, &errorResponse{Error: err.Error()})
+	}
+
+	resp := &CertificateResponse{
+		JobID:   jobID,
+		Status:  StatusPending,
+		Domains: j.Domains,
+	}
+
+	requestedAt := time.Unix(j.RequestedAt, 0)
+
+	switch {
+	case existing != nil && !existing.NotBefore.Before(requestedAt.Add(-time.Hour)):
+		// Let's Encrypt backdates certificates by an hour
+		resp.Status = StatusIssued
+		resp.CertificateARN = existing.ID
+		resp.Expiry = &existing.NotAfter
+	case time.Since(requestedAt) > issueTimeout:
+		resp.Status = StatusFailed
+		resp.Error = "certificate has not been issued in time, see logs of the function"
+	}
+
+	return jsonResponse(http.StatusOK, resp)
+}
+
+// newHTTPJob creates the job of the certificate requested by HTTP, replaced by tests
+var newHTTPJob = createHTTPJob
+
+// createHTTPJob creates the job of the only group of the given payload with the defaults of the function settings
+func createHTTPJob(payload Payload) (*runner.Job, error) {
+	conf := InitConfig(payload)
+
+	groupsConf, err := loadConfig(conf, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.NewJob(groupsConf.Groups[0], newRunnerOptions(conf, logrus.New())), nil
+}
+
+// callerHostedZones returns names of hosted zones allowed for HTTP requests of the IAM principal with the given ARN,
+// empty if the caller is not allowed to request certificates.
+// Keys ending with '*' match ARNs by prefix, e.g. sessions of the assumed role 'arn:aws:sts::123456789012:assumed-role/pipeline/*'.
+func callerHostedZones(callerARN string) ([]string, error) {
+	value := os.Getenv(CallerHostedZonesEnvVar)
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var callers map[string][]string
+	if err := json.Unmarshal([]byte(value), &callers); err != nil {
+		return nil, errors.Wrapf(err, "lambda: invalid %s, expected JSON object mapping ARNs to hosted zones", CallerHostedZonesEnvVar)
+	}
+
+	if zones, ok := callers[callerARN]; ok {
+		return zones, nil
+	}
+
+	var zones []string
+	for arn, callerZones := range callers {
+		if strings.HasSuffix(arn, "*") && strings.HasPrefix(callerARN, strings.TrimSuffix(arn, "*")) {
+			zones = append(zones, callerZones...)
+		}
+	}
+
+	return zones, nil
+}
+
+// deniedDomains returns the given domains which do not belong to any of the given hosted zones
+func deniedDomains(domains, zones []string) []string {
+	var denied []string
+	for _, domain := range domains {
+		allowed := false
+		for _, zone := range zones {
+			if config.InZone(domain, zone) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			denied = append(denied, domain)
+		}
+	}
+
+	return denied
+}
+
+// jsonResponse creates the HTTP response with the given status code and JSON encoded body
+func jsonResponse(statusCode int, body interface{}) (events.APIGatewayV2HTTPResponse, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(data),
+	}, nil
+}
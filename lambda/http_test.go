@@ -0,0 +1,306 @@
+package lambda
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/go-acme/lego/certificate"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// fakeCertStore contains the only certificate and fails on any change
+type fakeCertStore struct {
+	cert *certstore.CertificateDetails
+}
+
+func (s *fakeCertStore) Store(*certificate.Resource, []string) ([]string, error) {
+	panic("certificate must not be stored")
+}
+
+func (s *fakeCertStore) Delete([]string) error {
+	panic("certificate must not be deleted")
+}
+
+func (s *fakeCertStore) List() ([]*certstore.CertificateDetails, error) {
+	return []*certstore.CertificateDetails{s.cert}, nil
+}
+
+func (s *fakeCertStore) Load([]string) (*certstore.CertificateDetails, error) {
+	return s.cert, nil
+}
+
+func TestDeniedDomains(t *testing.T) {
+	zones := []string{"example.com", "example.org."}
+
+	denied := deniedDomains([]string{
+		"example.com",
+		"*.api.example.com",
+		"www.example.org",
+		"example.net",
+		"badexample.com",
+	}, zones)
+
+	require.Equal(t, []string{"example.net", "badexample.com"}, denied)
+}
+
+func TestCallerHostedZones(t *testing.T) {
+	os.Setenv(CallerHostedZonesEnvVar, `{
+		"arn:aws:iam::123456789012:role/pipeline": ["example.com"],
+		"arn:aws:sts::123456789012:assumed-role/deploy/*": ["example.org", "example.net"]
+	}`)
+	defer os.Unsetenv(CallerHostedZonesEnvVar)
+
+	zones, err := callerHostedZones("arn:aws:iam::123456789012:role/pipeline")
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.com"}, zones)
+
+	zones, err = callerHostedZones("arn:aws:sts::123456789012:assumed-role/deploy/session")
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.org", "example.net"}, zones)
+
+	zones, err = callerHostedZones("arn:aws:iam::123456789012:role/pipeline-other")
+	require.NoError(t, err)
+	require.Empty(t, zones)
+
+	os.Setenv(CallerHostedZonesEnvVar, "example.com")
+	_, err = callerHostedZones("arn:aws:iam::123456789012:role/pipeline")
+	require.Error(t, err)
+}
+
+func TestJobID(t *testing.T) {
+	j := &httpJob{Domains: []string{"example.com", "www.example.com"}, Staging: true, RequestedAt: 1700000000}
+	secret := []byte("secret")
+
+	jobID, err := encodeJobID(j, secret)
+	require.NoError(t, err)
+
+	decoded, err := decodeJobID(jobID, secret)
+	require.NoError(t, err)
+	require.Equal(t, j, decoded)
+
+	_, err = decodeJobID(jobID, []byte("another secret"))
+	require.Equal(t, ErrInvalidJobID, err)
+
+	// The unsigned ID of the job with other domains
+	forged, err := encodeJobID(&httpJob{Domains: []string{"example.net"}, RequestedAt: 1700000000}, []byte("forged"))
+	require.NoError(t, err)
+	_, err = decodeJobID(strings.Split(forged, ".")[0]+"."+strings.Split(jobID, ".")[1], secret)
+	require.Equal(t, ErrInvalidJobID, err)
+
+	_, err = decodeJobID("not a job", secret)
+	require.Equal(t, ErrInvalidJobID, err)
+}
+
+func TestHandleHTTPRequest(t *testing.T) {
+	const caller = "arn:aws:iam::123456789012:role/pipeline"
+
+	os.Setenv(CallerHostedZonesEnvVar, `{"`+caller+`": ["example.com"]}`)
+	defer os.Unsetenv(CallerHostedZonesEnvVar)
+
+	os.Setenv(JobSecretEnvVar, "secret")
+	defer os.Unsetenv(JobSecretEnvVar)
+
+	// The job of the certificate of another caller
+	otherJobID, err := encodeJobID(&httpJob{Domains: []string{"example.org"}, RequestedAt: 1700000000}, []byte("secret"))
+	require.NoError(t, err)
+
+	testTable := []*struct {
+		testName           string
+		method             string
+		path               string
+		body               string
+		callerARN          string
+		expectedStatusCode int
+	}{
+		{
+			testName:           "unsigned request",
+			method:             http.MethodPost,
+			path:               "/certificates",
+			body:               `{"domains": ["example.com"]}`,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			testName:           "caller without hosted zones",
+			method:             http.MethodPost,
+			path:               "/certificates",
+			body:               `{"domains": ["example.com"]}`,
+			callerARN:          "arn:aws:iam::123456789012:role/other",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			testName:           "unknown route",
+			method:             http.MethodGet,
+			path:               "/unknown",
+			callerARN:          caller,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			testName:           "invalid body",
+			method:             http.MethodPost,
+			path:               "/certificates",
+			body:               "{",
+			callerARN:          caller,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			testName:           "empty domains",
+			method:             http.MethodPost,
+			path:               "/certificates",
+			body:               `{"domains": []}`,
+			callerARN:          caller,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			testName:           "denied domains",
+			method:             http.MethodPost,
+			path:               "/prod/certificates/",
+			body:               `{"domains": ["example.com", "example.net"]}`,
+			callerARN:          caller,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			testName:           "invalid job ID",
+			method:             http.MethodGet,
+			path:               "/certificates/@@@",
+			callerARN:          caller,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			testName:           "job of denied domains",
+			method:             http.MethodGet,
+			path:               "/certificates/" + otherJobID,
+			callerARN:          caller,
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			req := events.APIGatewayV2HTTPRequest{
+				Version: httpEventVersion,
+				RawPath: tt.path,
+				Body:    tt.body,
+			}
+			req.RequestContext.HTTP.Method = tt.method
+
+			event, err := json.Marshal(req)
+			require.NoError(t, err)
+
+			if len(tt.callerARN) > 0 {
+				var fields map[string]interface{}
+				require.NoError(t, json.Unmarshal(event, &fields))
+				fields["requestContext"].(map[string]interface{})["authorizer"] = map[string]interface{}{
+					"iam": map[string]interface{}{"userArn": tt.callerARN},
+				}
+
+				event, err = json.Marshal(fields)
+				require.NoError(t, err)
+			}
+
+			resp, err := Handle(context.Background(), event)
+			require.NoError(t, err)
+
+			httpResp, ok := resp.(events.APIGatewayV2HTTPResponse)
+			require.True(t, ok)
+			require.Equal(t, tt.expectedStatusCode, httpResp.StatusCode)
+		})
+	}
+}
+
+func TestRequestCertificateRenewalInfo(t *testing.T) {
+	const caller = "arn:aws:iam::123456789012:role/pipeline"
+
+	os.Setenv(CallerHostedZonesEnvVar, `{"`+caller+`": ["example.com"]}`)
+	defer os.Unsetenv(CallerHostedZonesEnvVar)
+
+	os.Setenv(JobSecretEnvVar, "secret")
+	defer os.Unsetenv(JobSecretEnvVar)
+
+	// The CA suggests renewing the certificate later than the renewal period starts
+	var ca *httptest.Server
+	ca = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/directory" {
+			fmt.Fprintf(w, `{"newOrder": "%s/new-order", "renewalInfo": "%s/renewal-info"}`, ca.URL, ca.URL)
+			return
+		}
+
+		start := time.Now().Add(5 * 24 * time.Hour)
+		fmt.Fprintf(w, `{"suggestedWindow": {"start": "%s", "end": "%s"}}`,
+			start.Format(time.RFC3339), start.Add(24*time.Hour).Format(time.RFC3339))
+	}))
+	defer ca.Close()
+
+	// The existing certificate expires within the renewal period
+	notAfter := time.Now().Add(10 * 24 * time.Hour)
+	store := &fakeCertStore{cert: &certstore.CertificateDetails{
+		ID:          "arn:aws:acm:us-east-1:123456789012:certificate/existing",
+		Domains:     []string{"example.com"},
+		NotAfter:    notAfter,
+		Certificate: issuedCertificate(t, notAfter),
+	}}
+
+	defer func(original func(Payload) (*runner.Job, error)) { newHTTPJob = original }(newHTTPJob)
+	newHTTPJob = func(payload Payload) (*runner.Job, error) {
+		return &runner.Job{
+			Group: &config.Group{Domains: payload.Certificate, RenewBefore: 30},
+			Handler: handler.NewCertificateHandler(&handler.CertificateHandlerOptions{
+				CADirURL:    ca.URL + "/directory",
+				RenewBefore: 30 * 24,
+				Store:       store,
+				Log:         logrus.New(),
+			}),
+		}, nil
+	}
+
+	req := events.APIGatewayV2HTTPRequest{RawPath: "/certificates", Body: `{"domains": ["example.com"]}`}
+	req.RequestContext.HTTP.Method = http.MethodPost
+
+	// The certificate is not obtained, since the invocation obtaining it would skip it too
+	resp, err := HandleHTTPRequest(context.Background(), req, caller)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var certResp CertificateResponse
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &certResp))
+	require.Equal(t, StatusIssued, certResp.Status)
+	require.Equal(t, store.cert.ID, certResp.CertificateARN)
+}
+
+// issuedCertificate returns the PEM encoded certificate with the authority key identifier expiring at the given time
+func issuedCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		DNSNames:       []string{"example.com"},
+		NotBefore:      notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:       notAfter,
+		AuthorityKeyId: []byte{0x01, 0x02},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,176 @@
+package lambda
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+const (
+	// JobSecretEnvVar is the name of env var which contains the secret signing IDs of jobs requested by HTTP
+	JobSecretEnvVar = "JOB_ID_SECRET"
+
+	// JobsParameterPath is the path of SSM parameters which keep jobs requested by HTTP until they are finished
+	JobsParameterPath = "/acme-dns-route53/jobs/"
+)
+
+var (
+	// ErrJobSecretMissing is the error when the secret signing job IDs is not configured
+	ErrJobSecretMissing = errors.New("JOB_ID_SECRET must be set to request certificates by HTTP")
+
+	// ErrInvalidJobID is the error when the job ID is malformed or its signature does not match
+	ErrInvalidJobID = errors.New("invalid job ID")
+)
+
+// httpJob is the certificate request encoded into the signed job ID, so the status is checked without loading jobs
+type httpJob struct {
+	Domains     []string `json:"d"`
+	Staging     bool     `json:"s,omitempty"`
+	RequestedAt int64    `json:"t"`
+}
+
+// key returns the name of the SSM parameter which keeps the job while it is in flight,
+// jobs of the same certificate have the same key
+func (j *httpJob) key() string {
+	domains := append([]string(nil), j.Domains...)
+	sort.Strings(domains)
+
+	sum := sha256.Sum256([]byte(strings.Join(domains, ",")))
+	if j.Staging {
+		sum = sha256.Sum256(append(sum[:], "staging"...))
+	}
+
+	return JobsParameterPath + hex.EncodeToString(sum[:])
+}
+
+// jobSecret returns the secret signing job IDs
+func jobSecret() ([]byte, error) {
+	secret := os.Getenv(JobSecretEnvVar)
+	if len(secret) == 0 {
+		return nil, ErrJobSecretMissing
+	}
+
+	return []byte(secret), nil
+}
+
+// encodeJobID encodes the given job into its ID signed by the given secret
+func encodeJobID(j *httpJob, secret []byte) (string, error) {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(encoded, secret)), nil
+}
+
+// decodeJobID decodes the job from the given ID, the signature of the ID must match the given secret
+func decodeJobID(jobID string, secret []byte) (*httpJob, error) {
+	parts := strings.Split(jobID, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidJobID
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, sign(parts[0], secret)) {
+		return nil, ErrInvalidJobID
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidJobID
+	}
+
+	var j httpJob
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, ErrInvalidJobID
+	}
+
+	return &j, nil
+}
+
+// sign returns HMAC-SHA256 of the given value
+func sign(value string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// jobStore keeps jobs requested by HTTP in SSM parameters, so the same certificate is not obtained by concurrent jobs
+type jobStore struct {
+	ssm    ssmiface.SSMAPI
+	secret []byte
+}
+
+// reserve records the job with the given ID as in flight, unless the job of the same certificate is in flight already.
+// Returns the ID of the in-flight job and true if it is reused.
+// Jobs are in flight until they are issued or fail by the timeout, the status endpoint reports them.
+func (s *jobStore) reserve(j *httpJob, jobID string) (string, bool, error) {
+	name := j.key()
+
+	_, err := s.ssm.PutParameter(&ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Type:      aws.String(ssm.ParameterTypeString),
+		Value:     aws.String(jobID),
+		Overwrite: aws.Bool(false),
+	})
+	if err == nil {
+		return jobID, false, nil
+	}
+
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != ssm.ErrCodeParameterAlreadyExists {
+		return "", false, err
+	}
+
+	resp, err := s.ssm.GetParameter(&ssm.GetParameterInput{Name: aws.String(name)})
+	if err != nil {
+		return "", false, err
+	}
+
+	// Reuse the in-flight job, the job which is timed out or signed by another secret is replaced
+	existingID := aws.StringValue(resp.Parameter.Value)
+	if existing, err := decodeJobID(existingID, s.secret); err == nil && time.Since(time.Unix(existing.RequestedAt, 0)) <= issueTimeout {
+		return existingID, true, nil
+	}
+
+	if _, err := s.ssm.PutParameter(&ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Type:      aws.String(ssm.ParameterTypeString),
+		Value:     aws.String(jobID),
+		Overwrite: aws.Bool(true),
+	}); err != nil {
+		return "", false, err
+	}
+
+	return jobID, false, nil
+}
+
+// release removes the job with the given ID if it is still in flight, e.g. when it could not be started
+func (s *jobStore) release(j *httpJob, jobID string) error {
+	name := j.key()
+
+	resp, err := s.ssm.GetParameter(&ssm.GetParameterInput{Name: aws.String(name)})
+	if err != nil {
+		return err
+	}
+
+	if aws.StringValue(resp.Parameter.Value) != jobID {
+		return nil
+	}
+
+	_, err = s.ssm.DeleteParameter(&ssm.DeleteParameterInput{Name: aws.String(name)})
+	return err
+}
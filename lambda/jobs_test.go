@@ -0,0 +1,89 @@
+package lambda
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSSM keeps parameters in memory
+type fakeSSM struct {
+	ssmiface.SSMAPI
+
+	parameters map[string]string
+}
+
+func (f *fakeSSM) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	name := aws.StringValue(input.Name)
+	if _, ok := f.parameters[name]; ok && !aws.BoolValue(input.Overwrite) {
+		return nil, awserr.New(ssm.ErrCodeParameterAlreadyExists, "parameter already exists", nil)
+	}
+
+	f.parameters[name] = aws.StringValue(input.Value)
+	return &ssm.PutParameterOutput{}, nil
+}
+
+func (f *fakeSSM) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	value, ok := f.parameters[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "parameter not found", nil)
+	}
+
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(value)}}, nil
+}
+
+func (f *fakeSSM) DeleteParameter(input *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error) {
+	delete(f.parameters, aws.StringValue(input.Name))
+	return &ssm.DeleteParameterOutput{}, nil
+}
+
+func TestJobStore(t *testing.T) {
+	secret := []byte("secret")
+	fake := &fakeSSM{parameters: make(map[string]string)}
+	store := &jobStore{ssm: fake, secret: secret}
+
+	newJob := func(requestedAt time.Time, domains ...string) (*httpJob, string) {
+		j := &httpJob{Domains: domains, RequestedAt: requestedAt.Unix()}
+		jobID, err := encodeJobID(j, secret)
+		require.NoError(t, err)
+		return j, jobID
+	}
+
+	first, firstID := newJob(time.Now(), "example.com", "www.example.com")
+	jobID, reused, err := store.reserve(first, firstID)
+	require.NoError(t, err)
+	require.False(t, reused)
+	require.Equal(t, firstID, jobID)
+
+	// The job of the same certificate reuses the in-flight one, the order of domains does not matter
+	second, secondID := newJob(time.Now().Add(time.Second), "www.example.com", "example.com")
+	jobID, reused, err = store.reserve(second, secondID)
+	require.NoError(t, err)
+	require.True(t, reused)
+	require.Equal(t, firstID, jobID)
+
+	// Jobs of other certificates are not affected
+	other, otherID := newJob(time.Now(), "example.com")
+	jobID, reused, err = store.reserve(other, otherID)
+	require.NoError(t, err)
+	require.False(t, reused)
+	require.Equal(t, otherID, jobID)
+
+	// The timed out job is replaced
+	fake.parameters[first.key()], _ = encodeJobID(&httpJob{Domains: first.Domains, RequestedAt: time.Now().Add(-2 * issueTimeout).Unix()}, secret)
+	jobID, reused, err = store.reserve(second, secondID)
+	require.NoError(t, err)
+	require.False(t, reused)
+	require.Equal(t, secondID, jobID)
+
+	// Only the own job is released
+	require.NoError(t, store.release(first, firstID))
+	require.Equal(t, secondID, fake.parameters[first.key()])
+	require.NoError(t, store.release(second, secondID))
+	require.NotContains(t, fake.parameters, second.key())
+}
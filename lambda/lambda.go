@@ -1,6 +1,10 @@
 package lambda
 
 import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/go-acme/lego/log"
@@ -19,5 +23,42 @@ func init() {
 }
 
 func Init() {
-	lambda.Start(HandleLambdaEvent)
+	lambda.Start(Handle)
+}
+
+// Handle handles the given event of any supported type:
+// SQS event with payloads in message bodies, HTTP request of API Gateway HTTP API or the function URL, or the payload itself
+func Handle(ctx context.Context, event json.RawMessage) (interface{}, error) {
+	var probe struct {
+		Version string `json:"version"`
+		RawPath string `json:"rawPath"`
+		Records []struct {
+			EventSource string `json:"eventSource"`
+		} `json:"Records"`
+	}
+
+	if err := json.Unmarshal(event, &probe); err == nil && probe.Version == httpEventVersion && len(probe.RawPath) > 0 {
+		var req events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(event, &req); err != nil {
+			return nil, err
+		}
+
+		return HandleHTTPRequest(ctx, req, callerARN(event))
+	}
+
+	if err := json.Unmarshal(event, &probe); err == nil && len(probe.Records) > 0 && probe.Records[0].EventSource == sqsEventSource {
+		var sqsEvent events.SQSEvent
+		if err := json.Unmarshal(event, &sqsEvent); err != nil {
+			return nil, err
+		}
+
+		return HandleSQSEvent(ctx, sqsEvent)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(event, &payload); err != nil {
+		return nil, err
+	}
+
+	return HandleLambdaEvent(ctx, payload)
 }
@@ -1,15 +1,14 @@
 package lambda
 
 import (
+	"context"
 	"errors"
-	"sync"
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/begmaroman/acme-dns-route53/certstore/acmstore"
+	"github.com/begmaroman/acme-dns-route53/audit"
 	"github.com/begmaroman/acme-dns-route53/handler"
-	"github.com/begmaroman/acme-dns-route53/handler/r53dns"
-	"github.com/begmaroman/acme-dns-route53/notifier/awsns"
+	"github.com/begmaroman/acme-dns-route53/runner"
 )
 
 const (
@@ -23,56 +22,148 @@ var (
 
 	// ErrDomainsMissing is the error when the domains list is empty
 	ErrDomainsMissing = errors.New("domains list must not be filled")
+
+	// ErrUnknownAction is the error when the requested action is not supported
+	ErrUnknownAction = errors.New("unknown action")
+
+	// ErrDryRunNotSupported is the error when the dry run is requested for the action which does not support it
+	ErrDryRunNotSupported = errors.New("dry run is not supported by the action")
 )
 
 // Payload contains payload data
 type Payload struct {
+	Action      string   `json:"action"`
+	Config      string   `json:"config"`
+	Groups      []string `json:"groups"`
+	Certificate []string `json:"certificate"`
 	Domains     []string `json:"domains"`
 	Email       string   `json:"email"`
 	Staging     string   `json:"staging"`
 	Topic       string   `json:"topic"`
 	RenewBefore int      `json:"renew_before"`
+	Reason      string   `json:"reason"`
+	Delete      bool     `json:"delete"`
+	Replace     bool     `json:"replace"`
+	Force       bool     `json:"force"`
+	DisableARI  bool     `json:"disable_ari"`
+	DryRun      bool     `json:"dry_run"`
+	Parallelism int      `json:"parallelism"`
+
+	// Continuation is the number of the invocation continuing the original one
+	Continuation int `json:"continuation,omitempty"`
+
+	// Discovered are domain sets of discovered certificates which are not processed by the previous invocation,
+	// they are renewed instead of discovering certificates again
+	Discovered [][]string `json:"discovered,omitempty"`
+}
+
+const (
+	// StatusSucceeded is the status of the response when all certificate groups are processed successfully
+	StatusSucceeded = "succeeded"
+
+	// StatusPartiallyFailed is the status of the response when processing of some certificate groups failed
+	StatusPartiallyFailed = "partially_failed"
+
+	// StatusFailed is the status of the response when processing of all certificate groups failed
+	StatusFailed = "failed"
+)
+
+// Response is the response of the lambda function with the result of each certificate group
+type Response struct {
+	Action string `json:"action"`
+	Status string `json:"status"`
+	*runner.Summary
+
+	// Audit is the report of the audit action
+	Audit *audit.Report `json:"audit,omitempty"`
+
+	// Continued is true if unprocessed groups are passed to the next invocation
+	Continued bool `json:"continued,omitempty"`
+}
+
+// newResponse creates the response of the given action with the given summary
+func newResponse(action string, summary *runner.Summary) *Response {
+	status := StatusSucceeded
+	if failed := summary.Failed + summary.Rejected; failed > 0 {
+		status = StatusPartiallyFailed
+		if failed == len(summary.Results) {
+			status = StatusFailed
+		}
+	}
+
+	return &Response{Action: action, Status: status, Summary: summary}
 }
 
-func HandleLambdaEvent(payload Payload) error {
+// HandleLambdaEvent handles the given payload.
+// The response is returned along with *runner.FailedError if processing of any group failed.
+func HandleLambdaEvent(ctx context.Context, payload Payload) (*Response, error) {
+	return handlePayload(ctx, payload, true)
+}
+
+// handlePayload handles the given payload, unprocessed groups are passed to the next invocation if it is allowed and enabled
+func handlePayload(ctx context.Context, payload Payload, allowContinue bool) (*Response, error) {
 	conf := InitConfig(payload)
 
-	// Domains list must not be empty
-	if len(conf.Domains) == 0 {
-		return ErrDomainsMissing
+	log := logrus.New()
+
+	if conf.Action == ActionAudit {
+		return auditCertificates(conf, log)
 	}
 
-	// Email must be filled
-	if len(conf.Email) == 0 {
-		return ErrEmailMissing
+	b, err := newBatch(conf, log)
+	if err != nil {
+		return nil, err
 	}
 
-	log := logrus.New()
+	summary := run(ctx, conf, b, log)
+	summary.DryRun = conf.DryRun
+
+	log.Info(summary.String())
+
+	resp := newResponse(conf.Action, summary)
+
+	// Process the rest of groups by the next invocation
+	if summary.Unprocessed > 0 && conf.ContinueAsync && allowContinue {
+		if err := continueAsync(ctx, payload, conf, summary.UnprocessedResults()); err != nil {
+			log.Errorf("lambda: unable to continue processing of %d groups: %s", summary.Unprocessed, err)
+		} else {
+			resp.Continued = true
+		}
+	}
+
+	return resp, summary.Err()
+}
+
+// newBatch creates the batch of jobs of the action of the given function settings
+func newBatch(conf *Config, log *logrus.Logger) (*batch, error) {
+	if conf.DryRun && conf.Action == ActionRevoke {
+		return nil, ErrDryRunNotSupported
+	}
+
+	switch conf.Action {
+	case ActionObtain:
+		return obtain(conf, log)
+	case ActionRevoke:
+		return revoke(conf, log)
+	case ActionRenew:
+		return renew(conf, log)
+	default:
+		return nil, ErrUnknownAction
+	}
+}
 
-	// Create a new handler
-	certificateHandler := handler.NewCertificateHandler(&handler.CertificateHandlerOptions{
-		ConfigDir:         ConfigDir,
-		Staging:           conf.Staging,
-		NotificationTopic: conf.Topic,
-		RenewBefore:       conf.RenewBefore * 24,
-		Log:               log,
-		Notifier:          awsns.New(AWSSession, log),    // Initialize SNS API client
-		DNS01:             r53dns.New(AWSSession, log),   // Initialize DNS-01 challenge provider by Route 53
-		Store:             acmstore.New(AWSSession, log), // Initialize ACM client
-	})
-
-	var wg sync.WaitGroup
-	for _, domain := range conf.Domains {
-		wg.Add(1)
-		go func(domainList []string) {
-			defer wg.Done()
-
-			if err := certificateHandler.Obtain(domainList, conf.Email); err != nil {
-				logrus.Errorf("[%s] unable to obtain certificate: %s\n", domain, err)
-			}
-		}([]string{domain})
+// obtain obtains certificates for the configured groups
+func obtain(conf *Config, log *logrus.Logger) (*batch, error) {
+	groupsConf, err := loadConfig(conf, false)
+	if err != nil {
+		return nil, err
 	}
-	wg.Wait()
 
-	return nil
+	return &batch{
+		jobs:        runner.NewJobs(groupsConf.Groups, newRunnerOptions(conf, log)),
+		parallelism: getParallelism(conf, groupsConf),
+		fn: func(job *runner.Job) (*handler.Result, error) {
+			return job.Handler.Obtain(job.Group.Domains, job.Group.Email)
+		},
+	}, nil
 }
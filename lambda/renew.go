@@ -0,0 +1,49 @@
+package lambda
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// renew renews existing certificates of the configured groups, or all managed certificates if groups are not configured
+func renew(conf *Config, log *logrus.Logger) (*batch, error) {
+	groupsConf, err := loadConfig(conf, true)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := newRunnerOptions(conf, log)
+
+	// Discover all managed certificates if groups are not configured,
+	// the continuing invocation renews certificates discovered by the previous one which are not processed yet
+	if len(groupsConf.Groups) == 0 {
+		domainsList := conf.Discovered
+		if len(domainsList) == 0 {
+			if domainsList, err = runner.NewJob(groupsConf.NewGroup(nil), opts).Handler.ManagedDomains(); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, domains := range domainsList {
+			groupsConf.Groups = append(groupsConf.Groups, groupsConf.NewGroup(domains))
+		}
+
+		if len(groupsConf.Groups) == 0 {
+			return &batch{}, nil
+		}
+
+		if err := groupsConf.Init(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &batch{
+		jobs:        runner.NewJobs(groupsConf.Groups, opts),
+		parallelism: getParallelism(conf, groupsConf),
+		fn: func(job *runner.Job) (*handler.Result, error) {
+			return job.Handler.Renew(job.Group.Domains, job.Group.Email, conf.Force)
+		},
+	}, nil
+}
@@ -0,0 +1,49 @@
+package lambda
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+// ErrRevokeGroupsMissing is the error when revocation of groups of the configuration file is requested without group names
+var ErrRevokeGroupsMissing = errors.New("groups must be filled to revoke certificates of the configuration file")
+
+// revoke revokes certificates of the configured groups
+func revoke(conf *Config, log *logrus.Logger) (*batch, error) {
+	reason, err := handler.ParseRevocationReason(conf.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &handler.RevokeOptions{
+		Reason:  reason,
+		Delete:  conf.Delete,
+		Replace: conf.Replace,
+	}
+
+	if opts.Delete && opts.Replace {
+		return nil, handler.ErrRevokeDeleteAndReplace
+	}
+
+	// Revocation of all groups at once is most likely a mistake
+	if len(conf.ConfigLocation) > 0 && len(conf.Groups) == 0 {
+		return nil, ErrRevokeGroupsMissing
+	}
+
+	groupsConf, err := loadConfig(conf, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &batch{
+		jobs:        runner.NewJobs(groupsConf.Groups, newRunnerOptions(conf, log)),
+		parallelism: getParallelism(conf, groupsConf),
+		fn: func(job *runner.Job) (*handler.Result, error) {
+			return job.Handler.Revoke(job.Group.Domains, job.Group.Email, opts)
+		},
+	}, nil
+}
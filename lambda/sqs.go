@@ -0,0 +1,146 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+const (
+	// sqsEventSource is the source of events sent by SQS event source mapping
+	sqsEventSource = "aws:sqs"
+)
+
+// errUnprocessed is the error when the certificate groups of the message were not processed before the function timeout
+var errUnprocessed = errors.New("certificate groups were not processed before the function timeout")
+
+// SQSEventResponse is the response to SQS event source mapping with ReportBatchItemFailures enabled.
+// Messages of the listed failures are returned to the queue, others are deleted.
+type SQSEventResponse struct {
+	BatchItemFailures []SQSBatchItemFailure `json:"batchItemFailures"`
+}
+
+// SQSBatchItemFailure is the message which has not been processed successfully
+type SQSBatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// sqsBatch is the batch of certificate groups of all messages of the SQS event
+type sqsBatch struct {
+	batch
+
+	// messageIDs are IDs of messages of the jobs in the same order
+	messageIDs []string
+
+	// fns are the functions processing the jobs of each message
+	fns map[*runner.Job]runner.JobFunc
+}
+
+// HandleSQSEvent handles the given SQS event, the body of each message is the payload describing a certificate group.
+// Certificate groups of all messages are processed by one run, so at most the configured number of them are processed at once.
+// Messages which failed or were not processed before the function timeout are reported as failures,
+// so SQS redelivers them, and moves them to the dead-letter queue after the configured number of attempts.
+func HandleSQSEvent(ctx context.Context, event events.SQSEvent) (*SQSEventResponse, error) {
+	conf := InitConfig(Payload{})
+	log := logrus.New()
+
+	failures := []SQSBatchItemFailure{}
+	fail := func(messageID string, err error) {
+		logrus.Errorf("[%s] lambda: unable to process SQS message: %s", messageID, err)
+		failures = append(failures, SQSBatchItemFailure{ItemIdentifier: messageID})
+	}
+
+	b := &sqsBatch{
+		batch: batch{parallelism: conf.Parallelism},
+		fns:   make(map[*runner.Job]runner.JobFunc),
+	}
+
+	for _, message := range event.Records {
+		if err := b.add(ctx, message, log); err != nil {
+			fail(message.MessageId, err)
+		}
+	}
+
+	if len(b.jobs) == 0 {
+		return &SQSEventResponse{BatchItemFailures: failures}, nil
+	}
+
+	b.fn = func(job *runner.Job) (*handler.Result, error) {
+		return b.fns[job](job)
+	}
+
+	summary := run(ctx, conf, &b.batch, log)
+	log.Info(summary.String())
+
+	errs := b.messageErrors(summary)
+	for _, message := range event.Records {
+		if err, ok := errs[message.MessageId]; ok {
+			fail(message.MessageId, err)
+		}
+	}
+
+	return &SQSEventResponse{BatchItemFailures: failures}, nil
+}
+
+// add adds jobs of the payload of the given message to the batch.
+// Payloads which are not processed by jobs, e.g. audits, are handled at once.
+func (b *sqsBatch) add(ctx context.Context, message events.SQSMessage, log *logrus.Logger) error {
+	var payload Payload
+	if err := json.Unmarshal([]byte(message.Body), &payload); err != nil {
+		return err
+	}
+
+	conf := InitConfig(payload)
+
+	if conf.Action == ActionAudit {
+		// Unprocessed groups are retried by SQS instead of the next invocation
+		_, err := handlePayload(ctx, payload, false)
+		return err
+	}
+
+	mb, err := newBatch(conf, log)
+	if err != nil {
+		return err
+	}
+
+	// The configured parallelism of the function takes precedence, otherwise the lowest one of the messages is used
+	if conf.Parallelism <= 0 && mb.parallelism > 0 && (b.parallelism <= 0 || mb.parallelism < b.parallelism) {
+		b.parallelism = mb.parallelism
+	}
+
+	for _, job := range mb.jobs {
+		b.jobs = append(b.jobs, job)
+		b.messageIDs = append(b.messageIDs, message.MessageId)
+		b.fns[job] = mb.fn
+	}
+
+	return nil
+}
+
+// messageErrors returns errors of messages whose certificate groups failed or were not processed according to the given summary
+func (b *sqsBatch) messageErrors(summary *runner.Summary) map[string]error {
+	results := make(map[string][]*runner.Result)
+	for i, result := range summary.Results {
+		results[b.messageIDs[i]] = append(results[b.messageIDs[i]], result)
+	}
+
+	errs := make(map[string]error)
+	for messageID, messageResults := range results {
+		messageSummary := runner.NewSummary(messageResults)
+
+		switch {
+		case messageSummary.Err() != nil:
+			errs[messageID] = messageSummary.Err()
+		case messageSummary.Unprocessed > 0:
+			errs[messageID] = errUnprocessed
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,109 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/runner"
+)
+
+func TestHandleSQSEvent(t *testing.T) {
+	event := json.RawMessage(`{
+		"Records": [
+			{"messageId": "invalid-json", "eventSource": "aws:sqs", "body": "{"},
+			{"messageId": "unknown-action", "eventSource": "aws:sqs", "body": "{\"action\": \"unknown\"}"},
+			{"messageId": "missing-email", "eventSource": "aws:sqs", "body": "{\"domains\": [\"example.com\"]}"}
+		]
+	}`)
+
+	resp, err := Handle(context.Background(), event)
+	require.NoError(t, err)
+
+	sqsResp, ok := resp.(*SQSEventResponse)
+	require.True(t, ok)
+
+	var ids []string
+	for _, failure := range sqsResp.BatchItemFailures {
+		ids = append(ids, failure.ItemIdentifier)
+	}
+	require.ElementsMatch(t, []string{"invalid-json", "unknown-action", "missing-email"}, ids)
+}
+
+func TestSQSBatch(t *testing.T) {
+	var (
+		lock              sync.Mutex
+		running, maxCount int
+	)
+
+	process := func(outcome handler.Outcome, err error) runner.JobFunc {
+		return func(job *runner.Job) (*handler.Result, error) {
+			lock.Lock()
+			running++
+			if running > maxCount {
+				maxCount = running
+			}
+			lock.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			lock.Lock()
+			running--
+			lock.Unlock()
+
+			return &handler.Result{Outcome: outcome}, err
+		}
+	}
+
+	b := &sqsBatch{
+		batch: batch{parallelism: 2},
+		fns:   make(map[*runner.Job]runner.JobFunc),
+	}
+
+	// Each message has several groups, the second message has a failed one
+	for _, message := range []struct {
+		id  string
+		fns []runner.JobFunc
+	}{
+		{id: "succeeded", fns: []runner.JobFunc{process(handler.OutcomeIssued, nil), process(handler.OutcomeSkipped, nil), process(handler.OutcomeIssued, nil)}},
+		{id: "failed", fns: []runner.JobFunc{process(handler.OutcomeIssued, nil), process(handler.OutcomeFailed, errors.New("challenge failed")), process(handler.OutcomeIssued, nil)}},
+	} {
+		for _, fn := range message.fns {
+			job := &runner.Job{
+				Group:   &config.Group{Domains: []string{"example.com"}},
+				Handler: handler.NewCertificateHandler(&handler.CertificateHandlerOptions{Store: &fakeCertStore{}, Log: logrus.New()}),
+			}
+
+			b.jobs = append(b.jobs, job)
+			b.messageIDs = append(b.messageIDs, message.id)
+			b.fns[job] = fn
+		}
+	}
+
+	b.fn = func(job *runner.Job) (*handler.Result, error) {
+		return b.fns[job](job)
+	}
+
+	summary := run(context.Background(), InitConfig(Payload{}), &b.batch, logrus.New())
+	require.Equal(t, 6, len(summary.Results))
+
+	// Groups of all messages share the parallelism
+	require.True(t, maxCount <= 2)
+
+	errs := b.messageErrors(summary)
+	require.Len(t, errs, 1)
+	require.Error(t, errs["failed"])
+}
+
+func TestHandlePayload(t *testing.T) {
+	_, err := Handle(context.Background(), json.RawMessage(`{"action": "unknown"}`))
+	require.Equal(t, ErrUnknownAction, err)
+}
@@ -1,21 +1,35 @@
 package awsns
 
 import (
+	"encoding/json"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"github.com/begmaroman/acme-dns-route53/notifier"
 )
 
+const (
+	// AttributeEventType is the message attribute with the type of the event, e.g. for subscription filter policies
+	AttributeEventType = "event_type"
+
+	// AttributeDomains is the message attribute with the domains of the certificate
+	AttributeDomains = "domains"
+
+	// AttributeCA is the message attribute with the directory URL of the CA
+	AttributeCA = "ca"
+)
+
 // To make sure that snsNotifier implements notifier.Notifier interface
 var _ notifier.Notifier = &snsNotifier{}
 
 // snsNotifier implements notifier.Notifier for ACM by Amazon Web Services
 type snsNotifier struct {
-	sns *sns.SNS
+	sns snsiface.SNSAPI
 	log *logrus.Logger
 }
 
@@ -28,12 +42,14 @@ func New(provider client.ConfigProvider, log *logrus.Logger) notifier.Notifier {
 }
 
 // Notify implements implements notifier.Notifier interface.
-// Publishes a message with the given topic to ACM by AWS
-func (n *snsNotifier) Notify(topic, message string) error {
-	publishResp, err := n.sns.Publish(&sns.PublishInput{
-		TopicArn: aws.String(topic),
-		Message:  aws.String(message),
-	})
+// Publishes the given event as JSON message with message attributes to the given topic of SNS by AWS
+func (n *snsNotifier) Notify(topic string, event *notifier.Event) error {
+	input, err := newPublishInput(topic, event)
+	if err != nil {
+		return err
+	}
+
+	publishResp, err := n.sns.Publish(input)
 	if err != nil {
 		return errors.Wrap(err, "sns: unable to publish notification to SNS")
 	}
@@ -42,3 +58,31 @@ func (n *snsNotifier) Notify(topic, message string) error {
 
 	return nil
 }
+
+// newPublishInput creates the input publishing the given event to the given topic
+func newPublishInput(topic string, event *notifier.Event) (*sns.PublishInput, error) {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrap(err, "sns: unable to encode event")
+	}
+
+	domains, err := json.Marshal(event.Domains)
+	if err != nil {
+		return nil, errors.Wrap(err, "sns: unable to encode domains")
+	}
+
+	attributes := map[string]*sns.MessageAttributeValue{
+		AttributeEventType: {DataType: aws.String("String"), StringValue: aws.String(string(event.Type))},
+		AttributeDomains:   {DataType: aws.String("String.Array"), StringValue: aws.String(string(domains))},
+	}
+	if len(event.CA) > 0 {
+		attributes[AttributeCA] = &sns.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(event.CA)}
+	}
+
+	return &sns.PublishInput{
+		TopicArn:          aws.String(topic),
+		Subject:           aws.String(event.Title()),
+		Message:           aws.String(string(message)),
+		MessageAttributes: attributes,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package awsns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+func TestNewPublishInput(t *testing.T) {
+	event := &notifier.Event{
+		Type:            notifier.EventCertificateRenewed,
+		Time:            time.Date(2026, 11, 1, 10, 0, 0, 0, time.UTC),
+		Domains:         []string{"example.com", "www.example.com"},
+		CertificateARNs: []string{"arn:aws:acm:us-east-1:123456789012:certificate/1"},
+		Serial:          "3a",
+		CA:              "https://acme-v02.api.letsencrypt.org/directory",
+	}
+	event.SetExpiry(time.Date(2027, 1, 30, 10, 0, 0, 0, time.UTC))
+
+	input, err := newPublishInput("arn:aws:sns:us-east-1:123456789012:certificates", event)
+	require.NoError(t, err)
+
+	require.Equal(t, "Certificate renewed", aws.StringValue(input.Subject))
+	require.JSONEq(t, `{
+		"type": "CertificateRenewed",
+		"time": "2026-11-01T10:00:00Z",
+		"domains": ["example.com", "www.example.com"],
+		"certificate_arns": ["arn:aws:acm:us-east-1:123456789012:certificate/1"],
+		"serial": "3a",
+		"expiry": "2027-01-30T10:00:00Z",
+		"ca": "https://acme-v02.api.letsencrypt.org/directory"
+	}`, aws.StringValue(input.Message))
+	require.Equal(t, map[string]*sns.MessageAttributeValue{
+		AttributeEventType: {DataType: aws.String("String"), StringValue: aws.String("CertificateRenewed")},
+		AttributeDomains:   {DataType: aws.String("String.Array"), StringValue: aws.String(`["example.com","www.example.com"]`)},
+		AttributeCA:        {DataType: aws.String("String"), StringValue: aws.String("https://acme-v02.api.letsencrypt.org/directory")},
+	}, input.MessageAttributes)
+}
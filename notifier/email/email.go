@@ -0,0 +1,171 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+// defaultHTMLTemplateText is the text of the default template of HTML bodies
+const defaultHTMLTemplateText = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h2 style="color: {{ if .IsFailure }}#a30200{{ else }}#2eb886{{ end }};">{{ .Title }}</h2>
+<p>{{ .Message }}</p>
+<table cellpadding="4">
+{{- range .Facts }}
+<tr><th align="left">{{ .Name }}</th><td>{{ .Value }}</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`
+
+var (
+	// DefaultHTMLTemplate renders the default HTML body of each event type
+	DefaultHTMLTemplate = MustParseHTMLTemplate(defaultHTMLTemplateText)
+)
+
+// HTMLTemplate renders HTML bodies of emails.
+// It is the html/template executed with the event and its Message rendered by the text template,
+// e.g. "<p>{{ .Message }}</p><p>{{ join .Domains ", " }}</p>".
+type HTMLTemplate struct {
+	tmpl *htmltemplate.Template
+}
+
+// ParseHTMLTemplate parses the HTML template with the given text
+func ParseHTMLTemplate(text string) (*HTMLTemplate, error) {
+	tmpl, err := htmltemplate.New("html").Funcs(htmltemplate.FuncMap(notifier.TemplateFuncs)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "email: invalid HTML template")
+	}
+
+	return &HTMLTemplate{tmpl: tmpl}, nil
+}
+
+// MustParseHTMLTemplate parses the HTML template with the given text, and panics if it is invalid
+func MustParseHTMLTemplate(text string) *HTMLTemplate {
+	t, err := ParseHTMLTemplate(text)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// htmlData is the data of HTML templates
+type htmlData struct {
+	*notifier.Event
+
+	// Message is the message of the event rendered by the text template
+	Message string
+}
+
+// message is the rendered email
+type message struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// render renders the email about the given event by the given templates.
+// The default HTML body is rendered if the HTML template is nil or fails.
+func render(event *notifier.Event, tmpl *notifier.Template, htmlTmpl *HTMLTemplate) *message {
+	m := &message{
+		Subject: event.Title(),
+		Text:    tmpl.Render(event),
+	}
+
+	if len(event.Domains) > 0 {
+		m.Subject += ": " + strings.Join(event.Domains, ", ")
+	}
+
+	data := &htmlData{Event: event, Message: m.Text}
+	for _, t := range []*HTMLTemplate{htmlTmpl, DefaultHTMLTemplate} {
+		if t == nil {
+			continue
+		}
+
+		var b bytes.Buffer
+		if err := t.tmpl.Execute(&b, data); err == nil {
+			m.HTML = b.String()
+			break
+		}
+	}
+
+	return m
+}
+
+// encode encodes the message as multipart MIME message with text and HTML alternatives
+// from the given sender to the given recipients
+func (m *message) encode(from string, to []string, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=UTF-8", content: m.Text},
+		{contentType: "text/html; charset=UTF-8", content: m.HTML},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", w.Boundary())
+	b.Write(body.Bytes())
+
+	return b.Bytes(), nil
+}
+
+// Recipients returns the recipients of the given topic, which is the comma-separated list of addresses
+func Recipients(topic string) []string {
+	var to []string
+	for _, address := range strings.Split(topic, ",") {
+		if address = strings.TrimSpace(address); len(address) > 0 {
+			to = append(to, address)
+		}
+	}
+
+	return to
+}
+
+// Topic returns the topic of the given recipients
+func Topic(to []string) string {
+	return strings.Join(to, ",")
+}
@@ -0,0 +1,212 @@
+package email
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+// fakeSMTPServer accepts one message and records the commands and the data
+type fakeSMTPServer struct {
+	listener  net.Listener
+	extension string
+	commands  []string
+	data      string
+	done      chan struct{}
+}
+
+func newFakeSMTPServer(t *testing.T, extension string) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &fakeSMTPServer{listener: listener, extension: extension, done: make(chan struct{})}
+	go s.serve()
+
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	write := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	write("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		s.commands = append(s.commands, line)
+
+		switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+		case "EHLO":
+			write("250-localhost")
+			write("250 " + s.extension)
+		case "AUTH":
+			write("235 Authenticated")
+		case "DATA":
+			write("354 Go ahead")
+
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil || dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+
+			s.data = data.String()
+			write("250 Queued")
+		case "QUIT":
+			write("221 Bye")
+			return
+		default:
+			write("250 OK")
+		}
+	}
+}
+
+// fakeSES records sent emails
+type fakeSES struct {
+	sesiface.SESAPI
+
+	inputs []*ses.SendEmailInput
+}
+
+func (f *fakeSES) SendEmail(input *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return &ses.SendEmailOutput{MessageId: aws.String("message-1")}, nil
+}
+
+func newFailedEvent() *notifier.Event {
+	event := notifier.NewEvent(notifier.EventRenewalFailed, []string{"example.com"})
+	event.Stage = "challenge"
+	event.Error = "acme: <rate limited>"
+
+	return event
+}
+
+func TestRender(t *testing.T) {
+	m := render(newFailedEvent(), notifier.DefaultTemplate, nil)
+	require.Equal(t, "Certificate renewal failed: example.com", m.Subject)
+	require.Equal(t, "Certificate for example.com could not be issued at challenge stage: acme: <rate limited>", m.Text)
+	require.Contains(t, m.HTML, "#a30200")
+	require.Contains(t, m.HTML, "acme: &lt;rate limited&gt;")
+
+	m = render(newFailedEvent(), notifier.DefaultTemplate, MustParseHTMLTemplate(`<b>{{ join .Domains ", " }}</b> {{ .Stage }}`))
+	require.Equal(t, "<b>example.com</b> challenge", m.HTML)
+
+	// Invalid fields fall back to the default HTML body
+	m = render(newFailedEvent(), notifier.DefaultTemplate, MustParseHTMLTemplate(`{{ .Missing }}`))
+	require.Contains(t, m.HTML, "<h2")
+
+	_, err := ParseHTMLTemplate("{{ .Domains")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "email: invalid HTML template")
+}
+
+func TestRecipients(t *testing.T) {
+	to := []string{"admin@example.com", "Ops <ops@example.com>"}
+	require.Equal(t, to, Recipients(Topic(to)))
+	require.Nil(t, Recipients(" , "))
+}
+
+func TestSMTPNotify(t *testing.T) {
+	server := newFakeSMTPServer(t, "AUTH PLAIN")
+	defer server.listener.Close()
+
+	n := NewSMTP(&SMTPOptions{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Username: "user",
+		Password: "secret",
+		TLS:      TLSNone,
+		From:     "Certificates <certs@example.com>",
+	}, notifier.DefaultTemplate, nil, logrus.New())
+
+	require.NoError(t, n.Notify("admin@example.com,Ops <ops@example.com>", newFailedEvent()))
+	<-server.done
+
+	require.Contains(t, server.commands, "MAIL FROM:<certs@example.com>")
+	require.Contains(t, server.commands, "RCPT TO:<admin@example.com>")
+	require.Contains(t, server.commands, "RCPT TO:<ops@example.com>")
+	require.True(t, strings.HasPrefix(server.commands[1], "AUTH PLAIN "))
+
+	require.Contains(t, server.data, "From: Certificates <certs@example.com>\r\n")
+	require.Contains(t, server.data, "To: admin@example.com, Ops <ops@example.com>\r\n")
+	require.Contains(t, server.data, "Subject: Certificate renewal failed: example.com\r\n")
+	require.Contains(t, server.data, "Content-Type: multipart/alternative;")
+	require.Contains(t, server.data, "Content-Type: text/plain; charset=UTF-8")
+	require.Contains(t, server.data, "Content-Type: text/html; charset=UTF-8")
+}
+
+func TestSMTPNotifyRequiresStartTLS(t *testing.T) {
+	server := newFakeSMTPServer(t, "AUTH PLAIN")
+	defer server.listener.Close()
+
+	n := NewSMTP(&SMTPOptions{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "certs@example.com",
+	}, notifier.DefaultTemplate, nil, logrus.New())
+
+	err := n.Notify("admin@example.com", newFailedEvent())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "server does not support STARTTLS")
+
+	<-server.done
+	require.Empty(t, server.data)
+}
+
+func TestSMTPNotifyWithoutRecipients(t *testing.T) {
+	n := NewSMTP(&SMTPOptions{Host: "127.0.0.1", Port: 1, From: "certs@example.com"}, notifier.DefaultTemplate, nil, logrus.New())
+	require.EqualError(t, n.Notify("", newFailedEvent()), "email: no recipients")
+}
+
+func TestSESNotify(t *testing.T) {
+	fake := &fakeSES{}
+	n := &sesNotifier{
+		ses:  fake,
+		from: "certs@example.com",
+		tmpl: notifier.MustParseTemplate(`{{ .Title }}`),
+		log:  logrus.New(),
+	}
+	n.log.Out = ioutil.Discard
+
+	event := notifier.NewEvent(notifier.EventCertificateRenewed, []string{"example.com", "www.example.com"}).SetExpiry(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, n.Notify("admin@example.com,owner@example.com", event))
+	require.Len(t, fake.inputs, 1)
+
+	input := fake.inputs[0]
+	require.Equal(t, "certs@example.com", aws.StringValue(input.Source))
+	require.Equal(t, []string{"admin@example.com", "owner@example.com"}, aws.StringValueSlice(input.Destination.ToAddresses))
+	require.Equal(t, "Certificate renewed: example.com, www.example.com", aws.StringValue(input.Message.Subject.Data))
+	require.Equal(t, "Certificate renewed", aws.StringValue(input.Message.Body.Text.Data))
+	require.Contains(t, aws.StringValue(input.Message.Body.Html.Data), "2027-01-01T00:00:00Z")
+	require.Equal(t, "UTF-8", aws.StringValue(input.Message.Body.Html.Charset))
+}
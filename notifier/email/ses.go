@@ -0,0 +1,72 @@
+package email
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+const (
+	// charset is the charset of all parts of emails
+	charset = "UTF-8"
+)
+
+// To make sure that sesNotifier implements notifier.Notifier interface
+var _ notifier.Notifier = &sesNotifier{}
+
+// sesNotifier implements notifier.Notifier for Amazon Simple Email Service
+type sesNotifier struct {
+	ses      sesiface.SESAPI
+	from     string
+	tmpl     *notifier.Template
+	htmlTmpl *HTMLTemplate
+	log      *logrus.Logger
+}
+
+// NewSES is the constructor of sesNotifier which sends emails rendered by the given templates from the given address.
+// The sender must be a verified identity of SES. The topic is the comma-separated list of recipients.
+func NewSES(provider client.ConfigProvider, from string, tmpl *notifier.Template, htmlTmpl *HTMLTemplate, log *logrus.Logger) notifier.Notifier {
+	return &sesNotifier{
+		ses:      ses.New(provider),
+		from:     from,
+		tmpl:     tmpl,
+		htmlTmpl: htmlTmpl,
+		log:      log,
+	}
+}
+
+// Notify implements notifier.Notifier interface
+func (n *sesNotifier) Notify(topic string, event *notifier.Event) error {
+	to := Recipients(topic)
+	if len(to) == 0 {
+		return errors.New("email: no recipients")
+	}
+
+	m := render(event, n.tmpl, n.htmlTmpl)
+
+	resp, err := n.ses.SendEmail(&ses.SendEmailInput{
+		Source:      aws.String(n.from),
+		Destination: &ses.Destination{ToAddresses: aws.StringSlice(to)},
+		Message: &ses.Message{
+			Subject: &ses.Content{Charset: aws.String(charset), Data: aws.String(m.Subject)},
+			Body: &ses.Body{
+				Text: &ses.Content{Charset: aws.String(charset), Data: aws.String(m.Text)},
+				Html: &ses.Content{Charset: aws.String(charset), Data: aws.String(m.HTML)},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "email: unable to send message via SES")
+	}
+
+	n.log.Infof("[%s] email: sent %s to %s via SES with message ID '%s'", strings.Join(event.Domains, ", "), event.Type, strings.Join(to, ", "), aws.StringValue(resp.MessageId))
+
+	return nil
+}
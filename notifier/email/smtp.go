@@ -0,0 +1,182 @@
+package email
+
+import (
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+const (
+	// TLSStartTLS upgrades SMTP connections by STARTTLS command, servers which do not support it are refused
+	TLSStartTLS = "starttls"
+
+	// TLSImplicit connects to SMTP servers over TLS, e.g. to port 465
+	TLSImplicit = "tls"
+
+	// TLSNone sends emails unencrypted, e.g. to a local relay
+	TLSNone = "none"
+
+	// DefaultPort is the default port of SMTP servers, the submission port
+	DefaultPort = 587
+
+	// sendTimeout is the timeout of sending an email including the connection
+	sendTimeout = 30 * time.Second
+)
+
+// To make sure that smtpNotifier implements notifier.Notifier interface
+var _ notifier.Notifier = &smtpNotifier{}
+
+// SMTPOptions is the options of the SMTP notifier
+type SMTPOptions struct {
+	Host string
+	Port int // DefaultPort if zero
+
+	// Username and Password authenticate by PLAIN mechanism, emails are sent without authentication if Username is empty
+	Username string
+	Password string
+
+	// TLS is the encryption of connections: TLSStartTLS if empty, TLSImplicit or TLSNone
+	TLS string
+
+	// From is the address of the sender
+	From string
+}
+
+// smtpNotifier implements notifier.Notifier for SMTP servers
+type smtpNotifier struct {
+	opts     *SMTPOptions
+	tmpl     *notifier.Template
+	htmlTmpl *HTMLTemplate
+	log      *logrus.Logger
+}
+
+// NewSMTP is the constructor of smtpNotifier which sends emails rendered by the given templates via the SMTP server.
+// The topic is the comma-separated list of recipients.
+func NewSMTP(opts *SMTPOptions, tmpl *notifier.Template, htmlTmpl *HTMLTemplate, log *logrus.Logger) notifier.Notifier {
+	return &smtpNotifier{
+		opts:     opts,
+		tmpl:     tmpl,
+		htmlTmpl: htmlTmpl,
+		log:      log,
+	}
+}
+
+// Notify implements notifier.Notifier interface
+func (n *smtpNotifier) Notify(topic string, event *notifier.Event) error {
+	to := Recipients(topic)
+	if len(to) == 0 {
+		return errors.New("email: no recipients")
+	}
+
+	data, err := render(event, n.tmpl, n.htmlTmpl).encode(n.opts.From, to, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "email: unable to encode message")
+	}
+
+	if err := n.send(to, data); err != nil {
+		return errors.Wrapf(err, "email: unable to send message via '%s'", n.opts.Host)
+	}
+
+	n.log.Infof("[%s] email: sent %s to %s", strings.Join(event.Domains, ", "), event.Type, strings.Join(to, ", "))
+
+	return nil
+}
+
+// send sends the given encoded message to the given recipients
+func (n *smtpNotifier) send(to []string, data []byte) error {
+	port := n.opts.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+
+	addr := net.JoinHostPort(n.opts.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: n.opts.Host}
+	dialer := &net.Dialer{Timeout: sendTimeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if n.opts.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(sendTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, n.opts.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if n.opts.TLS == TLSStartTLS || len(n.opts.TLS) == 0 {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	// PLAIN authentication is refused over unencrypted connections to hosts other than localhost
+	if len(n.opts.Username) > 0 {
+		if err := c.Auth(smtp.PlainAuth("", n.opts.Username, n.opts.Password, n.opts.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(envelopeAddress(n.opts.From)); err != nil {
+		return err
+	}
+
+	for _, rcpt := range to {
+		if err := c.Rcpt(envelopeAddress(rcpt)); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// envelopeAddress returns the bare address of the given address with the optional name, e.g. "Certificates <certs@example.com>"
+func envelopeAddress(address string) string {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return address
+	}
+
+	return parsed.Address
+}
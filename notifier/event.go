@@ -0,0 +1,153 @@
+package notifier
+
+import (
+	"strings"
+	"time"
+)
+
+// EventType is the type of the event about a certificate
+type EventType string
+
+const (
+	// EventCertificateIssued means that a new certificate has been issued
+	EventCertificateIssued EventType = "CertificateIssued"
+
+	// EventCertificateRenewed means that the existing certificate has been renewed
+	EventCertificateRenewed EventType = "CertificateRenewed"
+
+	// EventRenewalSkipped means that the existing certificate does not need renewal yet
+	EventRenewalSkipped EventType = "RenewalSkipped"
+
+	// EventRenewalFailed means that the certificate could not be issued or renewed
+	EventRenewalFailed EventType = "RenewalFailed"
+
+	// EventExpiringSoon means that the certificate expires soon
+	EventExpiringSoon EventType = "ExpiringSoon"
+
+	// EventRevoked means that the certificate has been revoked
+	EventRevoked EventType = "Revoked"
+)
+
+var (
+	// EventTypes are all event types
+	EventTypes = []EventType{
+		EventCertificateIssued,
+		EventCertificateRenewed,
+		EventRenewalSkipped,
+		EventRenewalFailed,
+		EventExpiringSoon,
+		EventRevoked,
+	}
+
+	// DefaultEvents are types of events sent to targets which do not select them,
+	// skipped renewals are not sent since they happen on every run
+	DefaultEvents = []EventType{
+		EventCertificateIssued,
+		EventCertificateRenewed,
+		EventRenewalFailed,
+		EventExpiringSoon,
+		EventRevoked,
+	}
+
+	// eventTitles are short summaries of events
+	eventTitles = map[EventType]string{
+		EventCertificateIssued:  "Certificate issued",
+		EventCertificateRenewed: "Certificate renewed",
+		EventRenewalSkipped:     "Certificate renewal skipped",
+		EventRenewalFailed:      "Certificate renewal failed",
+		EventExpiringSoon:       "Certificate expiring soon",
+		EventRevoked:            "Certificate revoked",
+	}
+)
+
+// Event is the event about a certificate
+type Event struct {
+	Type            EventType  `json:"type"`
+	Time            time.Time  `json:"time"`
+	Domains         []string   `json:"domains"`
+	CertificateARNs []string   `json:"certificate_arns,omitempty"`
+	Serial          string     `json:"serial,omitempty"`
+	Expiry          *time.Time `json:"expiry,omitempty"`
+	CA              string     `json:"ca,omitempty"` // The directory URL of the CA
+
+	// ExplanationURL is the explanation of the renewal suggested by the CA, if any
+	ExplanationURL string `json:"explanation_url,omitempty"`
+
+	// Reason is the revocation reason of revoked certificates
+	Reason string `json:"reason,omitempty"`
+
+	// Stage is the failed stage of failed renewals: policy, caa, registration, challenge, store or deploy
+	Stage string `json:"stage,omitempty"`
+
+	// Error is the failure reason of failed renewals, the chain of wrapped errors
+	Error string `json:"error,omitempty"`
+}
+
+// NewEvent creates the event of the given type about the certificate of the given domains
+func NewEvent(eventType EventType, domains []string) *Event {
+	return &Event{
+		Type:    eventType,
+		Time:    time.Now().UTC(),
+		Domains: domains,
+	}
+}
+
+// SetExpiry sets the given expiration time of the certificate, zero time is ignored
+func (e *Event) SetExpiry(expiry time.Time) *Event {
+	if !expiry.IsZero() {
+		expiry = expiry.UTC()
+		e.Expiry = &expiry
+	}
+
+	return e
+}
+
+// IsFailure checks if the event is about a failure
+func (e *Event) IsFailure() bool {
+	return e.Type == EventRenewalFailed || e.Type == EventExpiringSoon
+}
+
+// Title returns the short summary of the event
+func (e *Event) Title() string {
+	if title, ok := eventTitles[e.Type]; ok {
+		return title
+	}
+
+	return string(e.Type)
+}
+
+// Fact is the named value of the event rendered by rich messages
+type Fact struct {
+	Name  string
+	Value string
+}
+
+// Facts returns the fields of the event which are set as named values in the order they are rendered
+func (e *Event) Facts() []Fact {
+	var facts []Fact
+	if len(e.Domains) > 0 {
+		facts = append(facts, Fact{Name: "Domains", Value: strings.Join(e.Domains, ", ")})
+	}
+
+	if e.Expiry != nil {
+		facts = append(facts, Fact{Name: "Expires", Value: e.Expiry.Format(time.RFC3339)})
+	}
+
+	if len(e.CertificateARNs) > 0 {
+		facts = append(facts, Fact{Name: "Certificate ARN", Value: strings.Join(e.CertificateARNs, ", ")})
+	}
+
+	if len(e.Serial) > 0 {
+		facts = append(facts, Fact{Name: "Serial", Value: e.Serial})
+	}
+
+	if len(e.Stage) > 0 {
+		facts = append(facts, Fact{Name: "Stage", Value: e.Stage})
+	}
+
+	if len(e.Error) > 0 {
+		facts = append(facts, Fact{Name: "Reason", Value: e.Error})
+	}
+
+	return facts
+}
@@ -2,6 +2,31 @@ package notifier
 
 // Notifier represents interface for sending notification
 type Notifier interface {
-	// Notify sends a notification with a given topic and message
-	Notify(topic, message string) error
+	// Notify sends a notification about the given event with a given topic
+	Notify(topic string, event *Event) error
+}
+
+// Target is the notifier with the topic to send notifications to
+type Target struct {
+	Notifier Notifier
+	Topic    string
+
+	// Events are types of events sent to the target, DefaultEvents if empty
+	Events []EventType
+}
+
+// Accepts checks if events of the given type are sent to the target
+func (t Target) Accepts(eventType EventType) bool {
+	events := t.Events
+	if len(events) == 0 {
+		events = DefaultEvents
+	}
+
+	for _, e := range events {
+		if e == eventType {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// To make sure that Router implements Notifier interface
+var _ Notifier = &Router{}
+
+// Route is the rule which dispatches events of its types to its targets
+type Route struct {
+	// Events are types of events dispatched by the route, DefaultEvents if empty
+	Events []EventType
+
+	Targets []Target
+}
+
+// Router is the notifier which fans out each event to targets of all routes matching it.
+// A target of several matching routes is notified once.
+type Router struct {
+	routes []*Route
+}
+
+// targetKey identifies the target among targets of routes
+type targetKey struct {
+	notifier Notifier
+	topic    string
+}
+
+// NewRouter is the constructor of Router
+func NewRouter(routes ...*Route) *Router {
+	return &Router{routes: routes}
+}
+
+// Notify implements Notifier interface, the topic is ignored since targets have their own topics.
+// All targets are notified even if some of them fail, the error describes all failures.
+func (r *Router) Notify(_ string, event *Event) error {
+	var failures []string
+	for _, target := range r.targets(event.Type) {
+		if err := target.Notifier.Notify(target.Topic, event); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.Errorf("notifier: %d target(s) failed: %s", len(failures), strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// Topics returns topics of targets which events of the given type are dispatched to, each topic once
+func (r *Router) Topics(eventType EventType) []string {
+	var topics []string
+
+	seen := make(map[string]bool)
+	for _, target := range r.targets(eventType) {
+		if !seen[target.Topic] {
+			seen[target.Topic] = true
+			topics = append(topics, target.Topic)
+		}
+	}
+
+	return topics
+}
+
+// targets returns targets of all routes matching events of the given type which accept them, each target once
+func (r *Router) targets(eventType EventType) []Target {
+	var targets []Target
+
+	seen := make(map[targetKey]bool)
+	for _, route := range r.routes {
+		if !(Target{Events: route.Events}).Accepts(eventType) {
+			continue
+		}
+
+		for _, target := range route.Targets {
+			key := targetKey{notifier: target.Notifier, topic: target.Topic}
+			if seen[key] || !target.Accepts(eventType) {
+				continue
+			}
+			seen[key] = true
+
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
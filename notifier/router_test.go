@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records topics of notified events
+type recorder struct {
+	topics []string
+	err    error
+}
+
+func (r *recorder) Notify(topic string, event *Event) error {
+	r.topics = append(r.topics, topic+":"+string(event.Type))
+	return r.err
+}
+
+func TestRouter(t *testing.T) {
+	pager, slack, sns := &recorder{}, &recorder{}, &recorder{}
+
+	router := NewRouter(
+		&Route{
+			Events: []EventType{EventRenewalFailed},
+			Targets: []Target{
+				{Notifier: pager, Topic: "pager", Events: EventTypes},
+				{Notifier: slack, Topic: "ops", Events: EventTypes},
+			},
+		},
+		&Route{
+			Events:  []EventType{EventCertificateIssued, EventCertificateRenewed},
+			Targets: []Target{{Notifier: sns, Topic: "arn", Events: EventTypes}},
+		},
+		&Route{
+			Targets: []Target{
+				{Notifier: slack, Topic: "ops", Events: EventTypes},
+				{Notifier: slack, Topic: "team", Events: []EventType{EventRenewalFailed}},
+			},
+		},
+	)
+
+	require.NoError(t, router.Notify("", NewEvent(EventRenewalFailed, []string{"example.com"})))
+	require.NoError(t, router.Notify("", NewEvent(EventCertificateRenewed, []string{"example.com"})))
+	require.NoError(t, router.Notify("", NewEvent(EventRenewalSkipped, []string{"example.com"})))
+
+	require.Equal(t, []string{"pager:RenewalFailed"}, pager.topics)
+	require.Equal(t, []string{"ops:RenewalFailed", "team:RenewalFailed", "ops:CertificateRenewed"}, slack.topics)
+	require.Equal(t, []string{"arn:CertificateRenewed"}, sns.topics)
+	require.Equal(t, []string{"pager", "ops", "team"}, router.Topics(EventRenewalFailed))
+	require.Equal(t, []string{"arn", "ops"}, router.Topics(EventCertificateRenewed))
+	require.Empty(t, router.Topics(EventRenewalSkipped))
+}
+
+func TestRouterFailures(t *testing.T) {
+	failing, ok := &recorder{err: errors.New("unavailable")}, &recorder{}
+
+	router := NewRouter(&Route{
+		Targets: []Target{
+			{Notifier: failing, Topic: "first"},
+			{Notifier: ok, Topic: "second"},
+		},
+	})
+
+	err := router.Notify("", NewEvent(EventRenewalFailed, []string{"example.com"}))
+	require.EqualError(t, err, "notifier: 1 target(s) failed: unavailable")
+	require.Len(t, ok.topics, 1)
+}
@@ -0,0 +1,177 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+const (
+	// postMessageURL is the URL of Slack Web API method which posts messages by bot tokens
+	postMessageURL = "https://slack.com/api/chat.postMessage"
+
+	// requestTimeout is the timeout of requests to Slack
+	requestTimeout = 10 * time.Second
+
+	colorGood   = "good"
+	colorDanger = "danger"
+)
+
+// To make sure that slackNotifier implements notifier.Notifier interface
+var _ notifier.Notifier = &slackNotifier{}
+
+// slackNotifier implements notifier.Notifier for Slack
+type slackNotifier struct {
+	url    string // The incoming webhook URL, or the Web API URL if the token is set
+	token  string
+	tmpl   *notifier.Template
+	client *http.Client
+	log    *logrus.Logger
+}
+
+// message is the Slack message with the attachment colored by the result
+type message struct {
+	Channel     string        `json:"channel,omitempty"`
+	Text        string        `json:"text"`
+	Attachments []*attachment `json:"attachments,omitempty"`
+}
+
+type attachment struct {
+	Color  string   `json:"color"`
+	Blocks []*block `json:"blocks"`
+}
+
+type block struct {
+	Type   string  `json:"type"`
+	Text   *text   `json:"text,omitempty"`
+	Fields []*text `json:"fields,omitempty"`
+}
+
+type text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// apiResponse is the response of Slack Web API
+type apiResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// NewWebhook is the constructor of slackNotifier which posts messages rendered by the given template
+// to the given incoming webhook URL. The channel of the webhook is fixed, topics are ignored.
+func NewWebhook(webhookURL string, tmpl *notifier.Template, log *logrus.Logger) notifier.Notifier {
+	return &slackNotifier{
+		url:    webhookURL,
+		tmpl:   tmpl,
+		client: &http.Client{Timeout: requestTimeout},
+		log:    log,
+	}
+}
+
+// NewBot is the constructor of slackNotifier which posts messages rendered by the given template
+// by the given bot token. Topics are channels to post messages to.
+func NewBot(token string, tmpl *notifier.Template, log *logrus.Logger) notifier.Notifier {
+	return &slackNotifier{
+		url:    postMessageURL,
+		token:  token,
+		tmpl:   tmpl,
+		client: &http.Client{Timeout: requestTimeout},
+		log:    log,
+	}
+}
+
+// Notify implements notifier.Notifier interface.
+// Posts the message about the given event to Slack.
+func (n *slackNotifier) Notify(topic string, event *notifier.Event) error {
+	msg := newMessage(event, n.tmpl.Render(event))
+	if len(n.token) > 0 {
+		msg.Channel = topic
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "slack: unable to encode message")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "slack: unable to create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if len(n.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "slack: unable to post message")
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("slack: unable to post message: unexpected response status %d: %s", resp.StatusCode, respBody)
+	}
+
+	// Web API responds with 200 to failed requests too
+	if len(n.token) > 0 {
+		var apiResp apiResponse
+		if err := json.Unmarshal(respBody, &apiResp); err != nil {
+			return errors.Wrap(err, "slack: unable to decode response")
+		}
+
+		if !apiResp.OK {
+			return errors.Errorf("slack: unable to post message: %s", apiResp.Error)
+		}
+	}
+
+	n.log.Infof("slack: message posted successfully")
+
+	return nil
+}
+
+// newMessage creates the message about the given event with the given rendered text
+func newMessage(event *notifier.Event, rendered string) *message {
+	color := colorGood
+	if event.IsFailure() {
+		color = colorDanger
+	}
+
+	blocks := []*block{
+		{Type: "header", Text: &text{Type: "plain_text", Text: event.Title()}},
+		{Type: "section", Text: &text{Type: "plain_text", Text: rendered}},
+	}
+
+	if facts := event.Facts(); len(facts) > 0 {
+		fields := make([]*text, len(facts))
+		for i, fact := range facts {
+			fields[i] = &text{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", fact.Name, escape(fact.Value))}
+		}
+
+		blocks = append(blocks, &block{Type: "section", Fields: fields})
+	}
+
+	return &message{
+		Text:        rendered,
+		Attachments: []*attachment{{Color: color, Blocks: blocks}},
+	}
+}
+
+// escaper escapes control characters of Slack mrkdwn
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escape escapes control characters of Slack mrkdwn in the given value
+func escape(s string) string {
+	return escaper.Replace(s)
+}
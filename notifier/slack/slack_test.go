@@ -0,0 +1,110 @@
+package slack
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+func TestNotify(t *testing.T) {
+	event := notifier.NewEvent(notifier.EventCertificateIssued, []string{"example.com"}).
+		SetExpiry(time.Date(2027, 1, 30, 10, 0, 0, 0, time.UTC))
+	event.CertificateARNs = []string{"arn:aws:acm:us-east-1:123456789012:certificate/1"}
+
+	expectedText := "Certificate for example.com has been issued, it expires 2027-01-30T10:00:00Z."
+
+	testTable := []*struct {
+		testName        string
+		bot             bool
+		status          int
+		response        string
+		expectedChannel string
+		expectedErr     string
+	}{
+		{
+			testName: "webhook",
+			status:   http.StatusOK,
+			response: "ok",
+		},
+		{
+			testName:    "webhook failure",
+			status:      http.StatusNotFound,
+			response:    "no_service",
+			expectedErr: "slack: unable to post message: unexpected response status 404: no_service",
+		},
+		{
+			testName:        "bot",
+			bot:             true,
+			status:          http.StatusOK,
+			response:        `{"ok":true}`,
+			expectedChannel: "#certificates",
+		},
+		{
+			testName:        "bot failure",
+			bot:             true,
+			status:          http.StatusOK,
+			response:        `{"ok":false,"error":"channel_not_found"}`,
+			expectedChannel: "#certificates",
+			expectedErr:     "slack: unable to post message: channel_not_found",
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			var (
+				received message
+				auth     string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				body, _ := ioutil.ReadAll(r.Body)
+				require.NoError(t, json.Unmarshal(body, &received))
+
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			n := NewWebhook(server.URL, notifier.DefaultTemplate, logrus.New()).(*slackNotifier)
+			if tt.bot {
+				n = NewBot("xoxb-token", notifier.DefaultTemplate, logrus.New()).(*slackNotifier)
+				n.url = server.URL
+			}
+
+			err := n.Notify("#certificates", event)
+			if len(tt.expectedErr) > 0 {
+				require.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tt.bot {
+				require.Equal(t, "Bearer xoxb-token", auth)
+			} else {
+				require.Empty(t, auth)
+			}
+
+			require.Equal(t, tt.expectedChannel, received.Channel)
+			require.Equal(t, expectedText, received.Text)
+			require.Len(t, received.Attachments, 1)
+			require.Equal(t, colorGood, received.Attachments[0].Color)
+			require.Equal(t, []*block{
+				{Type: "header", Text: &text{Type: "plain_text", Text: "Certificate issued"}},
+				{Type: "section", Text: &text{Type: "plain_text", Text: expectedText}},
+				{Type: "section", Fields: []*text{
+					{Type: "mrkdwn", Text: "*Domains*\nexample.com"},
+					{Type: "mrkdwn", Text: "*Expires*\n2027-01-30T10:00:00Z"},
+					{Type: "mrkdwn", Text: "*Certificate ARN*\narn:aws:acm:us-east-1:123456789012:certificate/1"},
+				}},
+			}, received.Attachments[0].Blocks)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package teams
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+const (
+	// requestTimeout is the timeout of requests to workflow webhooks
+	requestTimeout = 10 * time.Second
+
+	// adaptiveCardContentType is the content type of attachments with Adaptive Cards
+	adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+
+	adaptiveCardSchema  = "http://adaptivecards.io/schemas/adaptive-card.json"
+	adaptiveCardVersion = "1.4"
+)
+
+// To make sure that teamsNotifier implements notifier.Notifier interface
+var _ notifier.Notifier = &teamsNotifier{}
+
+// teamsNotifier implements notifier.Notifier for Microsoft Teams workflow webhooks
+type teamsNotifier struct {
+	url    string
+	tmpl   *notifier.Template
+	client *http.Client
+	log    *logrus.Logger
+}
+
+// message is the message with the Adaptive Card posted to the workflow
+type message struct {
+	Type        string        `json:"type"`
+	Attachments []*attachment `json:"attachments"`
+}
+
+type attachment struct {
+	ContentType string `json:"contentType"`
+	Content     *card  `json:"content"`
+}
+
+type card struct {
+	Schema  string     `json:"$schema"`
+	Type    string     `json:"type"`
+	Version string     `json:"version"`
+	Body    []*element `json:"body"`
+}
+
+type element struct {
+	Type   string  `json:"type"`
+	Text   string  `json:"text,omitempty"`
+	Weight string  `json:"weight,omitempty"`
+	Size   string  `json:"size,omitempty"`
+	Color  string  `json:"color,omitempty"`
+	Wrap   bool    `json:"wrap,omitempty"`
+	Facts  []*fact `json:"facts,omitempty"`
+}
+
+type fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// New is the constructor of teamsNotifier which posts messages rendered by the given template
+// to the given workflow webhook URL. The channel of the workflow is fixed, topics are ignored.
+func New(webhookURL string, tmpl *notifier.Template, log *logrus.Logger) notifier.Notifier {
+	return &teamsNotifier{
+		url:    webhookURL,
+		tmpl:   tmpl,
+		client: &http.Client{Timeout: requestTimeout},
+		log:    log,
+	}
+}
+
+// Notify implements notifier.Notifier interface.
+// Posts the Adaptive Card about the given event to Microsoft Teams.
+func (n *teamsNotifier) Notify(topic string, event *notifier.Event) error {
+	body, err := json.Marshal(newMessage(event, n.tmpl.Render(event)))
+	if err != nil {
+		return errors.Wrap(err, "teams: unable to encode message")
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "teams: unable to post message")
+	}
+	defer resp.Body.Close()
+
+	// Workflows respond with 202 Accepted
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("teams: unable to post message: unexpected response status %d: %s", resp.StatusCode, respBody)
+	}
+
+	n.log.Infof("teams: message posted successfully")
+
+	return nil
+}
+
+// newMessage creates the message with the Adaptive Card about the given event with the given rendered text
+func newMessage(event *notifier.Event, text string) *message {
+	color := "Good"
+	if event.IsFailure() {
+		color = "Attention"
+	}
+
+	body := []*element{
+		{Type: "TextBlock", Text: event.Title(), Weight: "Bolder", Size: "Medium", Color: color, Wrap: true},
+		{Type: "TextBlock", Text: text, Wrap: true},
+	}
+
+	if facts := event.Facts(); len(facts) > 0 {
+		set := &element{Type: "FactSet"}
+		for _, f := range facts {
+			set.Facts = append(set.Facts, &fact{Title: f.Name, Value: f.Value})
+		}
+
+		body = append(body, set)
+	}
+
+	return &message{
+		Type: "message",
+		Attachments: []*attachment{{
+			ContentType: adaptiveCardContentType,
+			Content: &card{
+				Schema:  adaptiveCardSchema,
+				Type:    "AdaptiveCard",
+				Version: adaptiveCardVersion,
+				Body:    body,
+			},
+		}},
+	}
+}
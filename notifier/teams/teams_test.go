@@ -0,0 +1,48 @@
+package teams
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+func TestNotify(t *testing.T) {
+	var received message
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &received))
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	n := New(server.URL, notifier.MustParseTemplate("{{ .Title }} for {{ join .Domains \", \" }}"), logrus.New())
+
+	event := notifier.NewEvent(notifier.EventRenewalFailed, []string{"example.com"})
+	event.Error = "domain 'example.com' is denied"
+
+	require.NoError(t, n.Notify("", event))
+
+	require.Equal(t, "message", received.Type)
+	require.Len(t, received.Attachments, 1)
+	require.Equal(t, adaptiveCardContentType, received.Attachments[0].ContentType)
+	require.Equal(t, []*element{
+		{Type: "TextBlock", Text: "Certificate renewal failed", Weight: "Bolder", Size: "Medium", Color: "Attention", Wrap: true},
+		{Type: "TextBlock", Text: "Certificate renewal failed for example.com", Wrap: true},
+		{Type: "FactSet", Facts: []*fact{
+			{Title: "Domains", Value: "example.com"},
+			{Title: "Reason", Value: "domain 'example.com' is denied"},
+		}},
+	}, received.Attachments[0].Content.Body)
+
+	status = http.StatusBadRequest
+	require.EqualError(t, n.Notify("", event), "teams: unable to post message: unexpected response status 400: ")
+}
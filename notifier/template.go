@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultTemplateText is the text of the default template of messages of human channels
+const defaultTemplateText = `
+{{- $domains := join .Domains ", " -}}
+{{- if eq .Type "CertificateIssued" -}}
+Certificate for {{ $domains }} has been issued{{ with .Expiry }}, it expires {{ date . }}{{ end }}.
+{{- else if eq .Type "CertificateRenewed" -}}
+Certificate for {{ $domains }} has been renewed{{ with .Expiry }}, it expires {{ date . }}{{ end }}.
+{{- with .ExplanationURL }} Renewal was suggested by the CA: {{ . }}{{ end }}
+{{- else if eq .Type "RenewalSkipped" -}}
+Certificate for {{ $domains }} does not need renewal yet{{ with .Expiry }}, it expires {{ date . }}{{ end }}.
+{{- else if eq .Type "RenewalFailed" -}}
+Certificate for {{ $domains }} could not be issued{{ with .Stage }} at {{ . }} stage{{ end }}: {{ .Error }}
+{{- else if eq .Type "ExpiringSoon" -}}
+Certificate for {{ $domains }} expires soon{{ with .Expiry }}, at {{ date . }}{{ end }}.
+{{- else if eq .Type "Revoked" -}}
+Certificate for {{ $domains }} has been revoked{{ with .Reason }} with reason '{{ . }}'{{ end }}.
+{{- else -}}
+{{ .Type }}: {{ $domains }}
+{{- end }}`
+
+var (
+	// TemplateFuncs are functions available to templates
+	TemplateFuncs = template.FuncMap{
+		"join": strings.Join,
+		"date": func(t time.Time) string { return t.Format(time.RFC3339) },
+	}
+
+	// DefaultTemplate renders the default message of each event type
+	DefaultTemplate = MustParseTemplate(defaultTemplateText)
+)
+
+// Template renders messages of events for human channels.
+// It is the text/template executed with the event, e.g. "{{ .Title }}: {{ join .Domains ", " }}".
+type Template struct {
+	tmpl *template.Template
+}
+
+// ParseTemplate parses the template with the given text
+func ParseTemplate(text string) (*Template, error) {
+	tmpl, err := template.New("message").Funcs(TemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "notifier: invalid template")
+	}
+
+	return &Template{tmpl: tmpl}, nil
+}
+
+// MustParseTemplate parses the template with the given text, and panics if it is invalid
+func MustParseTemplate(text string) *Template {
+	t, err := ParseTemplate(text)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// Render renders the message of the given event.
+// The default message is rendered if the template is nil or fails.
+func (t *Template) Render(event *Event) string {
+	if t != nil {
+		var b bytes.Buffer
+		if err := t.tmpl.Execute(&b, event); err == nil {
+			return b.String()
+		}
+	}
+
+	if t == DefaultTemplate {
+		return event.Title()
+	}
+
+	return DefaultTemplate.Render(event)
+}
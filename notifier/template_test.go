@@ -0,0 +1,67 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateRender(t *testing.T) {
+	expiry := time.Date(2027, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	testTable := []*struct {
+		testName        string
+		template        *Template
+		event           *Event
+		expectedMessage string
+	}{
+		{
+			testName:        "issued",
+			template:        DefaultTemplate,
+			event:           &Event{Type: EventCertificateIssued, Domains: []string{"example.com", "www.example.com"}, Expiry: &expiry},
+			expectedMessage: "Certificate for example.com, www.example.com has been issued, it expires 2027-01-30T10:00:00Z.",
+		},
+		{
+			testName:        "renewed by CA suggestion",
+			template:        DefaultTemplate,
+			event:           &Event{Type: EventCertificateRenewed, Domains: []string{"example.com"}, ExplanationURL: "https://example.net/incident"},
+			expectedMessage: "Certificate for example.com has been renewed. Renewal was suggested by the CA: https://example.net/incident",
+		},
+		{
+			testName:        "failed",
+			template:        DefaultTemplate,
+			event:           &Event{Type: EventRenewalFailed, Domains: []string{"example.com"}, Stage: "challenge", Error: "rate limited"},
+			expectedMessage: "Certificate for example.com could not be issued at challenge stage: rate limited",
+		},
+		{
+			testName:        "revoked",
+			template:        DefaultTemplate,
+			event:           &Event{Type: EventRevoked, Domains: []string{"example.com"}, Reason: "keyCompromise"},
+			expectedMessage: "Certificate for example.com has been revoked with reason 'keyCompromise'.",
+		},
+		{
+			testName:        "custom template",
+			template:        MustParseTemplate(`{{ .Title }}: {{ join .Domains " " }}`),
+			event:           &Event{Type: EventRevoked, Domains: []string{"example.com", "example.org"}},
+			expectedMessage: "Certificate revoked: example.com example.org",
+		},
+		{
+			testName:        "failed custom template",
+			template:        MustParseTemplate(`{{ .Expiry.Year }}`),
+			event:           &Event{Type: EventRevoked, Domains: []string{"example.com"}},
+			expectedMessage: "Certificate for example.com has been revoked.",
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			require.Equal(t, tt.expectedMessage, tt.template.Render(tt.event))
+		})
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	_, err := ParseTemplate("{{ .Domains")
+	require.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+const (
+	// SignatureHeader is the header with HMAC-SHA256 signature of the request body, "sha256=<hex>"
+	SignatureHeader = "X-Signature-256"
+
+	// DefaultTimeout is the default timeout of each request
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultRetries is the default number of retries of failed requests
+	DefaultRetries = 3
+)
+
+var (
+	// initialBackoff is the delay before the first retry, doubled by each next retry, replaced by tests
+	initialBackoff = time.Second
+)
+
+// To make sure that webhookNotifier implements notifier.Notifier interface
+var _ notifier.Notifier = &webhookNotifier{}
+
+// Options is the options of the webhook notifier
+type Options struct {
+	URL string
+
+	// Secret is the key of HMAC-SHA256 signature of requests, requests are not signed if empty
+	Secret string
+
+	// Headers are added to each request
+	Headers map[string]string
+
+	// Timeout is the timeout of each request, DefaultTimeout if zero
+	Timeout time.Duration
+
+	// Retries is the number of retries of requests failed by network errors, 429 or 5xx responses
+	Retries int
+}
+
+// webhookNotifier implements notifier.Notifier which posts JSON events to a webhook
+type webhookNotifier struct {
+	opts   *Options
+	client *http.Client
+	log    *logrus.Logger
+}
+
+// New is the constructor of webhookNotifier, topics are ignored
+func New(opts *Options, log *logrus.Logger) notifier.Notifier {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &webhookNotifier{
+		opts:   opts,
+		client: &http.Client{Timeout: timeout},
+		log:    log,
+	}
+}
+
+// Notify implements notifier.Notifier interface.
+// Posts the given event as JSON to the webhook, retrying with exponential backoff.
+func (n *webhookNotifier) Notify(topic string, event *notifier.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "webhook: unable to encode event")
+	}
+
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(body)
+		if err == nil {
+			n.log.Infof("webhook: event posted successfully")
+			return nil
+		}
+
+		if !retry || attempt >= n.opts.Retries {
+			return errors.Wrap(err, "webhook: unable to post event")
+		}
+
+		n.log.Warnf("webhook: unable to post event, retrying in %s: %s", backoff, err)
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post posts the given body once, and returns whether the request may be retried if it failed
+func (n *webhookNotifier) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, n.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range n.opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if len(n.opts.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.opts.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+
+	return retry, errors.Errorf("unexpected response status %d: %s", resp.StatusCode, respBody)
+}
+
+// Sign returns the value of the signature header of the given body signed with the given secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/notifier"
+)
+
+func TestNotify(t *testing.T) {
+	initialBackoff = time.Millisecond
+
+	testTable := []*struct {
+		testName         string
+		statuses         []int
+		retries          int
+		expectedRequests int
+		expectedErr      string
+	}{
+		{
+			testName:         "success",
+			statuses:         []int{http.StatusNoContent},
+			retries:          3,
+			expectedRequests: 1,
+		},
+		{
+			testName:         "retried server errors",
+			statuses:         []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK},
+			retries:          3,
+			expectedRequests: 3,
+		},
+		{
+			testName:         "retries exhausted",
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			retries:          1,
+			expectedRequests: 2,
+			expectedErr:      "webhook: unable to post event: unexpected response status 503: unavailable",
+		},
+		{
+			testName:         "client error",
+			statuses:         []int{http.StatusUnauthorized},
+			retries:          3,
+			expectedRequests: 1,
+			expectedErr:      "webhook: unable to post event: unexpected response status 401: unavailable",
+		},
+	}
+
+	for _, tt := range testTable {
+		t.Run(tt.testName, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+
+				require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				require.Equal(t, "Bearer deploy", r.Header.Get("Authorization"))
+				require.Equal(t, Sign("s3cret", body), r.Header.Get(SignatureHeader))
+
+				var e notifier.Event
+				require.NoError(t, json.Unmarshal(body, &e))
+				require.Equal(t, notifier.EventCertificateIssued, e.Type)
+				require.Equal(t, []string{"example.com"}, e.Domains)
+				require.Equal(t, time.Date(2027, 1, 30, 10, 0, 0, 0, time.UTC), *e.Expiry)
+
+				status := tt.statuses[requests]
+				requests++
+
+				w.WriteHeader(status)
+				if status >= 300 {
+					w.Write([]byte("unavailable"))
+				}
+			}))
+			defer server.Close()
+
+			n := New(&Options{
+				URL:     server.URL,
+				Secret:  "s3cret",
+				Headers: map[string]string{"Authorization": "Bearer deploy"},
+				Retries: tt.retries,
+			}, logrus.New())
+
+			err := n.Notify("", notifier.NewEvent(notifier.EventCertificateIssued, []string{"example.com"}).
+				SetExpiry(time.Date(2027, 1, 30, 10, 0, 0, 0, time.UTC)))
+			if len(tt.expectedErr) > 0 {
+				require.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tt.expectedRequests, requests)
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	require.Equal(t, "sha256=77325902caca812dc259733aacd046b73817372c777b8d95b402647474516e13", Sign("secret", []byte(`{}`)))
+}
@@ -0,0 +1,349 @@
+package runner
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/sirupsen/logrus"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/certstore/acmstore"
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/deploy"
+	"github.com/begmaroman/acme-dns-route53/handler"
+	"github.com/begmaroman/acme-dns-route53/handler/r53dns"
+	"github.com/begmaroman/acme-dns-route53/notifier"
+	"github.com/begmaroman/acme-dns-route53/notifier/awsns"
+	"github.com/begmaroman/acme-dns-route53/notifier/email"
+	"github.com/begmaroman/acme-dns-route53/notifier/slack"
+	"github.com/begmaroman/acme-dns-route53/notifier/teams"
+	"github.com/begmaroman/acme-dns-route53/notifier/webhook"
+)
+
+// Options is the options of certificate handlers built for groups
+type Options struct {
+	Session    *session.Session
+	ConfigDir  string
+	DisableARI bool
+	DryRun     bool // Plans changes without requesting certificates, changing records and stores or notifying
+	Log        *logrus.Logger
+
+	// DisableCommands skips deploy hooks running local commands, e.g. in Lambda where there are no services to reload
+	DisableCommands bool
+}
+
+// Job is the certificate group with the handler processing it
+type Job struct {
+	Group   *config.Group
+	Handler *handler.CertificateHandler
+	DNS01   r53dns.Provider
+
+	// Notifications are the notification targets of the group
+	Notifications []notifier.Target
+
+	logger *logrus.Logger
+}
+
+// NewJobs builds jobs of the given groups
+func NewJobs(groups []*config.Group, opts *Options) []*Job {
+	jobs := make([]*Job, len(groups))
+	for i, group := range groups {
+		jobs[i] = NewJob(group, opts)
+	}
+
+	return jobs
+}
+
+// NewJob builds the job of the given group
+func NewJob(group *config.Group, opts *Options) *Job {
+	dns01 := r53dns.NewWithHostedZones(opts.Session, group.HostedZones, opts.Log)
+
+	stores := make([]certstore.CertStore, len(group.Stores))
+	for i, store := range group.Stores {
+		stores[i] = acmstore.New(regionalSession(opts.Session, store.Region), opts.Log)
+	}
+
+	notifications := NewGroupTargets(group, opts)
+
+	return &Job{
+		Group:         group,
+		DNS01:         dns01,
+		Notifications: notifications,
+		logger:        opts.Log,
+		Handler: handler.NewCertificateHandler(&handler.CertificateHandlerOptions{
+			GroupName:       group.Name,
+			CADirURL:        group.CADirURL(),
+			KeyType:         group.CertKeyType(),
+			ConfigDir:       opts.ConfigDir,
+			RenewBefore:     group.RenewBefore * 24,
+			DisableARI:      opts.DisableARI,
+			Policy:          newPolicy(group.Policy()),
+			DisableCAA:      group.CAA == config.CAADisabled,
+			CAAIdentities:   group.CAAIdentities,
+			CAAUpdater:      caaUpdater(group, dns01),
+			DryRun:          opts.DryRun,
+			FailureInterval: group.FailureInterval,
+			Log:             opts.Log,
+			Notifications:   notifications,
+			DNS01:           dns01,
+			Store:           certstore.NewMulti(stores...),
+			Hooks:           newHooks(group.Deploy, opts),
+		}),
+	}
+}
+
+// Name returns the name of the job's group
+func (j *Job) Name() string {
+	return j.Group.Name
+}
+
+// DomainsString returns the domains of the job's group joined by comma
+func (j *Job) DomainsString() string {
+	return strings.Join(j.Group.Domains, ", ")
+}
+
+// log returns the logger of the job
+func (j *Job) log() *logrus.Logger {
+	if j.logger == nil {
+		return logrus.StandardLogger()
+	}
+
+	return j.logger
+}
+
+// NewTargets creates notification targets of the given configured notifications
+func NewTargets(notifications []*config.Notification, opts *Options) []notifier.Target {
+	targets := make([]notifier.Target, len(notifications))
+	for i, notification := range notifications {
+		targets[i] = newTarget(notification, opts)
+	}
+
+	return targets
+}
+
+// NewGroupTargets creates notification targets of the given group: targets of its notifications,
+// and the router which dispatches events to notifiers of the configured routes which apply to the group
+func NewGroupTargets(group *config.Group, opts *Options) []notifier.Target {
+	targets := NewTargets(group.Notifications, opts)
+
+	groupRoutes := group.Routes()
+	if len(groupRoutes) == 0 {
+		return targets
+	}
+
+	// Each notifier is created once, so the router notifies it once even if several routes match the event
+	notifiers := make(map[*config.Notification]notifier.Target)
+
+	routes := make([]*notifier.Route, len(groupRoutes))
+	for i, groupRoute := range groupRoutes {
+		route := &notifier.Route{Events: eventTypes(groupRoute.Events)}
+		for _, notification := range groupRoute.Notifications {
+			target, ok := notifiers[notification]
+			if !ok {
+				target = newTarget(notification, opts)
+				if len(notification.Events) == 0 {
+					// Routes select events of notifiers which do not select them
+					target.Events = notifier.EventTypes
+				}
+				notifiers[notification] = target
+			}
+
+			route.Targets = append(route.Targets, target)
+		}
+
+		routes[i] = route
+	}
+
+	return append(targets, notifier.Target{Notifier: notifier.NewRouter(routes...), Events: notifier.EventTypes})
+}
+
+// newTarget creates the notification target of the given configured notification.
+// Topics of webhooks, which are fixed by webhook URLs, are their hosts. Topics of emails are their recipients.
+func newTarget(notification *config.Notification, opts *Options) notifier.Target {
+	target := notifier.Target{Events: eventTypes(notification.Events)}
+
+	// The template is validated by the configuration
+	tmpl := notifier.DefaultTemplate
+	if len(notification.Template) > 0 {
+		tmpl = notifier.MustParseTemplate(notification.Template)
+	}
+
+	switch notification.Type {
+	case config.NotificationTypeSlack:
+		if len(notification.Token) > 0 {
+			target.Notifier = slack.NewBot(notification.Token, tmpl, opts.Log)
+			target.Topic = notification.Channel
+		} else {
+			target.Notifier = slack.NewWebhook(notification.URL, tmpl, opts.Log)
+			target.Topic = urlHost(notification.URL)
+		}
+	case config.NotificationTypeTeams:
+		target.Notifier = teams.New(notification.URL, tmpl, opts.Log)
+		target.Topic = urlHost(notification.URL)
+	case config.NotificationTypeWebhook:
+		retries := webhook.DefaultRetries
+		if notification.Retries != nil {
+			retries = *notification.Retries
+		}
+
+		target.Notifier = webhook.New(&webhook.Options{
+			URL:     notification.URL,
+			Secret:  notification.Secret,
+			Headers: notification.Headers,
+			Timeout: notification.Timeout,
+			Retries: retries,
+		}, opts.Log)
+		target.Topic = urlHost(notification.URL)
+	case config.NotificationTypeSMTP:
+		target.Notifier = email.NewSMTP(&email.SMTPOptions{
+			Host:     notification.Host,
+			Port:     notification.Port,
+			Username: notification.Username,
+			Password: notification.Password,
+			TLS:      notification.TLS,
+			From:     notification.From,
+		}, tmpl, htmlTemplate(notification), opts.Log)
+		target.Topic = email.Topic(notification.To)
+	case config.NotificationTypeSES:
+		target.Notifier = email.NewSES(regionalSession(opts.Session, notification.Region), notification.From, tmpl, htmlTemplate(notification), opts.Log)
+		target.Topic = email.Topic(notification.To)
+	default:
+		target.Notifier = awsns.New(regionalSession(opts.Session, arnRegion(notification.Topic)), opts.Log)
+		target.Topic = notification.Topic
+	}
+
+	return target
+}
+
+// newHooks creates deploy hooks of the given configured hooks
+func newHooks(hooks []*config.Hook, opts *Options) []deploy.Target {
+	var targets []deploy.Target
+	for _, hook := range hooks {
+		target := deploy.Target{Name: hook.HookName(), Timeout: hook.Timeout}
+
+		switch hook.Type {
+		case config.HookTypeCommand:
+			if opts.DisableCommands {
+				opts.Log.Warnf("runner: deploy hook '%s' is skipped since local commands are disabled", target.Name)
+				continue
+			}
+
+			target.Hook = deploy.NewCommand(hook.Command, hook.Dir)
+		case config.HookTypeLambda:
+			target.Hook = deploy.NewLambda(regionalSession(opts.Session, hook.Region), hook.Function)
+		case config.HookTypeSSM:
+			target.Hook = deploy.NewSSM(regionalSession(opts.Session, hook.Region), &deploy.SSMOptions{
+				Command:     hook.Command,
+				InstanceIDs: hook.InstanceIDs,
+				Targets:     hook.Targets,
+			})
+		case config.HookTypeEventBridge:
+			target.Hook = deploy.NewEventBridge(regionalSession(opts.Session, hook.Region))
+		case config.HookTypeELBv2:
+			target.Hook = deploy.NewListener(regionalSession(opts.Session, arnRegion(hook.Listener)), hook.Listener, hook.DefaultCertificate)
+		case config.HookTypeCloudFront:
+			target.Hook = deploy.NewDistribution(opts.Session, hook.Distribution)
+		case config.HookTypeAPIGateway:
+			sess := regionalSession(opts.Session, hook.Region)
+			target.Hook = deploy.NewDomainName(sess, aws.StringValue(sess.Config.Region), hook.DomainName)
+		default:
+			continue
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// eventTypes converts the given configured names of event types, nil if there are no names
+func eventTypes(names []string) []notifier.EventType {
+	if len(names) == 0 {
+		return nil
+	}
+
+	types := make([]notifier.EventType, len(names))
+	for i, name := range names {
+		types[i] = notifier.EventType(name)
+	}
+
+	return types
+}
+
+// htmlTemplate returns the HTML template of emails of the given configured notification, nil for the default one.
+// The template is validated by the configuration.
+func htmlTemplate(notification *config.Notification) *email.HTMLTemplate {
+	if len(notification.HTMLTemplate) == 0 {
+		return nil
+	}
+
+	return email.MustParseHTMLTemplate(notification.HTMLTemplate)
+}
+
+// newPolicy converts the given configured policy into the policy of certificate handlers
+func newPolicy(p *config.Policy) *handler.Policy {
+	if p == nil {
+		return nil
+	}
+
+	keyTypes := make([]certcrypto.KeyType, len(p.AllowedKeyTypes))
+	for i, name := range p.AllowedKeyTypes {
+		keyTypes[i] = config.KeyType(name)
+	}
+
+	caDirURLs := make([]string, len(p.AllowedCAs))
+	for i, ca := range p.AllowedCAs {
+		caDirURLs[i] = config.CADirURL(ca)
+	}
+
+	return &handler.Policy{
+		AllowedSuffixes:  p.AllowedSuffixes,
+		DeniedNames:      p.DeniedNames,
+		DenyWildcard:     p.AllowWildcard != nil && !*p.AllowWildcard,
+		MaxSANs:          p.MaxSANs,
+		AllowedKeyTypes:  keyTypes,
+		AllowedCADirURLs: caDirURLs,
+	}
+}
+
+// caaUpdater returns the given DNS provider if CAA records of the given group must be updated, otherwise nil
+func caaUpdater(group *config.Group, dns01 r53dns.Provider) handler.CAAUpdater {
+	if group.CAA != config.CAAUpsert {
+		return nil
+	}
+
+	return dns01
+}
+
+// regionalSession returns the copy of the given session for the given region, or the session itself if the region is empty
+func regionalSession(sess *session.Session, region string) *session.Session {
+	if len(region) == 0 {
+		return sess
+	}
+
+	return sess.Copy(&aws.Config{Region: aws.String(region)})
+}
+
+// arnRegion returns the region of the given ARN, or empty string if it is not a valid ARN
+func arnRegion(val string) string {
+	parsed, err := arn.Parse(val)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Region
+}
+
+// urlHost returns the host of the given URL, or empty string if it is not a valid URL
+func urlHost(val string) string {
+	u, err := url.Parse(val)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
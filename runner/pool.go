@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/begmaroman/acme-dns-route53/handler"
+)
+
+const (
+	// DefaultParallelism is the default number of certificate groups processed at once
+	DefaultParallelism = 5
+)
+
+// JobFunc is the function processing the certificate group of the job
+type JobFunc func(job *Job) (*handler.Result, error)
+
+// Run runs the given function for each job and aggregates the results in the order of jobs.
+// At most parallelism jobs run at once, and jobs of certificates which expire first are started first,
+// so the most urgent certificates are processed even if the time is over before all jobs are done.
+// New jobs are not started once the given context is done, they are reported as unprocessed.
+// Running jobs are always waited for, since an ACME order cannot be interrupted without leaving its challenges behind.
+func Run(ctx context.Context, jobs []*Job, parallelism int, fn JobFunc) *Summary {
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+
+	results := make([]*Result, len(jobs))
+
+	forEach(ctx, orderByExpiry(ctx, jobs, parallelism), parallelism, func(i int) {
+		job := jobs[i]
+
+		start := time.Now()
+		result, err := fn(job)
+		if err != nil {
+			job.log().Errorf("[%s] unable to process certificate: %s", job.DomainsString(), err)
+		}
+
+		results[i] = NewResult(job, result, err, time.Since(start))
+	})
+
+	for i, job := range jobs {
+		if results[i] == nil {
+			results[i] = UnprocessedResult(job)
+		}
+	}
+
+	return NewSummary(results)
+}
+
+// orderByExpiry returns indexes of the given jobs ordered by expiration time of their certificates.
+// Jobs of missing certificates go first.
+func orderByExpiry(ctx context.Context, jobs []*Job, parallelism int) []int {
+	notAfter := make([]time.Time, len(jobs))
+	order := make([]int, len(jobs))
+	for i := range jobs {
+		order[i] = i
+	}
+
+	forEach(ctx, order, parallelism, func(i int) {
+		var err error
+		if notAfter[i], err = jobs[i].Handler.NotAfter(jobs[i].Group.Domains); err != nil {
+			jobs[i].log().Warnf("[%s] unable to load expiration time of certificate: %s", jobs[i].DomainsString(), err)
+		}
+	})
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return notAfter[order[a]].Before(notAfter[order[b]])
+	})
+
+	return order
+}
+
+// forEach calls the given function for each of the given indexes in their order by at most parallelism workers.
+// The function is not called for the rest of indexes once the given context is done.
+func forEach(ctx context.Context, indexes []int, parallelism int, fn func(i int)) {
+	queue := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism && w < len(indexes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range queue {
+				// The queue may pass an index while the context is being done
+				if ctx.Err() != nil {
+					continue
+				}
+
+				fn(i)
+			}
+		}()
+	}
+
+enqueue:
+	for _, i := range indexes {
+		select {
+		case <-ctx.Done():
+			break enqueue
+		case queue <- i:
+		}
+	}
+	close(queue)
+
+	wg.Wait()
+}
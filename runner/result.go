@@ -0,0 +1,266 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/begmaroman/acme-dns-route53/deploy"
+	"github.com/begmaroman/acme-dns-route53/handler"
+)
+
+// Result is the result of processing a certificate group
+type Result struct {
+	Group           string           `json:"group"`
+	Domains         []string         `json:"domains"`
+	Outcome         handler.Outcome  `json:"outcome"`
+	CertificateARNs []string         `json:"certificate_arns,omitempty"`
+	Serial          string           `json:"serial,omitempty"`
+	OldExpiry       *time.Time       `json:"old_expiry,omitempty"`
+	NewExpiry       *time.Time       `json:"new_expiry,omitempty"`
+	Duration        float64          `json:"duration_seconds"`
+	Error           string           `json:"error,omitempty"`
+	Deployments     []*deploy.Result `json:"deployments,omitempty"`
+	Plan            *Plan            `json:"plan,omitempty"`
+}
+
+// Plan describes changes which processing of a certificate group would make, it is set by dry runs only
+type Plan struct {
+	HostedZones   []string `json:"hosted_zones,omitempty"`
+	CAAUpdates    []string `json:"caa_updates,omitempty"`
+	Stores        []string `json:"stores,omitempty"`
+	Notifications []string `json:"notifications,omitempty"`
+	Deployments   []string `json:"deployments,omitempty"`
+}
+
+// Summary is the aggregated result of processing certificate groups
+type Summary struct {
+	Results      []*Result `json:"results"`
+	Issued       int       `json:"issued"`
+	Renewed      int       `json:"renewed"`
+	Skipped      int       `json:"skipped"`
+	Revoked      int       `json:"revoked"`
+	Rejected     int       `json:"rejected"`
+	Failed       int       `json:"failed"`
+	Unprocessed  int       `json:"unprocessed"`
+	DeployFailed int       `json:"deploy_failed,omitempty"` // Stored certificates whose deploy hooks failed
+	DryRun       bool      `json:"dry_run,omitempty"`
+}
+
+// FailedError is the error returned when processing of some groups failed or was rejected by the policy,
+// or deploy hooks of their certificates failed
+type FailedError struct {
+	Results []*Result
+}
+
+// Error implements error interface
+func (e *FailedError) Error() string {
+	failed := make([]string, len(e.Results))
+	for i, result := range e.Results {
+		if len(result.Error) > 0 {
+			failed[i] = fmt.Sprintf("%s: %s", result.Group, result.Error)
+		} else {
+			failed[i] = fmt.Sprintf("%s: deploy hook(s) failed: %s", result.Group, strings.Join(result.failedDeployments(), ", "))
+		}
+	}
+
+	return fmt.Sprintf("runner: %d certificate group(s) failed: %s", len(e.Results), strings.Join(failed, "; "))
+}
+
+// NewResult creates the result of the given job from the result and the error of its function which took the given duration
+func NewResult(job *Job, result *handler.Result, err error, duration time.Duration) *Result {
+	r := &Result{
+		Group:    job.Name(),
+		Domains:  job.Group.Domains,
+		Outcome:  handler.OutcomeFailed,
+		Duration: duration.Seconds(),
+	}
+
+	if result != nil {
+		r.Outcome = result.Outcome
+		r.CertificateARNs = result.CertificateIDs
+		r.Serial = result.Serial
+		r.OldExpiry = timePtr(result.OldNotAfter)
+		r.NewExpiry = timePtr(result.NewNotAfter)
+		r.Deployments = result.Deployments
+		r.Plan = newPlan(job, result.Plan)
+	}
+
+	if err != nil {
+		if r.Outcome != handler.OutcomeRejected {
+			r.Outcome = handler.OutcomeFailed
+		}
+		r.Error = err.Error()
+	}
+
+	return r
+}
+
+// newPlan creates the plan of the given job from the given plan of its handler, nil if there is no plan
+func newPlan(job *Job, plan *handler.Plan) *Plan {
+	if plan == nil {
+		return nil
+	}
+
+	stores := make([]string, len(job.Group.Stores))
+	for i, store := range job.Group.Stores {
+		stores[i] = store.Type
+		if len(store.Region) > 0 {
+			stores[i] = fmt.Sprintf("%s (%s)", store.Type, store.Region)
+		}
+	}
+
+	return &Plan{
+		HostedZones:   plan.HostedZones,
+		CAAUpdates:    plan.CAAUpdates,
+		Stores:        stores,
+		Notifications: plan.Notifications,
+		Deployments:   plan.Deployments,
+	}
+}
+
+// failedDeployments returns names of deploy hooks which failed
+func (r *Result) failedDeployments() []string {
+	var names []string
+	for _, deployment := range r.Deployments {
+		if len(deployment.Error) > 0 {
+			names = append(names, deployment.Name)
+		}
+	}
+
+	return names
+}
+
+// UnprocessedResult creates the result of the given job which was not processed
+func UnprocessedResult(job *Job) *Result {
+	return &Result{
+		Group:   job.Name(),
+		Domains: job.Group.Domains,
+		Outcome: handler.OutcomeUnprocessed,
+	}
+}
+
+// timePtr returns the pointer to the given time, or nil if it is zero
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+// NewSummary aggregates the given results
+func NewSummary(results []*Result) *Summary {
+	s := &Summary{Results: results}
+	for _, result := range results {
+		switch result.Outcome {
+		case handler.OutcomeIssued:
+			s.Issued++
+		case handler.OutcomeRenewed:
+			s.Renewed++
+		case handler.OutcomeSkipped:
+			s.Skipped++
+		case handler.OutcomeRevoked:
+			s.Revoked++
+		case handler.OutcomeRejected:
+			s.Rejected++
+		case handler.OutcomeFailed:
+			s.Failed++
+		case handler.OutcomeUnprocessed:
+			s.Unprocessed++
+		}
+
+		if len(result.failedDeployments()) > 0 {
+			s.DeployFailed++
+		}
+	}
+
+	return s
+}
+
+// UnprocessedResults returns results of groups which were not processed
+func (s *Summary) UnprocessedResults() []*Result {
+	var unprocessed []*Result
+	for _, result := range s.Results {
+		if result.Outcome == handler.OutcomeUnprocessed {
+			unprocessed = append(unprocessed, result)
+		}
+	}
+
+	return unprocessed
+}
+
+// Err returns *FailedError if processing of any group failed or was rejected by the policy,
+// or deploy hooks of any certificate failed, otherwise nil
+func (s *Summary) Err() error {
+	if s.Failed+s.Rejected+s.DeployFailed == 0 {
+		return nil
+	}
+
+	failed := make([]*Result, 0, s.Failed+s.Rejected+s.DeployFailed)
+	for _, result := range s.Results {
+		if result.Outcome == handler.OutcomeFailed || result.Outcome == handler.OutcomeRejected || len(result.failedDeployments()) > 0 {
+			failed = append(failed, result)
+		}
+	}
+
+	return &FailedError{Results: failed}
+}
+
+// String returns the human-readable summary with the result of each group
+func (s *Summary) String() string {
+	var b strings.Builder
+
+	if s.DryRun {
+		b.WriteString("Dry run: no certificates requested, no records, stores or notifications changed\n")
+	}
+
+	fmt.Fprintf(&b, "Summary: %d issued, %d renewed, %d skipped, %d revoked, %d failed",
+		s.Issued, s.Renewed, s.Skipped, s.Revoked, s.Failed)
+	if s.Rejected > 0 {
+		fmt.Fprintf(&b, ", %d rejected", s.Rejected)
+	}
+	if s.Unprocessed > 0 {
+		fmt.Fprintf(&b, ", %d unprocessed", s.Unprocessed)
+	}
+	if s.DeployFailed > 0 {
+		fmt.Fprintf(&b, ", %d deploy failed", s.DeployFailed)
+	}
+	b.WriteString("\n")
+
+	for _, result := range s.Results {
+		fmt.Fprintf(&b, "  %-8s %s (%s) in %.1fs", result.Outcome, result.Group, strings.Join(result.Domains, ", "), result.Duration)
+		if result.NewExpiry != nil {
+			fmt.Fprintf(&b, ", expires %s", result.NewExpiry.Format(time.RFC3339))
+		}
+		if len(result.Error) > 0 {
+			fmt.Fprintf(&b, ": %s", result.Error)
+		}
+		b.WriteString("\n")
+
+		for _, deployment := range result.Deployments {
+			status := "ok"
+			if len(deployment.Error) > 0 {
+				status = "failed: " + deployment.Error
+			}
+			fmt.Fprintf(&b, "           deploy %s in %.1fs: %s\n", deployment.Name, deployment.Duration, status)
+		}
+
+		if p := result.Plan; p != nil {
+			writePlanLine(&b, "hosted zones", p.HostedZones)
+			writePlanLine(&b, "CAA updates", p.CAAUpdates)
+			writePlanLine(&b, "stores", p.Stores)
+			writePlanLine(&b, "notifications", p.Notifications)
+			writePlanLine(&b, "deployments", p.Deployments)
+		}
+	}
+
+	return b.String()
+}
+
+// writePlanLine writes the line with the given planned changes, nothing if there are no changes
+func writePlanLine(b *strings.Builder, name string, values []string) {
+	if len(values) > 0 {
+		fmt.Fprintf(b, "           %s: %s\n", name, strings.Join(values, ", "))
+	}
+}
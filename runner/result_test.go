@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/certificate"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/begmaroman/acme-dns-route53/certstore"
+	"github.com/begmaroman/acme-dns-route53/config"
+	"github.com/begmaroman/acme-dns-route53/deploy"
+	"github.com/begmaroman/acme-dns-route53/handler"
+)
+
+// expiryStore is the CertStore which contains certificates of the given domains with the given expiration time
+type expiryStore map[string]time.Time
+
+func (s expiryStore) Store(*certificate.Resource, []string) ([]string, error) { return nil, nil }
+func (s expiryStore) Delete([]string) error                                   { return nil }
+func (s expiryStore) List() ([]*certstore.CertificateDetails, error)          { return nil, nil }
+
+func (s expiryStore) Load(domains []string) (*certstore.CertificateDetails, error) {
+	notAfter, ok := s[domains[0]]
+	if !ok {
+		return nil, nil
+	}
+
+	return &certstore.CertificateDetails{Domains: domains, NotAfter: notAfter}, nil
+}
+
+// newTestJobs creates jobs of groups with the given names, each group has one domain equal to its name
+func newTestJobs(store certstore.CertStore, names ...string) []*Job {
+	h := handler.NewCertificateHandler(&handler.CertificateHandlerOptions{
+		Store: store,
+		Log:   logrus.New(),
+	})
+
+	jobs := make([]*Job, len(names))
+	for i, name := range names {
+		jobs[i] = &Job{
+			Group:   &config.Group{Name: name, Domains: []string{name}},
+			Handler: h,
+		}
+	}
+
+	return jobs
+}
+
+func TestRun(t *testing.T) {
+	jobs := newTestJobs(expiryStore{}, "issued", "skipped", "failed", "renewed")
+
+	newExpiry := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	summary := Run(context.Background(), jobs, 0, func(job *Job) (*handler.Result, error) {
+		if job.Name() == "failed" {
+			return &handler.Result{Outcome: handler.OutcomeFailed}, errors.New("rate limited")
+		}
+
+		return &handler.Result{
+			Outcome:        handler.Outcome(job.Name()),
+			CertificateIDs: []string{"arn:aws:acm:us-east-1:123456789012:certificate/" + job.Name()},
+			Serial:         "3a",
+			NewNotAfter:    newExpiry,
+		}, nil
+	})
+
+	require.Equal(t, 1, summary.Issued)
+	require.Equal(t, 1, summary.Renewed)
+	require.Equal(t, 1, summary.Skipped)
+	require.Equal(t, 1, summary.Failed)
+
+	require.Len(t, summary.Results, 4)
+	require.Equal(t, []string{"arn:aws:acm:us-east-1:123456789012:certificate/issued"}, summary.Results[0].CertificateARNs)
+	require.Equal(t, "3a", summary.Results[0].Serial)
+	require.Equal(t, &newExpiry, summary.Results[0].NewExpiry)
+	require.Nil(t, summary.Results[0].OldExpiry)
+	require.Equal(t, "failed", summary.Results[2].Group)
+	require.Equal(t, handler.OutcomeFailed, summary.Results[2].Outcome)
+	require.Equal(t, "rate limited", summary.Results[2].Error)
+
+	err := summary.Err()
+	require.Error(t, err)
+	require.Equal(t, "runner: 1 certificate group(s) failed: failed: rate limited", err.Error())
+
+	require.Contains(t, summary.String(), "Summary: 1 issued, 1 renewed, 1 skipped, 0 revoked, 1 failed\n")
+}
+
+func TestRunOrderAndParallelism(t *testing.T) {
+	now := time.Now()
+	jobs := newTestJobs(expiryStore{
+		"later.example.com": now.Add(60 * 24 * time.Hour),
+		"soon.example.com":  now.Add(2 * 24 * time.Hour),
+		"month.example.com": now.Add(30 * 24 * time.Hour),
+	}, "later.example.com", "missing.example.com", "soon.example.com", "month.example.com")
+
+	var (
+		lock    sync.Mutex
+		order   []string
+		running int
+		maxRun  int
+	)
+
+	summary := Run(context.Background(), jobs, 1, func(job *Job) (*handler.Result, error) {
+		lock.Lock()
+		order = append(order, job.Name())
+		running++
+		if running > maxRun {
+			maxRun = running
+		}
+		lock.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		lock.Lock()
+		running--
+		lock.Unlock()
+
+		return &handler.Result{Outcome: handler.OutcomeSkipped}, nil
+	})
+
+	require.Equal(t, 1, maxRun)
+	require.Equal(t, []string{"missing.example.com", "soon.example.com", "month.example.com", "later.example.com"}, order)
+	require.Equal(t, "later.example.com", summary.Results[0].Group)
+}
+
+func TestSummaryErr(t *testing.T) {
+	summary := NewSummary([]*Result{
+		{Group: "example.com", Outcome: handler.OutcomeSkipped},
+	})
+
+	require.NoError(t, summary.Err())
+
+	summary = NewSummary([]*Result{
+		{Group: "example.com", Outcome: handler.OutcomeSkipped},
+		{Group: "example.org", Outcome: handler.OutcomeRejected, Error: "handler: rejected by policy: key type '4096' is not allowed"},
+	})
+
+	require.Equal(t, 1, summary.Rejected)
+	require.EqualError(t, summary.Err(), "runner: 1 certificate group(s) failed: example.org: handler: rejected by policy: key type '4096' is not allowed")
+}
+
+func TestRunCancelled(t *testing.T) {
+	jobs := newTestJobs(expiryStore{}, "first.example.com", "second.example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	summary := Run(ctx, jobs, 1, func(job *Job) (*handler.Result, error) {
+		// Stop starting new jobs while the first one is running
+		cancel()
+
+		return &handler.Result{Outcome: handler.OutcomeIssued}, nil
+	})
+
+	require.Equal(t, 1, summary.Issued)
+	require.Equal(t, 1, summary.Unprocessed)
+	require.Len(t, summary.UnprocessedResults(), 1)
+	require.NoError(t, summary.Err())
+}
+
+func TestSummaryDryRun(t *testing.T) {
+	summary := NewSummary([]*Result{
+		{
+			Group:   "example.com",
+			Domains: []string{"example.com"},
+			Outcome: handler.OutcomeIssued,
+			Plan: &Plan{
+				HostedZones:   []string{"Z1"},
+				Stores:        []string{"acm (us-east-1)"},
+				Notifications: []string{"arn:aws:sns:us-east-1:123456789012:certificates"},
+			},
+		},
+	})
+	summary.DryRun = true
+
+	require.Equal(t, "Dry run: no certificates requested, no records, stores or notifications changed\n"+
+		"Summary: 1 issued, 0 renewed, 0 skipped, 0 revoked, 0 failed\n"+
+		"  issued   example.com (example.com) in 0.0s\n"+
+		"           hosted zones: Z1\n"+
+		"           stores: acm (us-east-1)\n"+
+		"           notifications: arn:aws:sns:us-east-1:123456789012:certificates\n", summary.String())
+}
+
+func TestSummaryDeployFailed(t *testing.T) {
+	summary := NewSummary([]*Result{
+		{
+			Group:    "web",
+			Domains:  []string{"example.com"},
+			Outcome:  handler.OutcomeRenewed,
+			Duration: 2,
+			Deployments: []*deploy.Result{
+				{Name: "haproxy", Duration: 0.5},
+				{Name: "keystore", Duration: 1, Error: "exit status 1"},
+			},
+		},
+	})
+
+	require.Equal(t, 1, summary.Renewed)
+	require.Equal(t, 1, summary.DeployFailed)
+	require.EqualError(t, summary.Err(), "runner: 1 certificate group(s) failed: web: deploy hook(s) failed: keystore")
+	require.Equal(t, "Summary: 0 issued, 1 renewed, 0 skipped, 0 revoked, 0 failed, 1 deploy failed\n"+
+		"  renewed  web (example.com) in 2.0s\n"+
+		"           deploy haproxy in 0.5s: ok\n"+
+		"           deploy keystore in 1.0s: failed: exit status 1\n", summary.String())
+}